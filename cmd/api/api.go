@@ -8,15 +8,24 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sandoxlabs99/gopher_social/docs" // This is required to generate swagger docs
 	"github.com/sandoxlabs99/gopher_social/internal/auth"
+	"github.com/sandoxlabs99/gopher_social/internal/blob"
+	"github.com/sandoxlabs99/gopher_social/internal/digest"
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/jobs"
 	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+	"github.com/sandoxlabs99/gopher_social/internal/outbox"
 	"github.com/sandoxlabs99/gopher_social/internal/ratelimiter"
 	"github.com/sandoxlabs99/gopher_social/internal/store"
 	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/timeline"
+	"github.com/sandoxlabs99/gopher_social/internal/trends"
+	"github.com/sandoxlabs99/gopher_social/internal/webhooks"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -33,6 +42,16 @@ type application struct {
 	authenticator auth.Authenticator
 	cacheStorage  cache.Storage
 	rateLimiter   ratelimiter.Limiter
+	// publicRateLimiter is the stricter limiter of the routes that don't
+	// require authentication.
+	publicRateLimiter ratelimiter.Limiter
+	timeline          *timeline.Service
+	events            events.Broker
+	blob              blob.Store
+	jobs              *jobs.Queue
+	// workers run from the server start to its shutdown, which waits for
+	// them to return once their context is cancelled.
+	workers []func(context.Context)
 }
 
 type config struct {
@@ -45,6 +64,19 @@ type config struct {
 	auth        authConfig
 	redis       redisConfig
 	rateLimiter ratelimiter.Config
+	comments    commentsConfig
+	pagination  paginationConfig
+	feed        feedConfig
+	timeline    timeline.Config
+	trends      trends.Config
+	digest      digest.Config
+	webhooks    webhooks.Config
+	outbox      outbox.Config
+	jobs        jobs.Config
+	stream      streamConfig
+	messages    messagesConfig
+	media       mediaConfig
+	blob        blob.Config
 }
 
 type dbConfig struct {
@@ -56,23 +88,8 @@ type dbConfig struct {
 }
 
 type mailConfig struct {
-	exp       time.Duration
-	fromEmail string
-	sendGrid  sendGridConfig
-	mailTrap  mailTrapConfig
-	resend    resendConfig
-}
-
-type sendGridConfig struct {
-	apiKey string
-}
-
-type mailTrapConfig struct {
-	apiKey string
-}
-
-type resendConfig struct {
-	apiKey string
+	exp    time.Duration
+	client mailer.Config
 }
 
 type authConfig struct {
@@ -91,6 +108,41 @@ type tokenConfig struct {
 	iss    string
 }
 
+type commentsConfig struct {
+	maxDepth int
+}
+
+type paginationConfig struct {
+	cursorSecret string
+}
+
+type feedConfig struct {
+	// includeOwnPosts adds the users' own posts to their home feed.
+	includeOwnPosts bool
+}
+
+type streamConfig struct {
+	// heartbeat is how often a comment is sent on idle streams, to keep
+	// proxies from closing them.
+	heartbeat time.Duration
+	// replaySize is the number of past events kept per user for streams to
+	// resume from.
+	replaySize int
+}
+
+type messagesConfig struct {
+	// maxGroupSize is the most members a conversation can have, its creator
+	// included.
+	maxGroupSize int
+}
+
+type mediaConfig struct {
+	// maxSize is the largest upload accepted, in bytes.
+	maxSize int64
+	// thumbnailSize is the side of the square thumbnails fit in.
+	thumbnailSize int
+}
+
 type redisConfig struct {
 	addr      string
 	pwd       string
@@ -104,13 +156,15 @@ func (app *application) mount() http.Handler {
 	// A good base middleware stack
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	// socket tokens are kept out of the logs
+	r.Use(StripTokenMiddleware)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	// Basic CORS
 	r.Use(cors.Handler(cors.Options{
 		// AllowedOrigins: []string{"https://*", "http://*"},
 		AllowedOrigins:   []string{app.config.frontendURL},
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: false,
@@ -118,52 +172,171 @@ func (app *application) mount() http.Handler {
 	}))
 	r.Use(app.RateLimiterMiddleware)
 
+	// Streams are meant to stay open, they are mounted outside of the
+	// request timeout.
+	r.With(app.AuthTokenMiddleware).Get("/v1/users/feed/stream", app.streamUserFeedHandler)
+	r.With(SocketTokenMiddleware, app.AuthTokenMiddleware).Get("/v1/conversations/ws", app.conversationsSocketHandler)
+
 	// Set a timeout value on the request context (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
 	// processing should be stopped.
-	r.Use(middleware.Timeout(60 * time.Second))
+	timeout := middleware.Timeout(60 * time.Second)
 
-	r.Route("/v1", func(r chi.Router) {
+	r.With(timeout).Route("/v1", func(r chi.Router) {
 		r.Get("/health", app.healthCheckHandler)
 
 		// expvar for observability and metrics
 		r.With(app.BasicAuthentication()).Get("/debug/vars", expvar.Handler().ServeHTTP)
 
+		// captured mails and template previews, for development
+		r.Route("/debug/mail", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Use(app.requireRole("admin"))
+
+			r.Get("/", app.listCapturedMailHandler)
+			r.Get("/templates", app.listMailTemplatesHandler)
+			r.Get("/templates/{template}", app.previewMailTemplateHandler)
+			r.Get("/{mailID}", app.getCapturedMailHandler)
+		})
+
 		docsURL := fmt.Sprintf("%s/swagger/doc.json", app.config.serverAddr)
 		r.Get("/swagger/*", httpSwagger.Handler(httpSwagger.URL(docsURL)))
 
 		r.Route("/posts", func(r chi.Router) {
-			r.Use(app.AuthTokenMiddleware)
-			r.Post("/", app.createPostHandler)
+			r.With(app.PublicRateLimiterMiddleware).Get("/explore", app.getExploreFeedHandler)
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Post("/", app.createPostHandler)
+
+				r.Route("/{postID}", func(r chi.Router) {
+					r.Use(app.postContextMiddleware)
+
+					r.Get("/", app.getPostHandler)
+					r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
+					r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+					r.Put("/repost", app.repostHandler)
+					r.Put("/unrepost", app.unrepostHandler)
 
-			r.Route("/{postID}", func(r chi.Router) {
-				r.Use(app.postContextMiddleware)
+					r.Route("/comments", func(r chi.Router) {
+						r.Get("/", app.getCommentsHandler)
+						r.Post("/", app.createCommentHandler)
 
-				r.Get("/", app.getPostHandler)
-				r.Patch("/", app.checkPostOwnership("moderator", app.updatePostHandler))
-				r.Delete("/", app.checkPostOwnership("admin", app.deletePostHandler))
+						r.Route("/{commentID}", func(r chi.Router) {
+							r.Use(app.commentContextMiddleware)
 
-				r.Route("/comments", func(r chi.Router) {
-					r.Post("/", app.createCommentHandler)
+							r.Patch("/", app.checkCommentOwnership("moderator", false, app.updateCommentHandler))
+							r.Delete("/", app.checkCommentOwnership("admin", true, app.deleteCommentHandler))
+							r.Get("/replies", app.getCommentRepliesHandler)
+						})
+					})
 				})
 			})
 		})
 
+		r.Route("/tags", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(app.PublicRateLimiterMiddleware)
+				r.Get("/{tag}/posts", app.getTagPostsHandler)
+				r.Get("/{tag}.atom", app.getTagAtomHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/followed", app.getFollowedTagsHandler)
+				r.Put("/{tag}/follow", app.followTagHandler)
+				r.Put("/{tag}/unfollow", app.unfollowTagHandler)
+			})
+		})
+
+		r.Route("/trends", func(r chi.Router) {
+			r.Use(app.PublicRateLimiterMiddleware)
+			r.Get("/tags", app.getTrendingTagsHandler)
+			r.Get("/posts", app.getTrendingPostsHandler)
+		})
+
 		r.Route("/users", func(r chi.Router) {
 			r.Put("/activate/{token}", app.activateUserHandler)
+			r.With(app.PublicRateLimiterMiddleware).Put("/digest/unsubscribe/{token}", app.unsubscribeDigestHandler)
 
 			r.Route("/{userID}", func(r chi.Router) {
-				r.Use(app.AuthTokenMiddleware)
-				r.Use(app.userContextMiddleware)
+				r.Group(func(r chi.Router) {
+					r.Use(app.PublicRateLimiterMiddleware)
+					r.Use(app.userContextMiddleware)
+					r.Get("/posts.atom", app.getUserPostsAtomHandler)
+					r.Get("/posts.rss", app.getUserPostsRSSHandler)
+				})
 
-				r.Get("/", app.getUserHandler)
-				r.Put("/follow", app.followUserHandler)
-				r.Put("/unfollow", app.unfollowUserHandler)
+				r.Group(func(r chi.Router) {
+					r.Use(app.AuthTokenMiddleware)
+					r.Use(app.userContextMiddleware)
+					r.Get("/", app.getUserHandler)
+					r.Get("/posts", app.getUserPostsHandler)
+					r.Put("/follow", app.followUserHandler)
+					r.Put("/unfollow", app.unfollowUserHandler)
+					r.Put("/block", app.blockUserHandler)
+					r.Put("/unblock", app.unblockUserHandler)
+				})
 			})
 
 			r.Group(func(r chi.Router) {
 				r.Use(app.AuthTokenMiddleware)
 				r.Get("/feed", app.getUserFeedHandler)
+				r.Get("/mentions", app.getMentionsHandler)
+				r.Put("/privacy", app.updatePrivacyHandler)
+				r.Get("/digest", app.getDigestSettingsHandler)
+				r.Put("/digest", app.updateDigestSettingsHandler)
+				r.Put("/avatar", app.uploadAvatarHandler)
+				r.Delete("/avatar", app.deleteAvatarHandler)
+				r.Put("/banner", app.uploadBannerHandler)
+				r.Delete("/banner", app.deleteBannerHandler)
+			})
+		})
+
+		r.Route("/notifications", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getNotificationsHandler)
+			r.Put("/read", app.markAllNotificationsReadHandler)
+			r.Put("/{notificationID}/read", app.markNotificationReadHandler)
+			r.Get("/preferences", app.getNotificationPreferencesHandler)
+			r.Put("/preferences", app.updateNotificationPreferencesHandler)
+		})
+
+		r.Route("/webhooks", func(r chi.Router) {
+			r.Use(app.AuthTokenMiddleware)
+			r.Get("/", app.getWebhooksHandler)
+			r.Post("/", app.createWebhookHandler)
+
+			r.Route("/{webhookID}", func(r chi.Router) {
+				r.Use(app.webhookContextMiddleware)
+
+				r.Get("/", app.getWebhookHandler)
+				r.Patch("/", app.updateWebhookHandler)
+				r.Delete("/", app.deleteWebhookHandler)
+				r.Get("/deliveries", app.getWebhookDeliveriesHandler)
+				r.Post("/deliveries/{deliveryID}/redeliver", app.redeliverWebhookHandler)
+			})
+		})
+
+		r.Route("/media", func(r chi.Router) {
+			r.Get("/files/*", app.getMediaFileHandler)
+			r.With(app.AuthTokenMiddleware).Post("/", app.uploadMediaHandler)
+		})
+
+		r.Route("/conversations", func(r chi.Router) {
+			r.Group(func(r chi.Router) {
+				r.Use(app.AuthTokenMiddleware)
+				r.Get("/", app.getConversationsHandler)
+				r.Post("/", app.createConversationHandler)
+
+				r.Route("/{conversationID}", func(r chi.Router) {
+					r.Use(app.conversationContextMiddleware)
+
+					r.Get("/", app.getConversationHandler)
+					r.Get("/messages", app.getMessagesHandler)
+					r.Post("/messages", app.sendMessageHandler)
+					r.Put("/read", app.markConversationReadHandler)
+				})
 			})
 		})
 
@@ -191,6 +364,11 @@ func (app *application) run(mux http.Handler) error {
 		IdleTimeout:  time.Minute,
 	}
 
+	workCtx, stopWork := context.WithCancel(context.Background())
+	defer stopWork()
+
+	var workers sync.WaitGroup
+
 	shutdown := make(chan error)
 
 	go func() {
@@ -206,9 +384,27 @@ func (app *application) run(mux http.Handler) error {
 
 		err := srv.Shutdown(ctx)
 
+		// let the running jobs finish within the same deadline
+		if app.jobs != nil {
+			err = errors.Join(err, app.jobs.Shutdown(ctx))
+		}
+
+		stopWork()
+		err = errors.Join(err, wait(ctx, &workers))
+
 		shutdown <- err
 	}()
 
+	if app.jobs != nil {
+		app.jobs.Start()
+	}
+
+	for _, work := range app.workers {
+		workers.Go(func() {
+			work(workCtx)
+		})
+	}
+
 	app.logger.Infow(
 		"server has started",
 		"addr", app.config.serverAddr,
@@ -230,3 +426,19 @@ func (app *application) run(mux http.Handler) error {
 
 	return nil
 }
+
+// wait waits for wg, until ctx is done.
+func wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
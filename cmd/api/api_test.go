@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -53,3 +54,55 @@ func TestRateLimiterMiddlware(t *testing.T) {
 	}
 
 }
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		want       string
+	}{
+		{remoteAddr: "192.168.1.1:51234", want: "192.168.1.1"},
+		{remoteAddr: "[2001:db8::1]:51234", want: "2001:db8::1"},
+		// set by middleware.RealIP from a proxy header
+		{remoteAddr: "192.168.1.1", want: "192.168.1.1"},
+		{remoteAddr: "2001:db8::1", want: "2001:db8::1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.remoteAddr, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+			r.RemoteAddr = tt.remoteAddr
+
+			if got := clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPublicRateLimiterIgnoresPorts(t *testing.T) {
+	cfg := config{
+		rateLimiter: ratelimiter.Config{
+			RequestsPerTimeFrame:       20,
+			PublicRequestsPerTimeFrame: 2,
+			TimeFrame:                  5 * time.Second,
+			IsEnabled:                  true,
+		},
+	}
+
+	app := newTestApplication(t, cfg)
+	mux := app.mount()
+
+	for i := range cfg.rateLimiter.PublicRequestsPerTimeFrame + 1 {
+		// a new connection, from a new port, for every request
+		req := httptest.NewRequest(http.MethodGet, "/v1/posts/explore?mode=top", nil)
+		req.RemoteAddr = fmt.Sprintf("192.168.1.1:%d", 50000+i)
+
+		rr := executeRequest(mux, req)
+
+		if i < cfg.rateLimiter.PublicRequestsPerTimeFrame {
+			checkResponseCode(t, http.StatusBadRequest, rr.Code)
+		} else {
+			checkResponseCode(t, http.StatusTooManyRequests, rr.Code)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -74,10 +75,37 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 	hash := sha256.Sum256([]byte(token.String()))
 	hashToken := hex.EncodeToString(hash[:])
 
+	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, token.String())
+
+	isProdEnv := app.config.namespace == "production"
+
+	vars, err := json.Marshal(struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      user.Username,
+		ActivationURL: activationURL,
+	})
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	// the invite is sent in the background, once the user is stored
+	expiresAt := time.Now().Add(app.config.mail.exp)
+	invite := &models.OutboxMail{
+		Template:  mailer.UserWelcomeTemplate,
+		Username:  user.Username,
+		Email:     user.Email,
+		Data:      vars,
+		IsSandbox: !isProdEnv,
+		ExpiresAt: &expiresAt,
+	}
+
 	ctx := r.Context()
 
 	// store the user
-	if err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.config.mail.exp); err != nil {
+	if err := app.store.Users.CreateAndInvite(ctx, user, hashToken, app.config.mail.exp, invite); err != nil {
 		switch err {
 		case store.ErrDuplicateEmail:
 			app.badRequestResponse(w, r, err)
@@ -94,38 +122,6 @@ func (app *application) registerUserHandler(w http.ResponseWriter, r *http.Reque
 		Token: token.String(),
 	}
 
-	activationURL := fmt.Sprintf("%s/confirm/%s", app.config.frontendURL, token.String())
-
-	isProdEnv := app.config.namespace == "production"
-
-	vars := struct {
-		Username      string
-		ActivationURL string
-	}{
-		Username:      UserWithToken.Username,
-		ActivationURL: activationURL,
-	}
-
-	// send the email invite
-	err = app.mailer.Send(mailer.UserWelcomeTemplate, user.Username, user.Email, vars, !isProdEnv)
-	if err != nil {
-		app.logger.Errorw(
-			"error sending welcome email",
-			"error", err,
-		)
-
-		// rollback user creation if email fails (SAGA Pattern)
-		if err := app.store.Users.Delete(ctx, UserWithToken.ID); err != nil {
-			app.logger.Errorw(
-				"error deleting user",
-				"error", err,
-			)
-		}
-
-		app.internalServerError(w, r, err)
-		return
-	}
-
 	if err := app.JSONResponse(w, http.StatusCreated, UserWithToken); err != nil {
 		app.internalServerError(w, r, err)
 		return
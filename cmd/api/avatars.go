@@ -0,0 +1,207 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/media"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+)
+
+// userImage describes a kind of image users put on their profile.
+type userImage struct {
+	dir       string
+	minWidth  int
+	minHeight int
+	variants  []media.Variant
+	set       func(ctx context.Context, userID int64, image models.ImageVariants) error
+}
+
+func (app *application) avatarImage() userImage {
+	return userImage{
+		dir:       "avatars",
+		minWidth:  128,
+		minHeight: 128,
+		variants: []media.Variant{
+			{Name: models.AvatarSmall, Width: 48, Height: 48},
+			{Name: models.AvatarMedium, Width: 128, Height: 128},
+			{Name: models.AvatarLarge, Width: 400, Height: 400},
+		},
+		set: app.store.Users.SetAvatar,
+	}
+}
+
+func (app *application) bannerImage() userImage {
+	return userImage{
+		dir:       "banners",
+		minWidth:  600,
+		minHeight: 200,
+		variants: []media.Variant{
+			{Name: models.BannerSmall, Width: 600, Height: 200},
+			{Name: models.BannerLarge, Width: 1500, Height: 500},
+		},
+		set: app.store.Users.SetBanner,
+	}
+}
+
+// UploadAvatar godoc
+//
+//	@Summary		Upload an avatar
+//	@Description	Replaces the authenticated user's avatar. The image must be at least 128x128, it is cropped to a square and resized to 48, 128 and 400 pixels
+//	@Tags			users
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		200		{object}	models.ImageVariants
+//	@Failure		400		{object}	error	"Invalid image"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		413		{object}	error	"File too large"
+//	@Failure		415		{object}	error	"Unsupported media type"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/avatar [put]
+func (app *application) uploadAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	app.uploadUserImage(w, r, app.avatarImage())
+}
+
+// DeleteAvatar godoc
+//
+//	@Summary		Remove the avatar
+//	@Description	Removes the authenticated user's avatar
+//	@Tags			users
+//	@Produce		json
+//	@Success		204	{object}	string	"Avatar removed"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/avatar [delete]
+func (app *application) deleteAvatarHandler(w http.ResponseWriter, r *http.Request) {
+	app.deleteUserImage(w, r, app.avatarImage())
+}
+
+// UploadBanner godoc
+//
+//	@Summary		Upload a profile banner
+//	@Description	Replaces the authenticated user's profile banner. The image must be at least 600x200, it is cropped to a 3:1 ratio and resized to 600x200 and 1500x500 pixels
+//	@Tags			users
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		200		{object}	models.ImageVariants
+//	@Failure		400		{object}	error	"Invalid image"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		413		{object}	error	"File too large"
+//	@Failure		415		{object}	error	"Unsupported media type"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/banner [put]
+func (app *application) uploadBannerHandler(w http.ResponseWriter, r *http.Request) {
+	app.uploadUserImage(w, r, app.bannerImage())
+}
+
+// DeleteBanner godoc
+//
+//	@Summary		Remove the profile banner
+//	@Description	Removes the authenticated user's profile banner
+//	@Tags			users
+//	@Produce		json
+//	@Success		204	{object}	string	"Banner removed"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/banner [delete]
+func (app *application) deleteBannerHandler(w http.ResponseWriter, r *http.Request) {
+	app.deleteUserImage(w, r, app.bannerImage())
+}
+
+// uploadUserImage resizes the uploaded image to every variant of kind and
+// sets them on the user's profile. The files are named after the hash of
+// the upload, so that their URLs change along with them and can be cached
+// for good. The previous files are kept for the responses still cached with
+// their URLs.
+func (app *application) uploadUserImage(w http.ResponseWriter, r *http.Request, kind userImage) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data, err := app.readUpload(w, r)
+	if err != nil {
+		switch {
+		case errors.Is(err, errFileTooLarge):
+			app.payloadTooLargeResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	images, err := media.Resize(data, kind.minWidth, kind.minHeight, kind.variants)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			app.unsupportedMediaTypeResponse(w, r, err)
+		case errors.Is(err, media.ErrTooLarge), errors.Is(err, media.ErrTooSmall):
+			app.badRequestResponse(w, r, fmt.Errorf("%w, the minimum is %dx%d", err, kind.minWidth, kind.minHeight))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	hash := sha256.Sum256(data)
+	name := fmt.Sprintf("%s/%d/%s", kind.dir, user.ID, hex.EncodeToString(hash[:8]))
+
+	variants := make(models.ImageVariants, len(images))
+	for _, v := range kind.variants {
+		img := images[v.Name]
+		key := name + "_" + v.Name + img.Ext
+
+		if err := app.blob.Put(r.Context(), key, img.Data, img.ContentType); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		variants[v.Name] = app.blob.URL(key)
+	}
+
+	if err := kind.set(r.Context(), user.ID, variants); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "user not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, variants); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+func (app *application) deleteUserImage(w http.ResponseWriter, r *http.Request, kind userImage) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := kind.set(r.Context(), user.ID, nil); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "user not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+)
+
+type UpdatePrivacyPayload struct {
+	IsPrivate *bool `json:"isPrivate" validate:"required"`
+}
+
+// BlockUser godoc
+//
+//	@Summary		Block a user
+//	@Description	Block a user by ID. Blocked users and the users who blocked them cannot message each other
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{object}	string	"Block successful"
+//	@Failure		400		{object}	error	"Invalid payload"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		409		{object}	error	"Already blocking user"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/block [put]
+func (app *application) blockUserHandler(w http.ResponseWriter, r *http.Request) {
+	blockerUser, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	blockedUser := getUserFromCtx(r)
+
+	if blockerUser.ID == blockedUser.ID {
+		app.badRequestResponse(w, r, fmt.Errorf("cannot block yourself"))
+		return
+	}
+
+	if err := app.store.Blocks.Block(r.Context(), blockerUser.ID, blockedUser.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateKey):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "user not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnblockUser godoc
+//
+//	@Summary		Unblock a user
+//	@Description	Unblock a user by ID
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int		true	"User ID"
+//	@Success		204		{object}	string	"Unblock successful"
+//	@Failure		400		{object}	error	"Invalid payload"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/unblock [put]
+func (app *application) unblockUserHandler(w http.ResponseWriter, r *http.Request) {
+	blockerUser, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	blockedUser := getUserFromCtx(r)
+
+	if blockerUser.ID == blockedUser.ID {
+		app.badRequestResponse(w, r, fmt.Errorf("cannot unblock yourself"))
+		return
+	}
+
+	if err := app.store.Blocks.UnBlock(r.Context(), blockerUser.ID, blockedUser.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdatePrivacy godoc
+//
+//	@Summary		Update the account privacy
+//	@Description	Makes the authenticated user's account private or public. Private accounts can only be messaged by the users they follow
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdatePrivacyPayload	true	"Privacy"
+//	@Success		204		{object}	string					"Privacy updated"
+//	@Failure		400		{object}	error					"Invalid payload"
+//	@Failure		401		{object}	error					"Unauthorized"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/privacy [put]
+func (app *application) updatePrivacyHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdatePrivacyPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Users.SetPrivacy(r.Context(), user.ID, *payload.IsPrivate); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "user not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	// the cached user would keep the previous privacy until it expires
+	if app.config.redis.isEnabled {
+		if err := app.cacheStorage.Users.Delete(r.Context(), user.ID); err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -1,24 +1,29 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/sandoxlabs99/gopher_social/internal/events"
 	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type CreateCommentsPayload struct {
-	UserID  int64  `json:"user_id" validate:"required"`
-	Content string `json:"content" validate:"required,min=3,max=1000"`
+	ParentID *int64 `json:"parent_id" validate:"omitempty,gte=1"`
+	Content  string `json:"content" validate:"required,min=3,max=1000"`
 }
 
 // CreateComment godoc
 //
 //	@Summary		Creates a comment on a post
-//	@Description	Creates a comment on a post
+//	@Description	Creates a comment on a post, or a reply to another comment when a parent_id is given
 //	@Tags			comments
 //	@Accept			json
 //	@Produce		json
@@ -51,19 +56,386 @@ func (app *application) createCommentHandler(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	var parent *models.Comment
+	if payload.ParentID != nil {
+		parent, err = app.store.Comments.GetByID(ctx, *payload.ParentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("parent comment does not exist"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		switch {
+		case parent.PostID != postID:
+			app.badRequestResponse(w, r, fmt.Errorf("parent comment belongs to another post"))
+			return
+		case parent.IsDeleted:
+			app.badRequestResponse(w, r, fmt.Errorf("cannot reply to a deleted comment"))
+			return
+		case parent.Depth+1 > app.config.comments.maxDepth:
+			app.badRequestResponse(w, r, fmt.Errorf("replies cannot be nested more than %d levels deep", app.config.comments.maxDepth))
+			return
+		}
+	}
+
 	comment := &models.Comment{
-		PostID:  postID,
-		UserID:  payload.UserID,
-		Content: payload.Content,
+		PostID:   postID,
+		UserID:   user.ID,
+		ParentID: payload.ParentID,
+		Content:  payload.Content,
 	}
 
-	if err := app.store.Comments.Create(r.Context(), comment); err != nil {
+	comment.Mentions, err = app.resolveMentions(ctx, comment.UserID, comment.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Comments.Create(ctx, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	post := getPostFromCtx(r)
+	if post.UserID != comment.UserID {
+		app.publishEvent(ctx, []int64{post.UserID}, events.TypeComment, comment)
+	}
+
+	// the author of the post hears about the replies in it as comments
+	if parent != nil && parent.UserID != post.UserID {
+		app.notify(ctx, models.Notification{
+			UserID:    parent.UserID,
+			ActorID:   comment.UserID,
+			Type:      models.NotificationReply,
+			GroupKey:  fmt.Sprintf("reply:%d", parent.ID),
+			PostID:    &post.ID,
+			CommentID: &comment.ID,
+		})
+	}
+
+	app.notify(ctx, models.Notification{
+		UserID:    post.UserID,
+		ActorID:   comment.UserID,
+		Type:      models.NotificationComment,
+		GroupKey:  fmt.Sprintf("comment:%d", post.ID),
+		PostID:    &post.ID,
+		CommentID: &comment.ID,
+	})
+
+	app.notifyMentions(ctx, comment.UserID, post, &comment.ID, comment.Mentions, nil, comment)
+
 	if err := app.JSONResponse(w, http.StatusCreated, comment); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// GetComments godoc
+//
+//	@Summary		Fetches the comments of a post
+//	@Description	Fetches a page of the top-level comments of a post, each with its reply count
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int64	true	"Post ID"
+//	@Param			limit	query		int		false	"Number of comments to return"	default(20)		minimum(1)	maximum(50)
+//	@Param			offset	query		int		false	"Number of comments to skip"	default(0)		minimum(0)
+//	@Param			cursor	query		string	false	"Opaque cursor taken from the Link header, replaces offset"
+//	@Param			sort	query		string	false	"Sort order: 'asc' or 'desc'"	default(desc)	enum(asc,desc)
+//	@Success		200		{object}	[]models.Comment
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments [get]
+func (app *application) getCommentsHandler(w http.ResponseWriter, r *http.Request) {
+	post := getPostFromCtx(r)
+
+	cq, err := app.parseCommentQuery(r, "desc")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.setCommentPaginationLinks(w, r, cq, comments)
+
+	if err := app.JSONResponse(w, http.StatusOK, comments); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetCommentReplies godoc
+//
+//	@Summary		Fetches the replies to a comment
+//	@Description	Fetches a page of the direct replies to a comment, each with its own reply count
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int64	true	"Post ID"
+//	@Param			commentID	path		int64	true	"Comment ID"
+//	@Param			limit		query		int		false	"Number of replies to return"	default(20)	minimum(1)	maximum(50)
+//	@Param			offset		query		int		false	"Number of replies to skip"		default(0)	minimum(0)
+//	@Param			cursor		query		string	false	"Opaque cursor taken from the Link header, replaces offset"
+//	@Param			sort		query		string	false	"Sort order: 'asc' or 'desc'"	default(asc)	enum(asc,desc)
+//	@Success		200			{object}	[]models.Comment
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID}/replies [get]
+func (app *application) getCommentRepliesHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	// replies read top to bottom, oldest first
+	cq, err := app.parseCommentQuery(r, "asc")
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	replies, err := app.store.Comments.GetReplies(r.Context(), comment.ID, cq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	app.setCommentPaginationLinks(w, r, cq, replies)
+
+	if err := app.JSONResponse(w, http.StatusOK, replies); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+type UpdateCommentPayload struct {
+	Content string `json:"content" validate:"required,min=3,max=1000"`
+	Version *int   `json:"version" validate:"omitempty,gte=0"`
+}
+
+// UpdateComment godoc
+//
+//	@Summary		Updates a comment
+//	@Description	Updates the content of a comment and marks it as edited. Passing the version last read makes the update fail with a conflict if the comment changed in the meantime.
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int64					true	"Post ID"
+//	@Param			commentID	path		int64					true	"Comment ID"
+//	@Param			payload		body		UpdateCommentPayload	true	"Comment payload"
+//	@Success		200			{object}	models.Comment
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		409			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [patch]
+func (app *application) updateCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	var payload UpdateCommentPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.Version != nil && *payload.Version != comment.Version {
+		app.conflictResponse(w, r, store.ErrUpdateConflict)
+		return
+	}
+
+	previousMentions := comment.Mentions
+	comment.Content = payload.Content
+
+	mentions, err := app.resolveMentions(r.Context(), comment.UserID, comment.Content)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	comment.Mentions = mentions
+
+	if err := app.store.Comments.Update(r.Context(), comment); err != nil {
+		switch {
+		case errors.Is(err, store.ErrUpdateConflict):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	post := getPostFromCtx(r)
+	app.notifyMentions(r.Context(), comment.UserID, post, &comment.ID, comment.Mentions, previousMentions, comment)
+
+	if app.isModeratedComment(r, comment, post) {
+		app.notifyModeration(r, comment.UserID, models.ActionCommentUpdated, &post.ID, &comment.ID)
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, comment); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// DeleteComment godoc
+//
+//	@Summary		Deletes a comment
+//	@Description	Deletes a comment by ID. A comment that has replies is kept as a placeholder.
+//	@Tags			comments
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID		path		int64	true	"Post ID"
+//	@Param			commentID	path		int64	true	"Comment ID"
+//	@Success		204			{object}	string
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/comments/{commentID} [delete]
+func (app *application) deleteCommentHandler(w http.ResponseWriter, r *http.Request) {
+	comment := getCommentFromCtx(r)
+
+	if err := app.store.Comments.Delete(r.Context(), comment.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "comment not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if post := getPostFromCtx(r); app.isModeratedComment(r, comment, post) {
+		app.notifyModeration(r, comment.UserID, models.ActionCommentDeleted, &post.ID, nil)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// isModeratedComment tells whether the authenticated user acts on the
+// comment as a moderator, rather than as its author or the author of the
+// post it was left on.
+func (app *application) isModeratedComment(r *http.Request, comment *models.Comment, post *models.Post) bool {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		return false
+	}
+
+	return user.ID != comment.UserID && user.ID != post.UserID
+}
+
+func (app *application) parseCommentQuery(r *http.Request, sort string) (utils.PaginatedCommentQuery, error) {
+	cq := utils.PaginatedCommentQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   sort,
+	}
+
+	cq, err := cq.Parse(r)
+	if err != nil {
+		return cq, err
+	}
+
+	if err := Validate.Struct(cq); err != nil {
+		return cq, err
+	}
+
+	cq.Keyset, err = app.decodeCursor(cq.Cursor)
+	if err != nil {
+		return cq, err
+	}
+
+	return cq, nil
+}
+
+func (app *application) setCommentPaginationLinks(w http.ResponseWriter, r *http.Request, cq utils.PaginatedCommentQuery, comments []models.Comment) {
+	if len(comments) == 0 {
+		return
+	}
+
+	first, last := comments[0], comments[len(comments)-1]
+	app.setPaginationLinks(
+		w, r, cq.Keyset, cq.Offset, cq.Limit, len(comments),
+		utils.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+		utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+	)
+}
+
+// Middlewares
+
+type ctxKeyComment string
+
+const CommentContextKey = ctxKeyComment("comment")
+
+func (app *application) commentContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		commentID, err := strconv.ParseInt(chi.URLParam(r, "commentID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		ctx := r.Context()
+
+		comment, err := app.store.Comments.GetByID(ctx, commentID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err, "comment not found")
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		// a comment is only reachable through the post it belongs to
+		if post := getPostFromCtx(r); post == nil || post.ID != comment.PostID {
+			app.notFoundResponse(w, r, store.ErrNotFound, "comment not found")
+			return
+		}
+
+		// placeholders of deleted comments can only be read
+		if comment.IsDeleted && r.Method != http.MethodGet {
+			app.notFoundResponse(w, r, store.ErrNotFound, "comment not found")
+			return
+		}
+
+		ctx = context.WithValue(ctx, CommentContextKey, comment)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getCommentFromCtx(r *http.Request) *models.Comment {
+	comment, _ := r.Context().Value(CommentContextKey).(*models.Comment)
+
+	return comment
+}
@@ -0,0 +1,285 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+// newCommentsTestApplication sets up post 1 of user 2 and post 2 of user 1,
+// both public, for the comments tests to use.
+func newCommentsTestApplication(t *testing.T) (*application, http.Handler, string) {
+	t.Helper()
+
+	app := newTestApplication(t, config{comments: commentsConfig{maxDepth: 2}})
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetByID", int64(1)).Return(&models.Post{ID: 1, UserID: 2, Visibility: models.VisibilityPublic}, nil)
+	posts.On("GetByID", int64(2)).Return(&models.Post{ID: 2, UserID: 1, Visibility: models.VisibilityPublic}, nil)
+
+	app.store.Notifications.(*store.MockNotificationStore).On("Create", mock.Anything).Return(nil)
+
+	return app, app.mount(), testToken
+}
+
+func TestCommentThreads(t *testing.T) {
+	app, mux, testToken := newCommentsTestApplication(t)
+
+	parentID := int64(10)
+
+	comments := app.store.Comments.(*store.MockCommentStore)
+	comments.On("GetByID", int64(10)).Return(&models.Comment{ID: 10, PostID: 1, UserID: 3}, nil)
+	comments.On("GetByID", int64(11)).Return(&models.Comment{ID: 11, PostID: 1, IsDeleted: true}, nil)
+	comments.On("GetByID", int64(12)).Return(&models.Comment{ID: 12, PostID: 1, UserID: 3, ParentID: &parentID, Depth: 2}, nil)
+	comments.On("GetByID", int64(13)).Return(&models.Comment{ID: 13, PostID: 2, UserID: 3}, nil)
+	comments.On("GetByID", int64(14)).Return(nil, store.ErrNotFound)
+	comments.On("GetReplies", mock.Anything, mock.Anything).Return(nil, nil)
+	comments.On("Create", mock.Anything).Return(nil)
+
+	request := func(t *testing.T, method, path, body string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(mux, req).Code
+	}
+
+	t.Run("should reply to a comment", func(t *testing.T) {
+		code := request(t, http.MethodPost, "/v1/posts/1/comments", `{"content": "a reply", "parent_id": 10}`)
+		checkResponseCode(t, http.StatusCreated, code)
+
+		comments.AssertCalled(t, "Create", mock.MatchedBy(func(c *models.Comment) bool {
+			return c.PostID == 1 && c.UserID == 1 && c.ParentID != nil && *c.ParentID == 10
+		}))
+
+		// the author of the parent hears about the reply, the post author
+		// about the comment
+		notifications := app.store.Notifications.(*store.MockNotificationStore)
+		notifications.AssertCalled(t, "Create", mock.MatchedBy(func(n *models.Notification) bool {
+			return n.UserID == 3 && n.Type == models.NotificationReply && n.GroupKey == "reply:10"
+		}))
+		notifications.AssertCalled(t, "Create", mock.MatchedBy(func(n *models.Notification) bool {
+			return n.UserID == 2 && n.Type == models.NotificationComment && n.GroupKey == "comment:1"
+		}))
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "to a missing parent", body: `{"content": "a reply", "parent_id": 14}`},
+		{name: "to a parent on another post", body: `{"content": "a reply", "parent_id": 13}`},
+		{name: "to a deleted parent", body: `{"content": "a reply", "parent_id": 11}`},
+		{name: "past the max depth", body: `{"content": "a reply", "parent_id": 12}`},
+	}
+
+	for _, tt := range tests {
+		t.Run("should NOT reply "+tt.name, func(t *testing.T) {
+			code := request(t, http.MethodPost, "/v1/posts/1/comments", tt.body)
+			checkResponseCode(t, http.StatusBadRequest, code)
+		})
+	}
+
+	t.Run("should list the replies oldest first", func(t *testing.T) {
+		code := request(t, http.MethodGet, "/v1/posts/1/comments/10/replies", "")
+		checkResponseCode(t, http.StatusOK, code)
+
+		comments.AssertCalled(t, "GetReplies", int64(10), mock.MatchedBy(func(cq utils.PaginatedCommentQuery) bool {
+			return cq.Sort == "asc"
+		}))
+	})
+
+	t.Run("should list the replies of placeholders", func(t *testing.T) {
+		code := request(t, http.MethodGet, "/v1/posts/1/comments/11/replies", "")
+		checkResponseCode(t, http.StatusOK, code)
+	})
+
+	t.Run("should only reach comments through their post", func(t *testing.T) {
+		code := request(t, http.MethodGet, "/v1/posts/1/comments/13/replies", "")
+		checkResponseCode(t, http.StatusNotFound, code)
+	})
+}
+
+func TestDeleteThreadedComment(t *testing.T) {
+	app, mux, testToken := newCommentsTestApplication(t)
+
+	comments := app.store.Comments.(*store.MockCommentStore)
+	comments.On("GetByID", int64(20)).Return(&models.Comment{ID: 20, PostID: 2, UserID: 1, ReplyCount: 2}, nil)
+	comments.On("GetByID", int64(21)).Return(&models.Comment{ID: 21, PostID: 2, IsDeleted: true}, nil)
+	comments.On("GetByID", int64(22)).Return(&models.Comment{ID: 22, PostID: 2, UserID: 1}, nil)
+	comments.On("Delete", int64(20)).Return(nil)
+	comments.On("Delete", int64(22)).Return(store.ErrNotFound)
+
+	request := func(t *testing.T, method, path string) int {
+		t.Helper()
+
+		req, err := http.NewRequest(method, path, strings.NewReader(`{"content": "edited"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(mux, req).Code
+	}
+
+	t.Run("should delete comments with replies", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNoContent, request(t, http.MethodDelete, "/v1/posts/2/comments/20"))
+
+		comments.AssertCalled(t, "Delete", int64(20))
+	})
+
+	t.Run("should report comments deleted meanwhile as not found", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, request(t, http.MethodDelete, "/v1/posts/2/comments/22"))
+	})
+
+	t.Run("should NOT delete placeholders", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, request(t, http.MethodDelete, "/v1/posts/2/comments/21"))
+
+		comments.AssertNotCalled(t, "Delete", int64(21))
+	})
+
+	t.Run("should NOT edit placeholders", func(t *testing.T) {
+		checkResponseCode(t, http.StatusNotFound, request(t, http.MethodPatch, "/v1/posts/2/comments/21"))
+	})
+}
+
+func TestCommentOwnership(t *testing.T) {
+	// comment 30 is user 1's, 31 user 3's on post 1 of user 2, and 32
+	// user 3's on post 2 of user 1
+	setup := func(t *testing.T, role models.Role) (*application, http.Handler, string) {
+		app, mux, testToken := newCommentsTestApplication(t)
+
+		// the authenticated user, see auth.TestAuthenticator
+		app.store.Users.(*store.MockUserStore).Users = map[int64]*models.User{
+			102: {ID: 1, Role: role},
+		}
+
+		roles := app.store.Roles.(*store.MockRoleStore)
+		roles.On("GetByName", "moderator").Return(&models.Role{Name: "moderator", Level: 2}, nil)
+		roles.On("GetByName", "admin").Return(&models.Role{Name: "admin", Level: 3}, nil)
+
+		comments := app.store.Comments.(*store.MockCommentStore)
+		comments.On("GetByID", int64(30)).Return(&models.Comment{ID: 30, PostID: 1, UserID: 1}, nil)
+		comments.On("GetByID", int64(31)).Return(&models.Comment{ID: 31, PostID: 1, UserID: 3}, nil)
+		comments.On("GetByID", int64(32)).Return(&models.Comment{ID: 32, PostID: 2, UserID: 3}, nil)
+		comments.On("Update", mock.Anything).Return(nil)
+		comments.On("Delete", mock.Anything).Return(nil)
+
+		return app, mux, testToken
+	}
+
+	tests := []struct {
+		name   string
+		role   models.Role
+		method string
+		path   string
+		want   int
+	}{
+		{name: "author edits", method: http.MethodPatch, path: "/v1/posts/1/comments/30", want: http.StatusOK},
+		{name: "author deletes", method: http.MethodDelete, path: "/v1/posts/1/comments/30", want: http.StatusNoContent},
+		{name: "someone else edits", method: http.MethodPatch, path: "/v1/posts/1/comments/31", want: http.StatusForbidden},
+		{name: "someone else deletes", method: http.MethodDelete, path: "/v1/posts/1/comments/31", want: http.StatusForbidden},
+		{name: "post author edits", method: http.MethodPatch, path: "/v1/posts/2/comments/32", want: http.StatusForbidden},
+		{name: "post author deletes", method: http.MethodDelete, path: "/v1/posts/2/comments/32", want: http.StatusNoContent},
+		{name: "moderator edits", role: models.Role{Level: 2}, method: http.MethodPatch, path: "/v1/posts/1/comments/31", want: http.StatusOK},
+		{name: "moderator deletes", role: models.Role{Level: 2}, method: http.MethodDelete, path: "/v1/posts/1/comments/31", want: http.StatusForbidden},
+		{name: "admin deletes", role: models.Role{Level: 3}, method: http.MethodDelete, path: "/v1/posts/1/comments/31", want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, mux, testToken := setup(t, tt.role)
+
+			req, err := http.NewRequest(tt.method, tt.path, strings.NewReader(`{"content": "edited"}`))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+
+			comments := app.store.Comments.(*store.MockCommentStore)
+			if tt.want == http.StatusForbidden {
+				comments.AssertNotCalled(t, "Update", mock.Anything)
+				comments.AssertNotCalled(t, "Delete", mock.Anything)
+			}
+		})
+	}
+
+	t.Run("should tell authors about moderators' edits", func(t *testing.T) {
+		app, mux, testToken := setup(t, models.Role{Level: 2})
+
+		req, err := http.NewRequest(http.MethodPatch, "/v1/posts/1/comments/31", strings.NewReader(`{"content": "edited"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		app.store.Notifications.(*store.MockNotificationStore).AssertCalled(t, "Create", mock.MatchedBy(func(n *models.Notification) bool {
+			return n.UserID == 3 && n.Type == models.NotificationModeration && n.Action == models.ActionCommentUpdated
+		}))
+	})
+}
+
+func TestUpdateCommentVersion(t *testing.T) {
+	app, mux, testToken := newCommentsTestApplication(t)
+
+	comments := app.store.Comments.(*store.MockCommentStore)
+	comments.On("GetByID", int64(40)).Return(&models.Comment{ID: 40, PostID: 1, UserID: 1, Version: 2}, nil)
+	comments.On("Update", mock.MatchedBy(func(c *models.Comment) bool { return c.Content == "stale" })).Return(store.ErrUpdateConflict)
+	comments.On("Update", mock.Anything).Return(nil)
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{name: "current version", body: `{"content": "edited", "version": 2}`, want: http.StatusOK},
+		{name: "no version", body: `{"content": "edited"}`, want: http.StatusOK},
+		{name: "outdated version", body: `{"content": "edited", "version": 1}`, want: http.StatusConflict},
+		{name: "edited meanwhile", body: `{"content": "stale", "version": 2}`, want: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPatch, "/v1/posts/1/comments/40", strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	// the outdated version is turned down before reaching the store
+	comments.AssertNumberOfCalls(t, "Update", 3)
+}
@@ -0,0 +1,559 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type conversationKey string
+
+const conversationCtx conversationKey = "conversation"
+
+var (
+	errCannotMessage        = errors.New("you cannot message this user")
+	errConversationNotFound = errors.New("conversation not found")
+)
+
+type CreateConversationPayload struct {
+	UserIDs []int64 `json:"userIds" validate:"required,min=1,dive,gt=0"`
+}
+
+type SendMessagePayload struct {
+	Content string `json:"content" validate:"required,max=2000"`
+}
+
+type MarkReadPayload struct {
+	MessageID int64 `json:"messageId" validate:"required,gt=0"`
+}
+
+// CreateConversation godoc
+//
+//	@Summary		Starts a conversation
+//	@Description	Starts a conversation between the authenticated user and the given users. With a single user, the existing one-to-one conversation is returned if there is one. Users who blocked or were blocked by the authenticated user, and private accounts that don't follow them, cannot be messaged
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateConversationPayload	true	"Conversation members"
+//	@Success		200		{object}	models.Conversation			"Existing conversation"
+//	@Success		201		{object}	models.Conversation			"New conversation"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations [post]
+func (app *application) createConversationHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateConversationPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	slices.Sort(payload.UserIDs)
+	otherIDs := slices.DeleteFunc(slices.Compact(payload.UserIDs), func(id int64) bool {
+		return id == user.ID
+	})
+
+	switch {
+	case len(otherIDs) == 0:
+		app.badRequestResponse(w, r, fmt.Errorf("cannot start a conversation with yourself"))
+		return
+	case len(otherIDs)+1 > app.config.messages.maxGroupSize:
+		app.badRequestResponse(w, r, fmt.Errorf("conversations cannot have more than %d members", app.config.messages.maxGroupSize))
+		return
+	}
+
+	ctx := r.Context()
+
+	if err := app.checkCanMessage(ctx, user.ID, otherIDs); err != nil {
+		switch {
+		case errors.Is(err, errCannotMessage):
+			app.forbiddenResponse(w, r)
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	isGroup := len(otherIDs) > 1
+
+	if !isGroup {
+		conversation, err := app.store.Conversations.GetDirect(ctx, user.ID, otherIDs[0])
+		switch {
+		case err == nil:
+			if err := app.JSONResponse(w, http.StatusOK, conversation); err != nil {
+				app.internalServerError(w, r, err)
+			}
+			return
+		case !errors.Is(err, store.ErrNotFound):
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	conversation := &models.Conversation{
+		IsGroup:   isGroup,
+		CreatedBy: user.ID,
+	}
+
+	if err := app.store.Conversations.Create(ctx, conversation, append(otherIDs, user.ID)); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	conversation, err = app.store.Conversations.GetByID(ctx, conversation.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusCreated, conversation); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetConversations godoc
+//
+//	@Summary		Fetches the authenticated user's conversations
+//	@Description	Fetches the conversations of the authenticated user, the most recently active first, with their last message and unread count
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of conversations to return"	default(20)	minimum(1)	maximum(50)
+//	@Param			offset	query		int	false	"Number of conversations to skip"	default(0)	minimum(0)
+//	@Success		200		{object}	[]models.Conversation
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations [get]
+func (app *application) getConversationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	limit, offset := 20, 0
+	qs := r.URL.Query()
+
+	if l := qs.Get("limit"); l != "" {
+		if limit, err = strconv.Atoi(l); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if o := qs.Get("offset"); o != "" {
+		if offset, err = strconv.Atoi(o); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	if err := Validate.Var(limit, "gte=1,lte=50"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Var(offset, "gte=0"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	conversations, err := app.store.Conversations.GetByUserID(r.Context(), user.ID, limit, offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, conversations); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetConversation godoc
+//
+//	@Summary		Fetches a conversation
+//	@Description	Fetches a conversation of the authenticated user, with the last message each member read
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationID	path		int	true	"Conversation ID"
+//	@Success		200				{object}	models.Conversation
+//	@Failure		401				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationID} [get]
+func (app *application) getConversationHandler(w http.ResponseWriter, r *http.Request) {
+	conversation := getConversationFromCtx(r)
+
+	if err := app.JSONResponse(w, http.StatusOK, conversation); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetMessages godoc
+//
+//	@Summary		Fetches the messages of a conversation
+//	@Description	Fetches the messages of a conversation, newest first. Older messages are reached through the next link
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationID	path		int		true	"Conversation ID"
+//	@Param			limit			query		int		false	"Number of messages to return"	default(50)	minimum(1)	maximum(100)
+//	@Param			cursor			query		string	false	"Opaque cursor taken from the Link header"
+//	@Success		200				{object}	[]models.Message
+//	@Header			200				{string}	Link	"next and prev page links"
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationID}/messages [get]
+func (app *application) getMessagesHandler(w http.ResponseWriter, r *http.Request) {
+	conversation := getConversationFromCtx(r)
+
+	mq, err := utils.PaginatedMessageQuery{Limit: 50}.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(mq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mq.Keyset, err = app.decodeCursor(mq.Cursor)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	messages, err := app.store.Messages.GetByConversationID(r.Context(), conversation.ID, mq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(messages) > 0 {
+		first, last := messages[0], messages[len(messages)-1]
+		app.setPaginationLinks(
+			w, r, mq.Keyset, 0, mq.Limit, len(messages),
+			utils.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		)
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, messages); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// SendMessage godoc
+//
+//	@Summary		Sends a message
+//	@Description	Sends a message to a conversation, delivering it live to the members connected to the conversations socket
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationID	path		int					true	"Conversation ID"
+//	@Param			payload			body		SendMessagePayload	true	"Message"
+//	@Success		201				{object}	models.Message
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		403				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationID}/messages [post]
+func (app *application) sendMessageHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload SendMessagePayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	message, err := app.sendMessage(r.Context(), getConversationFromCtx(r), user.ID, payload.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, errCannotMessage):
+			app.forbiddenResponse(w, r)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusCreated, message); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// MarkConversationRead godoc
+//
+//	@Summary		Marks a conversation as read
+//	@Description	Marks a conversation as read up to a message, sending a read receipt to the other members
+//	@Tags			conversations
+//	@Accept			json
+//	@Produce		json
+//	@Param			conversationID	path		int				true	"Conversation ID"
+//	@Param			payload			body		MarkReadPayload	true	"Last message read"
+//	@Success		204				{string}	string			"Marked as read"
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		404				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/{conversationID}/read [put]
+func (app *application) markConversationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload MarkReadPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.markRead(r.Context(), getConversationFromCtx(r), user.ID, payload.MessageID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "message not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// checkCanMessage makes sure userID can start a conversation with every one
+// of otherIDs: none of them blocked userID or was blocked by them, and the
+// ones with a private account follow userID.
+func (app *application) checkCanMessage(ctx context.Context, userID int64, otherIDs []int64) error {
+	blocked, err := app.store.Blocks.GetBlockedBetween(ctx, userID, otherIDs)
+	if err != nil {
+		return err
+	}
+
+	if len(blocked) > 0 {
+		return errCannotMessage
+	}
+
+	for _, id := range otherIDs {
+		other, err := app.store.Users.GetByID(ctx, id)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				return fmt.Errorf("user %d: %w", id, err)
+			}
+			return err
+		}
+
+		if !other.IsPrivate {
+			continue
+		}
+
+		followeeIDs, err := app.store.Followers.GetFolloweeIDs(ctx, other.ID)
+		if err != nil {
+			return err
+		}
+
+		if !slices.Contains(followeeIDs, userID) {
+			return errCannotMessage
+		}
+	}
+
+	return nil
+}
+
+// sendMessage stores a message and delivers it to the members of the
+// conversation. Blocks are checked again in one-to-one conversations, since
+// they may have come after the conversation started.
+func (app *application) sendMessage(ctx context.Context, conversation *models.Conversation, senderID int64, content string) (*models.Message, error) {
+	otherIDs := otherMemberIDs(conversation, senderID)
+
+	if !conversation.IsGroup {
+		blocked, err := app.store.Blocks.GetBlockedBetween(ctx, senderID, otherIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(blocked) > 0 {
+			return nil, errCannotMessage
+		}
+	}
+
+	message := &models.Message{
+		ConversationID: conversation.ID,
+		SenderID:       senderID,
+		Content:        content,
+	}
+
+	if err := app.store.Messages.Create(ctx, message); err != nil {
+		return nil, err
+	}
+
+	// the sender's other sockets get it too
+	app.signalEvent(ctx, append(otherIDs, senderID), events.TypeMessage, message)
+
+	return message, nil
+}
+
+// markRead moves the read marker of a member and sends a read receipt to the
+// other members.
+func (app *application) markRead(ctx context.Context, conversation *models.Conversation, userID, messageID int64) error {
+	if err := app.store.Conversations.MarkRead(ctx, conversation.ID, userID, messageID); err != nil {
+		return err
+	}
+
+	receipt := models.ReadReceipt{
+		ConversationID: conversation.ID,
+		UserID:         userID,
+		MessageID:      messageID,
+	}
+
+	app.signalEvent(ctx, otherMemberIDs(conversation, userID), events.TypeRead, receipt)
+
+	return nil
+}
+
+// signalEvent sends an ephemeral event to the users connected right now.
+// Like publishEvent, failures are only logged.
+func (app *application) signalEvent(ctx context.Context, userIDs []int64, typ string, data any) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	e, err := events.NewEvent(typ, data)
+	if err == nil {
+		err = app.events.Signal(ctx, userIDs, e)
+	}
+
+	if err != nil {
+		app.logger.Errorw("error signaling event", "type", typ, "error", err)
+	}
+}
+
+func isMember(conversation *models.Conversation, userID int64) bool {
+	return slices.ContainsFunc(conversation.Members, func(m models.ConversationMember) bool {
+		return m.ID == userID
+	})
+}
+
+func otherMemberIDs(conversation *models.Conversation, userID int64) []int64 {
+	var ids []int64
+	for _, m := range conversation.Members {
+		if m.ID != userID {
+			ids = append(ids, m.ID)
+		}
+	}
+
+	return ids
+}
+
+// getConversation fetches a conversation the user is a member of. Other
+// conversations are reported as not found.
+func (app *application) getConversation(ctx context.Context, conversationID, userID int64) (*models.Conversation, error) {
+	conversation, err := app.store.Conversations.GetByID(ctx, conversationID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, errConversationNotFound
+		}
+		return nil, err
+	}
+
+	if !isMember(conversation, userID) {
+		return nil, errConversationNotFound
+	}
+
+	return conversation, nil
+}
+
+func (app *application) conversationContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conversationID, err := strconv.ParseInt(chi.URLParam(r, "conversationID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user, err := getAuthUserFromContext(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		conversation, err := app.getConversation(r.Context(), conversationID, user.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, errConversationNotFound):
+				app.notFoundResponse(w, r, err, "conversation not found")
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), conversationCtx, conversation)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getConversationFromCtx(r *http.Request) *models.Conversation {
+	conversation, _ := r.Context().Value(conversationCtx).(*models.Conversation)
+
+	return conversation
+}
@@ -0,0 +1,300 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func newConversation(id int64, isGroup bool, memberIDs ...int64) *models.Conversation {
+	conversation := &models.Conversation{ID: id, IsGroup: isGroup}
+	for _, memberID := range memberIDs {
+		conversation.Members = append(conversation.Members, models.ConversationMember{ID: memberID})
+	}
+
+	return conversation
+}
+
+func TestConversationContext(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conversations := app.store.Conversations.(*store.MockConversationStore)
+	conversations.On("GetByID", int64(1)).Return(newConversation(1, false, 1, 2), nil)
+	conversations.On("GetByID", int64(2)).Return(newConversation(2, true, 2, 3, 4), nil)
+	conversations.On("GetByID", int64(3)).Return(nil, store.ErrNotFound)
+
+	tests := []struct {
+		name           string
+		conversationID string
+		want           int
+	}{
+		{name: "member", conversationID: "1", want: http.StatusOK},
+		{name: "not a member", conversationID: "2", want: http.StatusNotFound},
+		{name: "missing", conversationID: "3", want: http.StatusNotFound},
+		{name: "invalid ID", conversationID: "one", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/conversations/"+tt.conversationID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+}
+
+func TestCheckCanMessage(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	// user 3 has a private account and follows user 1, user 4 has one too
+	// but doesn't
+	app.store.Users.(*store.MockUserStore).Users = map[int64]*models.User{
+		2: {ID: 2},
+		3: {ID: 3, IsPrivate: true},
+		4: {ID: 4, IsPrivate: true},
+	}
+
+	blocks := app.store.Blocks.(*store.MockBlockStore)
+	blocks.On("GetBlockedBetween", int64(1), []int64{5}).Return([]int64{5}, nil)
+	blocks.On("GetBlockedBetween", int64(1), []int64{2, 5}).Return([]int64{5}, nil)
+	blocks.On("GetBlockedBetween", int64(1), mock.Anything).Return(nil, nil)
+
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.On("GetFolloweeIDs", int64(3)).Return([]int64{1, 2}, nil)
+	followers.On("GetFolloweeIDs", int64(4)).Return([]int64{2}, nil)
+
+	tests := []struct {
+		name     string
+		otherIDs []int64
+		want     error
+	}{
+		{name: "public account", otherIDs: []int64{2}},
+		{name: "private account following back", otherIDs: []int64{3}},
+		{name: "private account not following back", otherIDs: []int64{4}, want: errCannotMessage},
+		{name: "blocked", otherIDs: []int64{5}, want: errCannotMessage},
+		{name: "group with someone blocked", otherIDs: []int64{2, 5}, want: errCannotMessage},
+		{name: "group with a private account not following back", otherIDs: []int64{2, 3, 4}, want: errCannotMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := app.checkCanMessage(context.Background(), 1, tt.otherIDs)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("checkCanMessage(%v) error = %v, want %v", tt.otherIDs, err, tt.want)
+			}
+		})
+	}
+
+	t.Run("should forbid starting a conversation", func(t *testing.T) {
+		app.config.messages.maxGroupSize = 10
+		mux := app.mount()
+
+		testToken, err := app.authenticator.GenerateToken(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/conversations", strings.NewReader(`{"userIds": [4]}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusForbidden, rr.Code)
+	})
+}
+
+func TestHandleFrame(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: 1}
+
+	newApp := func(t *testing.T) *application {
+		app := newTestApplication(t, config{stream: streamConfig{replaySize: 10}})
+
+		conversations := app.store.Conversations.(*store.MockConversationStore)
+		conversations.On("GetByID", int64(1)).Return(newConversation(1, false, 1, 2), nil)
+		conversations.On("GetByID", int64(2)).Return(newConversation(2, true, 2, 3), nil)
+		conversations.On("GetByID", int64(3)).Return(newConversation(3, false, 1, 3), nil)
+
+		blocks := app.store.Blocks.(*store.MockBlockStore)
+		blocks.On("GetBlockedBetween", int64(1), []int64{2}).Return(nil, nil)
+		blocks.On("GetBlockedBetween", int64(1), []int64{3}).Return([]int64{3}, nil)
+
+		return app
+	}
+
+	// receive waits for the next event sent to a subscription
+	receive := func(t *testing.T, sub *events.Subscription) events.Event {
+		t.Helper()
+
+		select {
+		case e := <-sub.Events():
+			return e
+		case <-time.After(time.Second):
+			t.Fatal("no event received")
+			return events.Event{}
+		}
+	}
+
+	t.Run("should send messages to the other members", func(t *testing.T) {
+		app := newApp(t)
+
+		messages := app.store.Messages.(*store.MockMessageStore)
+		messages.On("Create", mock.Anything).Return(nil)
+
+		_, sub, err := app.events.Subscribe(ctx, 2, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		frame := clientFrame{Type: "message", ConversationID: 1, Content: "hello"}
+		if err := app.handleFrame(ctx, user, frame); err != nil {
+			t.Fatalf("handleFrame() error = %v", err)
+		}
+
+		messages.AssertCalled(t, "Create", mock.MatchedBy(func(m *models.Message) bool {
+			return m.ConversationID == 1 && m.SenderID == 1 && m.Content == "hello"
+		}))
+
+		if e := receive(t, sub); e.Type != events.TypeMessage {
+			t.Errorf("got a %q event, want %q", e.Type, events.TypeMessage)
+		}
+	})
+
+	t.Run("should signal typing without storing anything", func(t *testing.T) {
+		app := newApp(t)
+
+		_, sub, err := app.events.Subscribe(ctx, 2, "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		frame := clientFrame{Type: "typing", ConversationID: 1}
+		if err := app.handleFrame(ctx, user, frame); err != nil {
+			t.Fatalf("handleFrame() error = %v", err)
+		}
+
+		if e := receive(t, sub); e.Type != events.TypeTyping {
+			t.Errorf("got a %q event, want %q", e.Type, events.TypeTyping)
+		}
+
+		app.store.Messages.(*store.MockMessageStore).AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should mark conversations as read", func(t *testing.T) {
+		app := newApp(t)
+
+		conversations := app.store.Conversations.(*store.MockConversationStore)
+		conversations.On("MarkRead", int64(1), int64(1), int64(7)).Return(nil)
+		conversations.On("MarkRead", int64(1), int64(1), int64(8)).Return(store.ErrNotFound)
+
+		if err := app.handleFrame(ctx, user, clientFrame{Type: "read", ConversationID: 1, MessageID: 7}); err != nil {
+			t.Errorf("handleFrame() error = %v", err)
+		}
+
+		err := app.handleFrame(ctx, user, clientFrame{Type: "read", ConversationID: 1, MessageID: 8})
+		if err == nil || err.Error() != "message not found" {
+			t.Errorf("handleFrame() error = %v, want message not found", err)
+		}
+	})
+
+	t.Run("should reject invalid frames", func(t *testing.T) {
+		app := newApp(t)
+
+		tests := []struct {
+			name  string
+			frame clientFrame
+			want  string
+		}{
+			{name: "unknown type", frame: clientFrame{Type: "shout", ConversationID: 1}, want: "invalid frame"},
+			{name: "no conversation", frame: clientFrame{Type: "typing"}, want: "invalid frame"},
+			{name: "empty message", frame: clientFrame{Type: "message", ConversationID: 1}, want: "invalid message"},
+			{name: "message too long", frame: clientFrame{Type: "message", ConversationID: 1, Content: strings.Repeat("a", 2001)}, want: "invalid message"},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				err := app.handleFrame(ctx, user, tt.frame)
+				if err == nil || err.Error() != tt.want {
+					t.Errorf("handleFrame() error = %v, want %s", err, tt.want)
+				}
+			})
+		}
+
+		app.store.Messages.(*store.MockMessageStore).AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should not act on the conversations of others", func(t *testing.T) {
+		app := newApp(t)
+
+		err := app.handleFrame(ctx, user, clientFrame{Type: "message", ConversationID: 2, Content: "hello"})
+		if !errors.Is(err, errConversationNotFound) {
+			t.Errorf("handleFrame() error = %v, want %v", err, errConversationNotFound)
+		}
+
+		app.store.Messages.(*store.MockMessageStore).AssertNotCalled(t, "Create", mock.Anything)
+	})
+
+	t.Run("should not send messages to blocked users", func(t *testing.T) {
+		app := newApp(t)
+
+		err := app.handleFrame(ctx, user, clientFrame{Type: "message", ConversationID: 3, Content: "hello"})
+		if !errors.Is(err, errCannotMessage) {
+			t.Errorf("handleFrame() error = %v, want %v", err, errCannotMessage)
+		}
+
+		app.store.Messages.(*store.MockMessageStore).AssertNotCalled(t, "Create", mock.Anything)
+	})
+}
+
+func TestSocketToken(t *testing.T) {
+	var gotAuthorization, gotURI string
+
+	handler := StripTokenMiddleware(SocketTokenMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotURI = r.RequestURI
+	})))
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/conversations/ws?token=secret&v=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.RequestURI = req.URL.RequestURI()
+
+	executeRequest(handler, req)
+
+	if gotAuthorization != "Bearer secret" {
+		t.Errorf("got Authorization %q, want the token from the query", gotAuthorization)
+	}
+
+	if strings.Contains(gotURI, "secret") {
+		t.Errorf("got request URI %q, want the token left out", gotURI)
+	}
+}
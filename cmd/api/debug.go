@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var errMailNotCaptured = errors.New("mails are not captured")
+
+// ListCapturedMail godoc
+//
+//	@Summary		Lists the captured mails
+//	@Description	Lists the mails kept by the capture mailer, the latest first. Only available when MAIL_PROVIDER is capture
+//	@Tags			debug
+//	@Produce		json
+//	@Success		200	{array}		mailer.CapturedMail
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		404	{object}	error	"Mails are not captured"
+//	@Security		ApiKeyAuth
+//	@Router			/debug/mail [get]
+func (app *application) listCapturedMailHandler(w http.ResponseWriter, r *http.Request) {
+	capture, ok := app.mailer.(*mailer.CaptureClient)
+	if !ok {
+		app.notFoundResponse(w, r, errMailNotCaptured, errMailNotCaptured.Error())
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, capture.Mails()); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetCapturedMail godoc
+//
+//	@Summary		Shows a captured mail
+//	@Description	Renders the HTML body of a mail kept by the capture mailer, its subject in the X-Mail-Subject header
+//	@Tags			debug
+//	@Produce		html
+//	@Param			mailID	path		string	true	"Mail ID"
+//	@Success		200		{string}	string
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error
+//	@Failure		404		{object}	error	"Mail not found"
+//	@Security		ApiKeyAuth
+//	@Router			/debug/mail/{mailID} [get]
+func (app *application) getCapturedMailHandler(w http.ResponseWriter, r *http.Request) {
+	capture, ok := app.mailer.(*mailer.CaptureClient)
+	if !ok {
+		app.notFoundResponse(w, r, errMailNotCaptured, errMailNotCaptured.Error())
+		return
+	}
+
+	mail, ok := capture.Mail(chi.URLParam(r, "mailID"))
+	if !ok {
+		app.notFoundResponse(w, r, errors.New("captured mail not found"), "mail not found")
+		return
+	}
+
+	writeMailHTML(w, mail.Subject, mail.Body)
+}
+
+// ListMailTemplates godoc
+//
+//	@Summary		Lists the mail templates
+//	@Description	Lists the templates mails are rendered from, to preview them
+//	@Tags			debug
+//	@Produce		json
+//	@Success		200	{array}		string
+//	@Failure		401	{object}	error
+//	@Failure		403	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/debug/mail/templates [get]
+func (app *application) listMailTemplatesHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := mailer.Templates()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, templates); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// PreviewMailTemplate godoc
+//
+//	@Summary		Previews a mail template
+//	@Description	Renders the HTML body of a template with sample data, its subject in the X-Mail-Subject header
+//	@Tags			debug
+//	@Produce		html
+//	@Param			template	path		string	true	"Template file, e.g. user_invitation.tmpl"
+//	@Success		200			{string}	string
+//	@Failure		401			{object}	error
+//	@Failure		403			{object}	error
+//	@Failure		404			{object}	error	"Template not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/debug/mail/templates/{template} [get]
+func (app *application) previewMailTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	templates, err := mailer.Templates()
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	name := chi.URLParam(r, "template")
+	if !slices.Contains(templates, name) {
+		app.notFoundResponse(w, r, errors.New("mail template not found"), "template not found")
+		return
+	}
+
+	subject, body, err := mailer.Render(name, mailer.SampleData(name))
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	writeMailHTML(w, subject, body)
+}
+
+// writeMailHTML serves the body of a mail as a page of its own. The sandbox
+// keeps the scripts a mail could carry from running on the API's origin.
+func writeMailHTML(w http.ResponseWriter, subject, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	w.Header().Set("X-Mail-Subject", subject)
+	w.WriteHeader(http.StatusOK)
+
+	_, _ = w.Write([]byte(body))
+}
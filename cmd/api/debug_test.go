@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func TestDebugMail(t *testing.T) {
+	app := newTestApplication(t, config{})
+
+	// the handlers, without the admin check of the routes
+	mux := chi.NewRouter()
+	mux.Get("/mail", app.listCapturedMailHandler)
+	mux.Get("/mail/templates/{template}", app.previewMailTemplateHandler)
+	mux.Get("/mail/{mailID}", app.getCapturedMailHandler)
+
+	capture := app.mailer.(*mailer.CaptureClient)
+
+	data := map[string]any{"Username": "gopher", "ActivationURL": "http://localhost:3000/confirm/token"}
+	if err := capture.Send(mailer.UserWelcomeTemplate, "gopher", "gopher@example.com", data, true); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("should list the captured mails", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/mail", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(mux, req)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data []mailer.CapturedMail `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res.Data) != 1 || res.Data[0].Email != "gopher@example.com" {
+			t.Errorf("got mails %+v, want gopher's", res.Data)
+		}
+	})
+
+	t.Run("should show a captured mail", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/mail/"+capture.Mails()[0].ID, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(mux, req)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if !strings.Contains(rr.Body.String(), "http://localhost:3000/confirm/token") {
+			t.Errorf("mail lacks the activation link:\n%s", rr.Body)
+		}
+	})
+
+	t.Run("should preview a template", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/mail/templates/"+mailer.DigestTemplate, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(mux, req)
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		if got := rr.Header().Get("X-Mail-Subject"); got != "Your weekly GopherSocial digest" {
+			t.Errorf("got subject %q", got)
+		}
+	})
+
+	t.Run("should not preview unknown templates", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/mail/templates/missing.tmpl", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		rr := executeRequest(mux, req)
+		checkResponseCode(t, http.StatusNotFound, rr.Code)
+	})
+}
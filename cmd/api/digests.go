@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/digest"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type UpdateDigestSettingsPayload struct {
+	Frequency string `json:"frequency" validate:"required,oneof=off daily weekly"`
+}
+
+// GetDigestSettings godoc
+//
+//	@Summary		Fetches the email digest settings
+//	@Description	Tells how often the authenticated user gets an email digest of their activity: off, daily or weekly
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	models.DigestSettings
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/digest [get]
+func (app *application) getDigestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	settings, err := app.store.Digests.GetSettings(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// UpdateDigestSettings godoc
+//
+//	@Summary		Updates the email digest settings
+//	@Description	Sets how often the authenticated user gets an email digest of their new followers, the top posts of the users they follow and the replies to them. Daily digests cover the last day and weekly ones the last week, from Monday, in UTC
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		UpdateDigestSettingsPayload	true	"Digest frequency"
+//	@Success		200		{object}	models.DigestSettings
+//	@Failure		400		{object}	error	"Invalid payload"
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/digest [put]
+func (app *application) updateDigestSettingsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload UpdateDigestSettingsPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Digests.SetFrequency(r.Context(), user.ID, payload.Frequency); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "user not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	settings := models.DigestSettings{Frequency: payload.Frequency}
+
+	if err := app.JSONResponse(w, http.StatusOK, settings); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// UnsubscribeDigest godoc
+//
+//	@Summary		Unsubscribes from the email digests
+//	@Description	Turns off the email digests of the user the token of an unsubscribe link was made for, without signing in
+//	@Tags			users
+//	@Produce		json
+//	@Param			token	path		string	true	"Unsubscribe token"
+//	@Success		204		{object}	string	"Unsubscribed"
+//	@Failure		400		{object}	error	"Invalid token"
+//	@Failure		500		{object}	error
+//	@Router			/users/digest/unsubscribe/{token} [put]
+func (app *application) unsubscribeDigestHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := digest.ParseUnsubscribeToken(chi.URLParam(r, "token"), app.config.digest.Secret)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Digests.SetFrequency(r.Context(), userID, models.DigestOff); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			// the account is gone, and so are its digests
+		default:
+			app.internalServerError(w, r, err)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetExploreFeed godoc
+//
+//	@Summary		Fetches the explore feed
+//	@Description	Fetches the recent posts of everyone, with optional filters. It doesn't require authentication, but is rate limited more strictly
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int			false	"Number of posts to return"		default(20)		minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Number of posts to skip"		default(0)		minimum(0)
+//	@Param			cursor	query		string		false	"Opaque cursor taken from the Link header, replaces offset"
+//	@Param			sort	query		string		false	"Sort order: 'asc' or 'desc'"	default(desc)	enum(asc,desc)
+//	@Param			search	query		string		false	"Search term to filter posts by title or content"
+//	@Param			tags	query		[]string	false	"Filter posts by tags"	minItems(1)	maxItems(5)	items(minLength(2),maxLength(30))
+//	@Param			since	query		string		false	"Only posts from this time on, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Param			until	query		string		false	"Only posts before this time, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Success		200		{object}	[]models.PostWithMetadata
+//	@Header			200		{string}	Link	"next and prev page links"
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/posts/explore [get]
+func (app *application) getExploreFeedHandler(w http.ResponseWriter, r *http.Request) {
+	fq, err := app.parseFeedQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.publicFeed(w, r, fq)
+}
+
+// GetTagPosts godoc
+//
+//	@Summary		Fetches the posts of a tag
+//	@Description	Fetches the recent posts tagged with the given tag, with optional filters. It doesn't require authentication, but is rate limited more strictly
+//	@Tags			feed
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag		path		string		true	"Tag"
+//	@Param			limit	query		int			false	"Number of posts to return"		default(20)		minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Number of posts to skip"		default(0)		minimum(0)
+//	@Param			cursor	query		string		false	"Opaque cursor taken from the Link header, replaces offset"
+//	@Param			sort	query		string		false	"Sort order: 'asc' or 'desc'"	default(desc)	enum(asc,desc)
+//	@Param			search	query		string		false	"Search term to filter posts by title or content"
+//	@Param			tags	query		[]string	false	"Other tags the posts must have"	maxItems(4)	items(minLength(2),maxLength(30))
+//	@Param			since	query		string		false	"Only posts from this time on, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Param			until	query		string		false	"Only posts before this time, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Success		200		{object}	[]models.PostWithMetadata
+//	@Header			200		{string}	Link	"next and prev page links"
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/tags/{tag}/posts [get]
+func (app *application) getTagPostsHandler(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "tag")
+	if err := Validate.Var(tag, "min=2,max=30"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq, err := app.parseFeedQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq.Tags = append(fq.Tags, tag)
+	if err := Validate.Struct(fq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	app.publicFeed(w, r, fq)
+}
+
+func (app *application) publicFeed(w http.ResponseWriter, r *http.Request, fq utils.PaginatedFeedQuery) {
+	// ranking is based on the affinity of the viewer with the authors
+	if fq.Mode == "top" {
+		app.badRequestResponse(w, r, errors.New("the top mode is only available on the home feed"))
+		return
+	}
+
+	feed, err := app.store.Posts.GetPublicFeed(r.Context(), fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(feed) > 0 {
+		first, last := feed[0], feed[len(feed)-1]
+		app.setPaginationLinks(
+			w, r, fq.Keyset, fq.Offset, fq.Limit, len(feed),
+			utils.Cursor{CreatedAt: first.CreatedAt, ID: first.ID},
+			utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID},
+		)
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, feed); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
@@ -2,23 +2,31 @@ package main
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/ranking"
 	"github.com/sandoxlabs99/gopher_social/internal/utils"
 )
 
 // GetUserFeed godoc
 //
 //	@Summary		Fetches the authenticated users feed
-//	@Description	Fetches the authenticated users feed with optional filters
+//	@Description	Fetches the posts authored or reposted by the users the authenticated user follows, the posts tagged with the tags they follow, and their own posts, with optional filters. Each post tells the reason it is on the feed
 //	@Tags			feed
 //	@Accept			json
 //	@Produce		json
 //	@Param			limit	query		int			false	"Number of posts to return"		default(20)		minimum(1)	maximum(100)
 //	@Param			offset	query		int			false	"Number of posts to skip"		default(0)		minimum(0)
+//	@Param			cursor	query		string		false	"Opaque cursor taken from the Link header, replaces offset"
 //	@Param			sort	query		string		false	"Sort order: 'asc' or 'desc'"	default(desc)	enum(asc,desc)
+//	@Param			mode	query		string		false	"'chronological', or 'top' to rank the posts of the last week by engagement, sort is ignored"	default(chronological)	enum(chronological,top)
 //	@Param			search	query		string		false	"Search term to filter posts by title or content"
 //	@Param			tags	query		[]string	false	"Filter posts by tags"	minItems(1)	maxItems(5)	items(minLength(2),maxLength(30))
+//	@Param			since	query		string		false	"Only posts from this time on, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Param			until	query		string		false	"Only posts before this time, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
 //	@Success		200		{object}	[]models.PostWithMetadata
+//	@Header			200		{string}	Link	"next and prev page links"
 //	@Failure		400		{object}	error
 //	@Failure		401		{object}	error
 //	@Failure		404		{object}	error
@@ -26,34 +34,131 @@ import (
 //	@Security		ApiKeyAuth
 //	@Router			/users/feed [get]
 func (app *application) getUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	fq, err := app.parseFeedQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq.WithOwnPosts = app.config.feed.includeOwnPosts
+
+	if fq.Mode == "top" {
+		app.getTopFeed(w, r, user.ID, fq)
+		return
+	}
+
+	var feed []models.PostWithMetadata
+
+	if app.timeline.CanServe(fq) {
+		feed, err = app.timeline.Feed(r.Context(), user.ID, fq)
+	} else {
+		feed, err = app.store.Posts.GetUserFeed(r.Context(), user.ID, fq)
+	}
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(feed) > 0 {
+		first, last := feed[0], feed[len(feed)-1]
+		app.setPaginationLinks(
+			w, r, fq.Keyset, fq.Offset, fq.Limit, len(feed),
+			utils.Cursor{CreatedAt: first.ActivityAt, ID: first.ID},
+			utils.Cursor{CreatedAt: last.ActivityAt, ID: last.ID},
+		)
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, feed); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// parseFeedQuery parses, validates and decodes the cursor of the feed query
+// params of a request.
+func (app *application) parseFeedQuery(r *http.Request) (utils.PaginatedFeedQuery, error) {
 	fq := utils.PaginatedFeedQuery{
 		Limit:  20,
 		Offset: 0,
 		Sort:   "desc",
+		Mode:   "chronological",
 		Search: "",
 		Tags:   []string{},
 	}
 
 	fq, err := fq.Parse(r)
 	if err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+		return fq, err
 	}
 
 	if err := Validate.Struct(fq); err != nil {
-		app.badRequestResponse(w, r, err)
-		return
+		return fq, err
 	}
 
-	userID := int64(25)
-	feed, err := app.store.Posts.GetUserFeed(r.Context(), userID, fq)
+	fq.Keyset, err = app.decodeCursor(fq.Cursor)
+
+	return fq, err
+}
+
+// getTopFeed serves the ranked feed. The candidates are ranked as of the time
+// the first page was requested, which the cursors carry along, so that pages
+// don't shift as posts get more engagement or older while the user scrolls.
+func (app *application) getTopFeed(w http.ResponseWriter, r *http.Request, userID int64, fq utils.PaginatedFeedQuery) {
+	asOf := time.Now().UTC().Truncate(time.Second)
+	if fq.Keyset != nil && fq.Keyset.AsOf != nil {
+		asOf = *fq.Keyset.AsOf
+	}
+
+	candidates, err := app.store.Posts.GetRankingCandidates(r.Context(), userID, fq, asOf, ranking.Window, ranking.MaxCandidates)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	ranked := ranking.Rank(candidates, asOf, ranking.DefaultWeights)
+
+	var feed []models.PostWithMetadata
+
+	switch c := fq.Keyset; {
+	case c == nil:
+		start := min(fq.Offset, len(ranked))
+		end := min(fq.Offset+fq.Limit, len(ranked))
+		feed = ranked[start:end]
+	case c.Direction == utils.CursorPrev:
+		feed = ranking.Before(ranked, rankedAt(c))
+		feed = feed[max(len(feed)-fq.Limit, 0):]
+	default:
+		feed = ranking.After(ranked, rankedAt(c))
+		feed = feed[:min(fq.Limit, len(feed))]
+	}
+
+	if len(feed) > 0 {
+		first, last := feed[0], feed[len(feed)-1]
+		app.setPaginationLinks(
+			w, r, fq.Keyset, fq.Offset, fq.Limit, len(feed),
+			utils.Cursor{CreatedAt: first.CreatedAt, ID: first.ID, Score: first.Score, AsOf: &asOf},
+			utils.Cursor{CreatedAt: last.CreatedAt, ID: last.ID, Score: last.Score, AsOf: &asOf},
+		)
+	}
+
 	if err := app.JSONResponse(w, http.StatusOK, feed); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
+
+// rankedAt is the position in a ranked feed a cursor points to.
+func rankedAt(c *utils.Cursor) models.PostWithMetadata {
+	var p models.PostWithMetadata
+	p.ID = c.ID
+	p.CreatedAt = c.CreatedAt
+	p.Score = c.Score
+
+	return p
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetUserFeed(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repostedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	feed := []models.PostWithMetadata{
+		{
+			Post:        models.Post{ID: 7, UserID: 3, CreatedAt: repostedAt.Add(-time.Hour)},
+			RepostCount: 2,
+			RepostedBy:  &models.User{ID: 2, Username: "reposter"},
+			ActivityAt:  repostedAt,
+			Reason:      models.FeedReasonFollowedUser,
+		},
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetUserFeed", int64(1), mock.Anything).Return(feed, nil)
+
+	t.Run("should attribute reposts", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		var res struct {
+			Data []models.PostWithMetadata `json:"data"`
+		}
+		if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(res.Data) != 1 {
+			t.Fatalf("got %d posts, want 1", len(res.Data))
+		}
+
+		got := res.Data[0]
+		if got.RepostedBy == nil || got.RepostedBy.Username != "reposter" || got.RepostCount != 2 {
+			t.Errorf("got post %+v, want it reposted by reposter and counted twice", got)
+		}
+
+		if !got.ActivityAt.Equal(repostedAt) {
+			t.Errorf("got activity at %v, want the repost time %v", got.ActivityAt, repostedAt)
+		}
+	})
+
+	t.Run("should page from the repost time", func(t *testing.T) {
+		req, err := http.NewRequest(http.MethodGet, "/v1/users/feed?limit=1", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusOK, rr.Code)
+
+		link := rr.Header().Get("Link")
+		if link == "" {
+			t.Fatal("got no Link header, want a next page")
+		}
+
+		next := nextCursor(t, link)
+
+		c, err := utils.DecodeCursor(next, app.config.pagination.cursorSecret)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !c.CreatedAt.Equal(repostedAt) || c.ID != 7 {
+			t.Errorf("got cursor %+v, want one at the repost of post 7", c)
+		}
+	})
+}
+
+// nextCursor takes the cursor of the next page out of a Link header.
+func nextCursor(t *testing.T, link string) string {
+	t.Helper()
+
+	for _, l := range strings.Split(link, ", ") {
+		target, rel, _ := strings.Cut(l, "; ")
+		if rel != `rel="next"` {
+			continue
+		}
+
+		u, err := url.Parse(strings.Trim(target, "<>"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return u.Query().Get("cursor")
+	}
+
+	t.Fatalf("no next page in %q", link)
+	return ""
+}
+
+func TestGetUserFeedOwnPosts(t *testing.T) {
+	tests := []struct {
+		name            string
+		includeOwnPosts bool
+	}{
+		{name: "with own posts", includeOwnPosts: true},
+		{name: "without own posts", includeOwnPosts: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApplication(t, config{feed: feedConfig{includeOwnPosts: tt.includeOwnPosts}})
+			mux := app.mount()
+
+			testToken, err := app.authenticator.GenerateToken(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			posts := app.store.Posts.(*store.MockPostStore)
+			posts.On("GetUserFeed", int64(1), mock.Anything).Return(nil, nil)
+
+			req, err := http.NewRequest(http.MethodGet, "/v1/users/feed", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, http.StatusOK, rr.Code)
+
+			posts.AssertCalled(t, "GetUserFeed", int64(1), mock.MatchedBy(func(fq utils.PaginatedFeedQuery) bool {
+				return fq.WithOwnPosts == tt.includeOwnPosts
+			}))
+		})
+	}
+}
+
+func TestGetUserPosts(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app.store.Users.(*store.MockUserStore).Users = map[int64]*models.User{
+		5: {ID: 5},
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetUserPosts", int64(5), int64(1), mock.Anything).Return([]models.PostWithMetadata{
+		{Post: models.Post{ID: 9, UserID: 5}, CommentCount: 3},
+	}, nil)
+
+	req, err := http.NewRequest(http.MethodGet, "/v1/users/5/posts?search=gopher&tags=go&limit=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	rr := executeRequest(mux, req)
+
+	checkResponseCode(t, http.StatusOK, rr.Code)
+
+	// the profile timeline of user 5, as seen by user 1
+	posts.AssertCalled(t, "GetUserPosts", int64(5), int64(1), mock.MatchedBy(func(fq utils.PaginatedFeedQuery) bool {
+		return fq.Search == "gopher" && len(fq.Tags) == 1 && fq.Tags[0] == "go" && fq.Limit == 1
+	}))
+
+	if rr.Header().Get("Link") == "" {
+		t.Error("got no Link header, want a next page")
+	}
+
+	var res struct {
+		Data []models.PostWithMetadata `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&res); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(res.Data) != 1 || res.Data[0].CommentCount != 3 {
+		t.Errorf("got posts %+v, want post 9 with its 3 comments", res.Data)
+	}
+}
@@ -1,17 +1,27 @@
 package main
 
 import (
+	"context"
 	"expvar"
+	"io"
 	"runtime"
 	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/auth"
+	"github.com/sandoxlabs99/gopher_social/internal/blob"
 	"github.com/sandoxlabs99/gopher_social/internal/db"
+	"github.com/sandoxlabs99/gopher_social/internal/digest"
 	"github.com/sandoxlabs99/gopher_social/internal/env"
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/jobs"
 	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+	"github.com/sandoxlabs99/gopher_social/internal/outbox"
 	"github.com/sandoxlabs99/gopher_social/internal/ratelimiter"
 	"github.com/sandoxlabs99/gopher_social/internal/store"
 	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/timeline"
+	"github.com/sandoxlabs99/gopher_social/internal/trends"
+	"github.com/sandoxlabs99/gopher_social/internal/webhooks"
 
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
@@ -43,16 +53,23 @@ func main() {
 			maxLifeTime:  env.GetDuration("DB_MAX_LIFE_TIME", "1h"),
 		},
 		mail: mailConfig{
-			exp:       time.Minute * 15, // 15 minutes,
-			fromEmail: env.GetString("FROM_EMAIL", "Acme <onboarding@resend.dev>"),
-			sendGrid: sendGridConfig{
-				apiKey: env.GetString("SENDGRID_API_KEY", ""),
-			},
-			mailTrap: mailTrapConfig{
-				apiKey: env.GetString("MAILTRAP_API_KEY", ""),
-			},
-			resend: resendConfig{
-				apiKey: env.GetString("RESEND_API_KEY", ""),
+			exp: time.Minute * 15, // 15 minutes,
+			client: mailer.Config{
+				Provider:  env.GetString("MAIL_PROVIDER", "resend"),
+				FromEmail: env.GetString("FROM_EMAIL", "Acme <onboarding@resend.dev>"),
+				SMTP: mailer.SMTPConfig{
+					Host:        env.GetString("SMTP_HOST", "localhost"),
+					Port:        env.GetInt("SMTP_PORT", 1025),
+					Username:    env.GetString("SMTP_USERNAME", ""),
+					Password:    env.GetString("SMTP_PASSWORD", ""),
+					Security:    env.GetString("SMTP_SECURITY", "none"),
+					Auth:        env.GetString("SMTP_AUTH", "plain"),
+					IdleTimeout: env.GetDuration("SMTP_IDLE_TIMEOUT", "30s"),
+				},
+				CaptureDir:     env.GetString("MAIL_CAPTURE_DIR", ""),
+				ResendAPIKey:   env.GetString("RESEND_API_KEY", ""),
+				SendGridAPIKey: env.GetString("SENDGRID_API_KEY", ""),
+				MailTrapAPIKey: env.GetString("MAILTRAP_API_KEY", ""),
 			},
 		},
 		auth: authConfig{
@@ -73,12 +90,104 @@ func main() {
 			db:        env.GetInt("REDIS_DB", 0),
 		},
 		rateLimiter: ratelimiter.Config{
-			RequestsPerTimeFrame: env.GetInt("RATELIMITER_REQS_COUNT", 20),
-			TimeFrame:            5 * time.Second,
-			IsEnabled:            env.GetBool("IS_RATELIMITER_ENABLED", true),
+			RequestsPerTimeFrame:       env.GetInt("RATELIMITER_REQS_COUNT", 20),
+			PublicRequestsPerTimeFrame: env.GetInt("RATELIMITER_PUBLIC_REQS_COUNT", 5),
+			TimeFrame:                  5 * time.Second,
+			IsEnabled:                  env.GetBool("IS_RATELIMITER_ENABLED", true),
+		},
+		comments: commentsConfig{
+			maxDepth: env.GetInt("COMMENTS_MAX_DEPTH", 5),
+		},
+		pagination: paginationConfig{
+			cursorSecret: env.GetString("PAGINATION_CURSOR_SECRET", "example"),
+		},
+		feed: feedConfig{
+			includeOwnPosts: env.GetBool("FEED_INCLUDE_OWN_POSTS", true),
+		},
+		trends: trends.Config{
+			IsEnabled:  env.GetBool("IS_TRENDS_ENABLED", true),
+			Schedule:   env.GetString("TRENDS_SCHEDULE", "*/5 * * * *"),
+			MinAuthors: env.GetInt("TRENDS_MIN_AUTHORS", 3),
+			Limit:      env.GetInt("TRENDS_LIMIT", 50),
+		},
+		digest: digest.Config{
+			IsEnabled: env.GetBool("IS_DIGEST_ENABLED", true),
+			Schedule:  env.GetString("DIGEST_SCHEDULE", "*/15 * * * *"),
+			BatchSize: env.GetInt("DIGEST_BATCH_SIZE", 100),
+			Secret:    env.GetString("DIGEST_UNSUBSCRIBE_SECRET", "example"),
+		},
+		webhooks: webhooks.Config{
+			IsEnabled:    env.GetBool("IS_WEBHOOKS_ENABLED", true),
+			PollInterval: env.GetDuration("WEBHOOKS_POLL_INTERVAL", "5s"),
+			BatchSize:    env.GetInt("WEBHOOKS_BATCH_SIZE", 20),
+			Timeout:      env.GetDuration("WEBHOOKS_TIMEOUT", "10s"),
+			MaxAttempts:  env.GetInt("WEBHOOKS_MAX_ATTEMPTS", 8),
+			BaseDelay:    env.GetDuration("WEBHOOKS_BASE_DELAY", "30s"),
+			MaxDelay:     env.GetDuration("WEBHOOKS_MAX_DELAY", "6h"),
+			AllowPrivate: env.GetBool("WEBHOOKS_ALLOW_PRIVATE", false),
+		},
+		jobs: jobs.Config{
+			IsEnabled:    env.GetBool("IS_JOBS_ENABLED", true),
+			PollInterval: env.GetDuration("JOBS_POLL_INTERVAL", "1s"),
+			Concurrency:  env.GetInt("JOBS_CONCURRENCY", 10),
+			Timeout:      env.GetDuration("JOBS_TIMEOUT", "1m"),
+			MaxAttempts:  env.GetInt("JOBS_MAX_ATTEMPTS", 5),
+			BaseDelay:    env.GetDuration("JOBS_BASE_DELAY", "10s"),
+			MaxDelay:     env.GetDuration("JOBS_MAX_DELAY", "1h"),
+			Retention:    env.GetDuration("JOBS_RETENTION", "168h"),
+		},
+		outbox: outbox.Config{
+			IsEnabled:    env.GetBool("IS_OUTBOX_ENABLED", true),
+			PollInterval: env.GetDuration("OUTBOX_POLL_INTERVAL", "2s"),
+			BatchSize:    env.GetInt("OUTBOX_BATCH_SIZE", 20),
+			MaxAttempts:  env.GetInt("OUTBOX_MAX_ATTEMPTS", 6),
+			BaseDelay:    env.GetDuration("OUTBOX_BASE_DELAY", "10s"),
+			MaxDelay:     env.GetDuration("OUTBOX_MAX_DELAY", "5m"),
+		},
+		stream: streamConfig{
+			heartbeat:  env.GetDuration("STREAM_HEARTBEAT", "15s"),
+			replaySize: env.GetInt("STREAM_REPLAY_SIZE", 100),
+		},
+		messages: messagesConfig{
+			maxGroupSize: env.GetInt("MESSAGES_MAX_GROUP_SIZE", 10),
+		},
+		media: mediaConfig{
+			maxSize:       int64(env.GetInt("MEDIA_MAX_SIZE", 10<<20)), // 10MB
+			thumbnailSize: env.GetInt("MEDIA_THUMBNAIL_SIZE", 320),
+		},
+		blob: blob.Config{
+			Provider:  env.GetString("BLOB_PROVIDER", "local"),
+			PublicURL: env.GetString("BLOB_PUBLIC_URL", ""),
+			Local: blob.LocalConfig{
+				Dir: env.GetString("BLOB_LOCAL_DIR", "./uploads"),
+			},
+			S3: blob.S3Config{
+				Endpoint:  env.GetString("BLOB_S3_ENDPOINT", ""),
+				Region:    env.GetString("BLOB_S3_REGION", "us-east-1"),
+				Bucket:    env.GetString("BLOB_S3_BUCKET", ""),
+				AccessKey: env.GetString("BLOB_S3_ACCESS_KEY", ""),
+				SecretKey: env.GetString("BLOB_S3_SECRET_KEY", ""),
+			},
+		},
+		timeline: timeline.Config{
+			IsEnabled:       env.GetBool("IS_TIMELINE_ENABLED", true),
+			MaxLength:       env.GetInt("TIMELINE_MAX_LENGTH", 800),
+			FanoutThreshold: env.GetInt("TIMELINE_FANOUT_THRESHOLD", 10_000),
 		},
 	}
 
+	// timelines live in redis
+	cfg.timeline.IsEnabled = cfg.timeline.IsEnabled && cfg.redis.isEnabled
+	cfg.timeline.IncludeOwnPosts = cfg.feed.includeOwnPosts
+
+	cfg.digest.FrontendURL = cfg.frontendURL
+	cfg.digest.IsSandbox = cfg.namespace != "production"
+
+	// local files are served by the api itself
+	if cfg.blob.Provider == "local" && cfg.blob.PublicURL == "" {
+		cfg.blob.PublicURL = "http://" + cfg.apiURL + "/v1/media/files"
+	}
+
 	// Database
 	db, err := db.NewConn(
 		cfg.db.addr,
@@ -107,32 +216,73 @@ func main() {
 
 	redisStore := cache.NewRedisStorage(redisDB)
 
-	// mailer := mailer.NewSendGrid(cfg.mail.sendGrid.apiKey, cfg.mail.fromEmail, logger)
-	// mailTrap, err := mailer.NewMailTrapClient(cfg.mail.mailTrap.apiKey, cfg.mail.fromEmail)
-	// if err != nil {
-	// 	logger.Fatal(err)
-	// }
-	resend, err := mailer.NewResendClient(cfg.mail.resend.apiKey, cfg.mail.fromEmail, logger)
+	// Mailer
+	mailClient, err := mailer.New(cfg.mail.client, logger)
 	if err != nil {
 		logger.Fatal(err)
 	}
 
+	if closer, ok := mailClient.(io.Closer); ok {
+		defer closer.Close()
+	}
+
 	// rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowLimiter(
 		cfg.rateLimiter.RequestsPerTimeFrame,
 		cfg.rateLimiter.TimeFrame,
 	)
+	publicRateLimiter := ratelimiter.NewFixedWindowLimiter(
+		cfg.rateLimiter.PublicRequestsPerTimeFrame,
+		cfg.rateLimiter.TimeFrame,
+	)
+
+	blobStore, err := blob.New(cfg.blob)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	jwtAuthenticator := auth.NewJWTAuthenticator(cfg.auth.token.secret, cfg.auth.token.iss, cfg.auth.token.iss)
 
+	homeTimeline := timeline.NewService(store, redisStore, cfg.timeline)
+
+	// the event broker runs until the server stops
+	bgCtx, stopBg := context.WithCancel(context.Background())
+	defer stopBg()
+
+	// events go through redis to reach the streams of every instance
+	var broker events.Broker = events.NewMemoryBroker(cfg.stream.replaySize)
+	if cfg.redis.isEnabled {
+		broker = events.NewRedisBroker(bgCtx, redisDB, cfg.stream.replaySize, logger)
+	}
+
+	// jobs run from the server start to its shutdown
+	jobQueue := jobs.New(store, cfg.jobs, logger)
+
+	if err := trends.NewService(store, cfg.trends, logger).Register(jobQueue); err != nil {
+		logger.Fatal(err)
+	}
+
+	if err := digest.NewService(store, mailClient, cfg.digest, logger).Register(jobQueue); err != nil {
+		logger.Fatal(err)
+	}
+
 	app := &application{
-		config:        cfg,
-		store:         store,
-		logger:        logger,
-		mailer:        resend,
-		authenticator: jwtAuthenticator,
-		cacheStorage:  redisStore,
-		rateLimiter:   rateLimiter,
+		config:            cfg,
+		store:             store,
+		logger:            logger,
+		mailer:            mailClient,
+		authenticator:     jwtAuthenticator,
+		cacheStorage:      redisStore,
+		rateLimiter:       rateLimiter,
+		publicRateLimiter: publicRateLimiter,
+		timeline:          homeTimeline,
+		events:            broker,
+		blob:              blobStore,
+		jobs:              jobQueue,
+		workers: []func(context.Context){
+			webhooks.NewDispatcher(store, cfg.webhooks, logger).Run,
+			outbox.NewDispatcher(store, mailClient, cfg.outbox, logger).Run,
+		},
 	}
 
 	// expvar metrics collected
@@ -145,6 +295,18 @@ func main() {
 		return runtime.NumGoroutine()
 	}))
 
+	expvar.Publish("jobs", expvar.Func(func() any {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		stats, err := jobQueue.Stats(ctx)
+		if err != nil {
+			return err.Error()
+		}
+
+		return stats
+	}))
+
 	mux := app.mount()
 	logger.Fatal(app.run(mux))
 }
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/sandoxlabs99/gopher_social/internal/blob"
+	"github.com/sandoxlabs99/gopher_social/internal/media"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	errMissingFile  = errors.New("missing file field")
+	errFileTooLarge = errors.New("file is too large")
+)
+
+// UploadMedia godoc
+//
+//	@Summary		Upload an image
+//	@Description	Uploads a JPEG, PNG, GIF or WebP image to attach to a post. Its metadata is stripped and a thumbnail is generated. The returned ID goes in the mediaIds of the post
+//	@Tags			media
+//	@Accept			mpfd
+//	@Produce		json
+//	@Param			file	formData	file	true	"Image"
+//	@Success		201		{object}	models.Media
+//	@Failure		400		{object}	error	"Invalid image"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		413		{object}	error	"File too large"
+//	@Failure		415		{object}	error	"Unsupported media type"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/media [post]
+func (app *application) uploadMediaHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	data, err := app.readUpload(w, r)
+	if err != nil {
+		switch {
+		case errors.Is(err, errFileTooLarge):
+			app.payloadTooLargeResponse(w, r, err)
+		default:
+			app.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	img, err := media.Process(data, app.config.media.thumbnailSize)
+	if err != nil {
+		switch {
+		case errors.Is(err, media.ErrUnsupportedType):
+			app.unsupportedMediaTypeResponse(w, r, err)
+		case errors.Is(err, media.ErrTooLarge):
+			app.badRequestResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	m, err := app.storeImage(r, user.ID, "media", img)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.store.Media.Create(r.Context(), m); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusCreated, m); err != nil {
+		app.internalServerError(w, r, err)
+	}
+}
+
+// GetMediaFile godoc
+//
+//	@Summary		Fetch an uploaded file
+//	@Description	Serves the files uploaded to the local blob storage. Other providers serve them from their own URL
+//	@Tags			media
+//	@Produce		image/jpeg,image/png,image/gif
+//	@Param			key	path		string	true	"File key"
+//	@Success		200	{file}		binary
+//	@Failure		404	{object}	error	"File not found"
+//	@Failure		500	{object}	error
+//	@Router			/media/files/{key} [get]
+func (app *application) getMediaFileHandler(w http.ResponseWriter, r *http.Request) {
+	key := chi.URLParam(r, "*")
+
+	// every file has an extension, which rules out the directories
+	if !fs.ValidPath(key) || path.Ext(key) == "" {
+		app.notFoundResponse(w, r, blob.ErrNotFound, "file not found")
+		return
+	}
+
+	body, err := app.blob.Get(r.Context(), key)
+	if err != nil {
+		switch {
+		case errors.Is(err, blob.ErrNotFound):
+			app.notFoundResponse(w, r, err, "file not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+	defer body.Close()
+
+	w.Header().Set("Content-Type", mime.TypeByExtension(path.Ext(key)))
+	w.Header().Set("Cache-Control", blob.CacheControl)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if _, err := io.Copy(w, body); err != nil {
+		app.logger.Warnw("serving file", "key", key, "error", err.Error())
+	}
+}
+
+// readUpload reads the "file" part of a multipart upload, up to the
+// configured size.
+func (app *application) readUpload(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	maxSize := app.config.media.maxSize
+
+	// leaves room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize+1<<20)
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, errMissingFile
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, errFileTooLarge
+			}
+			return nil, err
+		}
+
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxSize+1))
+		part.Close()
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				return nil, errFileTooLarge
+			}
+			return nil, err
+		}
+
+		if int64(len(data)) > maxSize {
+			return nil, errFileTooLarge
+		}
+
+		return data, nil
+	}
+}
+
+// storeImage puts an image and its thumbnail in the blob store under random
+// keys in dir, and returns the media describing them.
+func (app *application) storeImage(r *http.Request, userID int64, dir string, img *media.Image) (*models.Media, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	name := fmt.Sprintf("%s/%d/%s", dir, userID, hex.EncodeToString(id))
+	key := name + img.Ext
+	thumbKey := name + "_thumb" + img.ThumbnailExt
+
+	if err := app.blob.Put(r.Context(), key, img.Data, img.ContentType); err != nil {
+		return nil, err
+	}
+
+	if err := app.blob.Put(r.Context(), thumbKey, img.Thumbnail, img.ThumbnailContentType); err != nil {
+		return nil, err
+	}
+
+	return &models.Media{
+		UserID:       userID,
+		Key:          key,
+		ThumbnailKey: thumbKey,
+		URL:          app.blob.URL(key),
+		ThumbnailURL: app.blob.URL(thumbKey),
+		ContentType:  img.ContentType,
+		Width:        img.Width,
+		Height:       img.Height,
+		Size:         len(img.Data),
+	}, nil
+}
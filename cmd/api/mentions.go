@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"slices"
+
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/mentions"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+)
+
+// GetMentions godoc
+//
+//	@Summary		Fetches the mentions of the authenticated user
+//	@Description	Fetches the posts and comments mentioning the authenticated user, the most recent first. Mentions by blocked users are left out
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int	false	"Number of mentions to return"	default(20)	minimum(1)	maximum(50)
+//	@Param			offset	query		int	false	"Number of mentions to skip"	default(0)	minimum(0)
+//	@Success		200		{object}	[]models.MentionOfUser
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/mentions [get]
+func (app *application) getMentionsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	pq := utils.PaginatedQuery{Limit: 20}
+
+	pq, err = pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	mentions, err := app.store.Mentions.GetByUserID(r.Context(), user.ID, pq.Limit, pq.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, mentions); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// resolveMentions finds the users mentioned in content written by authorID.
+// Unknown usernames are left as plain text, and so are the users who
+// blocked the author or were blocked by them.
+func (app *application) resolveMentions(ctx context.Context, authorID int64, content string) ([]models.Mention, error) {
+	parsed := mentions.Parse(content)
+	if len(parsed) == 0 {
+		return nil, nil
+	}
+
+	var usernames []string
+	for _, m := range parsed {
+		if !slices.Contains(usernames, m.Username) {
+			usernames = append(usernames, m.Username)
+		}
+	}
+
+	users, err := app.store.Users.GetByUsernames(ctx, usernames)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]int64, len(users))
+	var userIDs []int64
+	for _, u := range users {
+		ids[u.Username] = u.ID
+		userIDs = append(userIDs, u.ID)
+	}
+
+	blocked, err := app.store.Blocks.GetBlockedBetween(ctx, authorID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	var resolved []models.Mention
+	for _, m := range parsed {
+		id, ok := ids[m.Username]
+		if !ok || slices.Contains(blocked, id) {
+			continue
+		}
+
+		resolved = append(resolved, models.Mention{
+			UserID:   id,
+			Username: m.Username,
+			Start:    m.Start,
+			End:      m.End,
+		})
+	}
+
+	return resolved, nil
+}
+
+// notifyMentions tells the users newly mentioned in a post, or one of its
+// comments, about it, as long as they can see the post. The author isn't
+// told about mentioning themselves.
+func (app *application) notifyMentions(ctx context.Context, authorID int64, post *models.Post, commentID *int64, mentioned, previous []models.Mention, data any) {
+	var userIDs []int64
+	for _, m := range mentioned {
+		wasMentioned := slices.ContainsFunc(previous, func(p models.Mention) bool {
+			return p.UserID == m.UserID
+		})
+
+		if m.UserID == authorID || wasMentioned || slices.Contains(userIDs, m.UserID) {
+			continue
+		}
+
+		visible, err := app.canSeePost(ctx, m.UserID, post)
+		if err != nil {
+			app.logger.Errorw("error checking post visibility", "postID", post.ID, "userID", m.UserID, "error", err)
+		}
+		if !visible {
+			continue
+		}
+
+		userIDs = append(userIDs, m.UserID)
+
+		app.notify(ctx, models.Notification{
+			UserID:    m.UserID,
+			ActorID:   authorID,
+			Type:      models.NotificationMention,
+			PostID:    &post.ID,
+			CommentID: commentID,
+		})
+	}
+
+	app.publishEvent(ctx, userIDs, events.TypeMention, data)
+}
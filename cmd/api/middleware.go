@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/base64"
 	"fmt"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -144,6 +145,66 @@ func (app *application) checkPostOwnership(requiredRole string, next http.Handle
 	})
 }
 
+// checkCommentOwnership lets the author of a comment through, as well as the
+// author of the post it was left on when allowPostAuthor is set. Anyone else
+// needs at least the required role.
+func (app *application) checkCommentOwnership(requiredRole string, allowPostAuthor bool, next http.HandlerFunc) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := getAuthUserFromContext(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		comment := getCommentFromCtx(r)
+		post := getPostFromCtx(r)
+
+		if comment.UserID == user.ID || (allowPostAuthor && post.UserID == user.ID) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requireRole only lets the users with at least the required role through.
+func (app *application) requireRole(requiredRole string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, err := getAuthUserFromContext(r)
+			if err != nil {
+				app.badRequestResponse(w, r, err)
+				return
+			}
+
+			allowed, err := app.checkRolePrecedence(r.Context(), user, requiredRole)
+			if err != nil {
+				app.internalServerError(w, r, err)
+				return
+			}
+
+			if !allowed {
+				app.forbiddenResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func (app *application) checkRolePrecedence(ctx context.Context, user *models.User, roleName string) (bool, error) {
 	role, err := app.store.Roles.GetByName(ctx, roleName)
 	if err != nil {
@@ -179,10 +240,37 @@ func (app *application) getUser(ctx context.Context, userID int64) (*models.User
 	return cachedUser, nil
 }
 
+// clientIP is the address requests are rate limited by. The port is left
+// out, or each new connection would get a limit of its own.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// set from a proxy header by middleware.RealIP
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+// PublicRateLimiterMiddleware applies the stricter limit of the routes that
+// can be used without authentication, on top of the global one.
+func (app *application) PublicRateLimiterMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if app.config.rateLimiter.IsEnabled {
+			if allow, retryAfter := app.publicRateLimiter.Allow(clientIP(r)); !allow {
+				app.rateLimitExceededResponse(w, r, retryAfter.String())
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *application) RateLimiterMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if app.config.rateLimiter.IsEnabled {
-			if allow, retryAfter := app.rateLimiter.Allow(r.RemoteAddr); !allow {
+			if allow, retryAfter := app.rateLimiter.Allow(clientIP(r)); !allow {
 				app.rateLimitExceededResponse(w, r, retryAfter.String())
 				return
 			}
@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// GetNotifications godoc
+//
+//	@Summary		Fetches the authenticated user's notifications
+//	@Description	Fetches the notifications of the authenticated user, the most recently updated first, along with the number of unread ones. Unread notifications of the same kind about the same thing are merged
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			limit	query		int		false	"Number of notifications to return"	default(20)	minimum(1)	maximum(50)
+//	@Param			offset	query		int		false	"Number of notifications to skip"	default(0)	minimum(0)
+//	@Param			unread	query		bool	false	"Only return the unread notifications"
+//	@Success		200		{object}	models.NotificationPage
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications [get]
+func (app *application) getNotificationsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	pq := utils.PaginatedQuery{Limit: 20}
+
+	pq, err = pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var unreadOnly bool
+	if u := r.URL.Query().Get("unread"); u != "" {
+		if unreadOnly, err = strconv.ParseBool(u); err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+
+	notifications, err := app.store.Notifications.GetByUserID(ctx, user.ID, unreadOnly, pq.Limit, pq.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	unread, err := app.store.Notifications.CountUnread(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	for i := range notifications {
+		notifications[i].Summary = notificationSummary(&notifications[i])
+	}
+
+	page := models.NotificationPage{Notifications: notifications, UnreadCount: unread}
+
+	if err := app.JSONResponse(w, http.StatusOK, page); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// MarkNotificationRead godoc
+//
+//	@Summary		Marks a notification as read
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			notificationID	path		int		true	"Notification ID"
+//	@Success		204				{object}	string	"Marked as read"
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		404				{object}	error	"Notification not found"
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/{notificationID}/read [put]
+func (app *application) markNotificationReadHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	notificationID, err := strconv.ParseInt(chi.URLParam(r, "notificationID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Notifications.MarkRead(r.Context(), user.ID, notificationID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "notification not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// MarkAllNotificationsRead godoc
+//
+//	@Summary		Marks every notification as read
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Success		204	{object}	string	"Marked as read"
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/read [put]
+func (app *application) markAllNotificationsReadHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Notifications.MarkAllRead(r.Context(), user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetNotificationPreferences godoc
+//
+//	@Summary		Fetches the notification preferences
+//	@Description	Tells for every notification type whether the authenticated user gets notified of it
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	map[string]bool
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/preferences [get]
+func (app *application) getNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	prefs, err := app.store.Notifications.GetPreferences(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, prefs); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// UpdateNotificationPreferences godoc
+//
+//	@Summary		Updates the notification preferences
+//	@Description	Turns notification types on or off for the authenticated user. The types left out are unchanged
+//	@Tags			notifications
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		map[string]bool	true	"Types to turn on or off"
+//	@Success		200		{object}	map[string]bool
+//	@Failure		400		{object}	error	"Invalid payload"
+//	@Failure		401		{object}	error
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/notifications/preferences [put]
+func (app *application) updateNotificationPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload map[string]bool
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	for typ := range payload {
+		if !slices.Contains(models.NotificationTypes, typ) {
+			app.badRequestResponse(w, r, fmt.Errorf("unknown notification type %q", typ))
+			return
+		}
+	}
+
+	ctx := r.Context()
+
+	if err := app.store.Notifications.SetPreferences(ctx, user.ID, payload); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	prefs, err := app.store.Notifications.GetPreferences(ctx, user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, prefs); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// notify notifies n.UserID of what n.ActorID did, unless they are the same
+// user. Failures are only logged, they don't fail the request.
+func (app *application) notify(ctx context.Context, n models.Notification) {
+	if n.UserID == n.ActorID {
+		return
+	}
+
+	if err := app.store.Notifications.Create(ctx, &n); err != nil {
+		app.logger.Errorw("error creating notification", "type", n.Type, "userID", n.UserID, "error", err)
+	}
+}
+
+// notifyModeration tells the author of some content that someone else
+// updated or deleted it.
+func (app *application) notifyModeration(r *http.Request, authorID int64, action string, postID, commentID *int64) {
+	moderator, err := getAuthUserFromContext(r)
+	if err != nil {
+		return
+	}
+
+	app.notify(r.Context(), models.Notification{
+		UserID:    authorID,
+		ActorID:   moderator.ID,
+		Type:      models.NotificationModeration,
+		Action:    action,
+		PostID:    postID,
+		CommentID: commentID,
+	})
+}
+
+// notificationSummary describes a notification in a sentence, such as
+// "alice and 4 others commented on your post".
+func notificationSummary(n *models.Notification) string {
+	if n.Type == models.NotificationModeration {
+		switch n.Action {
+		case models.ActionPostUpdated:
+			return "A moderator edited your post"
+		case models.ActionPostDeleted:
+			return "A moderator removed your post"
+		case models.ActionCommentUpdated:
+			return "A moderator edited your comment"
+		case models.ActionCommentDeleted:
+			return "A moderator removed your comment"
+		default:
+			return "A moderator acted on your content"
+		}
+	}
+
+	actors := "Someone"
+	if len(n.Actors) > 0 {
+		actors = n.Actors[0].Username
+
+		switch {
+		case n.ActorCount == 2 && len(n.Actors) > 1:
+			actors += " and " + n.Actors[1].Username
+		case n.ActorCount == 2:
+			actors += " and 1 other"
+		case n.ActorCount > 2:
+			actors += fmt.Sprintf(" and %d others", n.ActorCount-1)
+		}
+	}
+
+	switch n.Type {
+	case models.NotificationFollow:
+		return actors + " followed you"
+	case models.NotificationComment:
+		return actors + " commented on your post"
+	case models.NotificationReply:
+		return actors + " replied to your comment"
+	case models.NotificationMention:
+		return actors + " mentioned you"
+	case models.NotificationRepost:
+		return actors + " reposted your post"
+	case models.NotificationReaction:
+		return actors + " reacted to your post"
+	default:
+		return actors + " interacted with you"
+	}
+}
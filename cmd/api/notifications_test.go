@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNotificationSummary(t *testing.T) {
+	alice := models.User{Username: "alice"}
+	bob := models.User{Username: "bob"}
+	carol := models.User{Username: "carol"}
+
+	tests := []struct {
+		name string
+		n    models.Notification
+		want string
+	}{
+		{
+			name: "single actor",
+			n:    models.Notification{Type: models.NotificationFollow, Actors: []models.User{alice}, ActorCount: 1},
+			want: "alice followed you",
+		},
+		{
+			name: "two actors",
+			n:    models.Notification{Type: models.NotificationComment, Actors: []models.User{alice, bob}, ActorCount: 2},
+			want: "alice and bob commented on your post",
+		},
+		{
+			name: "two actors, one of them gone",
+			n:    models.Notification{Type: models.NotificationReply, Actors: []models.User{alice}, ActorCount: 2},
+			want: "alice and 1 other replied to your comment",
+		},
+		{
+			name: "many actors",
+			n:    models.Notification{Type: models.NotificationComment, Actors: []models.User{alice, bob, carol}, ActorCount: 5},
+			want: "alice and 4 others commented on your post",
+		},
+		{
+			name: "no actors left",
+			n:    models.Notification{Type: models.NotificationRepost, ActorCount: 1},
+			want: "Someone reposted your post",
+		},
+		{
+			name: "mention",
+			n:    models.Notification{Type: models.NotificationMention, Actors: []models.User{bob}, ActorCount: 1},
+			want: "bob mentioned you",
+		},
+		{
+			name: "reaction",
+			n:    models.Notification{Type: models.NotificationReaction, Actors: []models.User{carol}, ActorCount: 1},
+			want: "carol reacted to your post",
+		},
+		{
+			name: "moderation",
+			n:    models.Notification{Type: models.NotificationModeration, Action: models.ActionCommentDeleted, Actors: []models.User{alice}, ActorCount: 1},
+			want: "A moderator removed your comment",
+		},
+		{
+			name: "unknown moderation",
+			n:    models.Notification{Type: models.NotificationModeration, Action: "banned"},
+			want: "A moderator acted on your content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := notificationSummary(&tt.n); got != tt.want {
+				t.Errorf("notificationSummary() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetNotifications(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	grouped := models.Notification{
+		ID:         1,
+		Type:       models.NotificationComment,
+		Actors:     []models.User{{Username: "alice"}, {Username: "bob"}, {Username: "carol"}},
+		ActorCount: 5,
+	}
+
+	notifications := app.store.Notifications.(*store.MockNotificationStore)
+	notifications.On("GetByUserID", int64(1), mock.Anything, 20, 0).Return([]models.Notification{grouped}, nil)
+	notifications.On("CountUnread", int64(1)).Return(1, nil)
+
+	request := func(t *testing.T, path string) *http.Response {
+		t.Helper()
+
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		return executeRequest(mux, req).Result()
+	}
+
+	t.Run("should summarize grouped notifications", func(t *testing.T) {
+		res := request(t, "/v1/notifications")
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		var body struct {
+			Data models.NotificationPage `json:"data"`
+		}
+		if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+
+		if body.Data.UnreadCount != 1 || len(body.Data.Notifications) != 1 {
+			t.Fatalf("got page %+v, want the grouped notification, unread", body.Data)
+		}
+
+		if got, want := body.Data.Notifications[0].Summary, "alice and 4 others commented on your post"; got != want {
+			t.Errorf("got summary %q, want %q", got, want)
+		}
+	})
+
+	t.Run("should only list the unread notifications", func(t *testing.T) {
+		res := request(t, "/v1/notifications?unread=true")
+		checkResponseCode(t, http.StatusOK, res.StatusCode)
+
+		notifications.AssertCalled(t, "GetByUserID", int64(1), true, 20, 0)
+	})
+
+	t.Run("should reject invalid filters", func(t *testing.T) {
+		res := request(t, "/v1/notifications?unread=maybe")
+		checkResponseCode(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestNotificationGroups(t *testing.T) {
+	app, mux, testToken := newCommentsTestApplication(t)
+
+	app.store.Comments.(*store.MockCommentStore).On("Create", mock.Anything).Return(nil)
+
+	users := app.store.Users.(*store.MockUserStore)
+
+	// users 3 and 4 comment on post 1 of user 2, then user 1 on their own
+	// post 2
+	for _, c := range []struct {
+		userID int64
+		postID string
+	}{
+		{userID: 3, postID: "1"},
+		{userID: 4, postID: "1"},
+		{userID: 1, postID: "2"},
+	} {
+		users.Users = map[int64]*models.User{102: {ID: c.userID}}
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts/"+c.postID+"/comments", strings.NewReader(`{"content": "nice post"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	}
+
+	notifications := app.store.Notifications.(*store.MockNotificationStore)
+
+	// the comments on post 1 are merged together, no one hears of their own
+	notifications.AssertNumberOfCalls(t, "Create", 2)
+	for _, actorID := range []int64{3, 4} {
+		notifications.AssertCalled(t, "Create", mock.MatchedBy(func(n *models.Notification) bool {
+			return n.UserID == 2 && n.ActorID == actorID && n.Type == models.NotificationComment && n.GroupKey == "comment:1"
+		}))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+)
+
+// decodeCursor verifies and decodes a cursor received as a query param.
+func (app *application) decodeCursor(raw string) (*utils.Cursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+
+	return utils.DecodeCursor(raw, app.config.pagination.cursorSecret)
+}
+
+// setPaginationLinks sets a Link header with the next and prev pages around
+// the rows that were just fetched. first and last are the sort keys of the
+// first and last rows of the page, n the number of rows it holds.
+func (app *application) setPaginationLinks(w http.ResponseWriter, r *http.Request, current *utils.Cursor, offset, limit, n int, first, last utils.Cursor) {
+	if n == 0 {
+		return
+	}
+
+	full := n == limit
+
+	hasNext, hasPrev := full, offset > 0
+	if current != nil {
+		hasNext = current.Direction == utils.CursorPrev || full
+		hasPrev = current.Direction == utils.CursorNext || full
+	}
+
+	var links []string
+
+	if hasNext {
+		last.Direction = utils.CursorNext
+		links = append(links, app.paginationLink(r, last, "next"))
+	}
+
+	if hasPrev {
+		first.Direction = utils.CursorPrev
+		links = append(links, app.paginationLink(r, first, "prev"))
+	}
+
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func (app *application) paginationLink(r *http.Request, c utils.Cursor, rel string) string {
+	u := *r.URL
+
+	qs := u.Query()
+	qs.Del("offset")
+	qs.Set("cursor", utils.EncodeCursor(c, app.config.pagination.cursorSecret))
+	u.RawQuery = qs.Encode()
+
+	return fmt.Sprintf(`<%s>; rel="%s"`, u.String(), rel)
+}
@@ -6,28 +6,37 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/sandoxlabs99/gopher_social/internal/events"
 	"github.com/sandoxlabs99/gopher_social/internal/models"
 	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+	"github.com/sandoxlabs99/gopher_social/internal/webhooks"
 
 	"github.com/go-chi/chi/v5"
 )
 
 type CreatePostPayload struct {
-	Title   string   `json:"title" validate:"required,max=30"`
-	Content string   `json:"content" validate:"required,max=1000"`
-	Tags    []string `json:"tags" validate:"required,unique,min=1,max=5,dive,min=2,max=30"`
+	Title        string   `json:"title" validate:"required,max=30"`
+	Content      string   `json:"content" validate:"required,max=1000"`
+	Tags         []string `json:"tags" validate:"required,unique,min=1,max=5,dive,min=2,max=30"`
+	QuotedPostID *int64   `json:"quotedPostId" validate:"omitempty,gte=1"`
+	Visibility   string   `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
+	// MediaIDs lists the uploaded images to attach, in display order.
+	MediaIDs []int64 `json:"mediaIds" validate:"omitempty,max=4,unique,dive,gt=0"`
 }
 type UpdatePostPayload struct {
-	Title   *string   `json:"title" validate:"omitempty,max=30"`
-	Content *string   `json:"content" validate:"omitempty,max=1000"`
-	Tags    *[]string `json:"tags" validate:"omitempty,unique,min=1,max=5,dive,min=2,max=30"`
+	Title      *string   `json:"title" validate:"omitempty,max=30"`
+	Content    *string   `json:"content" validate:"omitempty,max=1000"`
+	Tags       *[]string `json:"tags" validate:"omitempty,unique,min=1,max=5,dive,min=2,max=30"`
+	Visibility *string   `json:"visibility" validate:"omitempty,oneof=public followers unlisted private"`
 }
 
 // CreatePost godoc
 //
 //	@Summary		Creates a post
-//	@Description	Creates a post
+//	@Description	Creates a post, public unless another visibility is given
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -50,25 +59,81 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 	// more research needed on how to handle validation.
 	if err := Validate.Struct(payload); err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	user, err := getAuthUserFromContext(r)
 	if err != nil {
 		app.badRequestResponse(w, r, err)
+		return
 	}
 
 	post := &models.Post{
-		Title:   payload.Title,
-		Content: payload.Content,
-		Tags:    payload.Tags,
-		UserID:  user.ID,
+		Title:        payload.Title,
+		Content:      payload.Content,
+		Tags:         payload.Tags,
+		UserID:       user.ID,
+		QuotedPostID: payload.QuotedPostID,
+		Visibility:   payload.Visibility,
+	}
+
+	if post.Visibility == "" {
+		post.Visibility = models.VisibilityPublic
+	}
+
+	for _, id := range payload.MediaIDs {
+		post.Attachments = append(post.Attachments, models.Media{ID: id})
+	}
+
+	ctx := r.Context()
+
+	// a quote post embeds a reference to an existing post
+	if post.QuotedPostID != nil {
+		quotedPost, err := app.getVisiblePost(ctx, user.ID, *post.QuotedPostID)
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.badRequestResponse(w, r, fmt.Errorf("quoted post does not exist"))
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		post.QuotedPost = quotedPost
 	}
 
-	if err := app.store.Posts.Create(r.Context(), post); err != nil {
+	post.Mentions, err = app.resolveMentions(ctx, user.ID, post.Content)
+	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	if err := app.store.Posts.Create(ctx, post); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.badRequestResponse(w, r, fmt.Errorf("media not found"))
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.timeline.OnPostCreated(ctx, post); err != nil {
+		app.logger.Errorw("error pushing post to timelines", "postID", post.ID, "error", err)
+	}
+
+	if post.Visibility != models.VisibilityPrivate {
+		followerIDs, err := app.store.Followers.GetFollowerIDs(ctx, post.UserID)
+		if err != nil {
+			app.logger.Errorw("error fetching followers", "userID", post.UserID, "error", err)
+		}
+		app.publishEvent(ctx, followerIDs, events.TypePost, post)
+	}
+
+	app.notifyMentions(ctx, post.UserID, post, nil, post.Mentions, nil, post)
+	app.emitWebhook(ctx, models.WebhookPostCreated, []int64{post.UserID}, post.Visibility == models.VisibilityPublic, post)
+
 	if err := app.JSONResponse(w, http.StatusCreated, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -78,7 +143,7 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 // GetPost godoc
 //
 //	@Summary		Fetches a post
-//	@Description	Fetches a post by ID
+//	@Description	Fetches a post by ID. Posts the authenticated user cannot see are reported as not found
 //	@Tags			posts
 //	@Accept			json
 //	@Produce		json
@@ -91,7 +156,15 @@ func (app *application) createPostHandler(w http.ResponseWriter, r *http.Request
 func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 	post := getPostFromCtx(r)
 
-	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID)
+	// only the first page of top-level comments is embedded, the rest
+	// is loaded through the comments and replies endpoints
+	cq := utils.PaginatedCommentQuery{
+		Limit:  20,
+		Offset: 0,
+		Sort:   "desc",
+	}
+
+	comments, err := app.store.Comments.GetByPostID(r.Context(), post.ID, cq)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -99,6 +172,22 @@ func (app *application) getPostHandler(w http.ResponseWriter, r *http.Request) {
 
 	post.Comments = comments
 
+	if post.QuotedPostID != nil {
+		user, err := getAuthUserFromContext(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		quotedPost, err := app.getVisiblePost(r.Context(), user.ID, *post.QuotedPostID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		post.QuotedPost = quotedPost
+	}
+
 	if err := app.JSONResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
@@ -139,6 +228,20 @@ func (app *application) deletePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	post := getPostFromCtx(r)
+
+	if err := app.timeline.OnPostDeleted(r.Context(), post); err != nil {
+		app.logger.Errorw("error removing post from timelines", "postID", postID, "error", err)
+	}
+
+	// the notification outlives the post
+	app.notifyModeration(r, post.UserID, models.ActionPostDeleted, nil, nil)
+
+	app.emitWebhook(
+		r.Context(), models.WebhookPostDeleted, []int64{post.UserID}, post.Visibility == models.VisibilityPublic,
+		webhooks.DeletedPostData{ID: post.ID, UserID: post.UserID},
+	)
+
 	// data := CustomJSON{
 	// 	PostID: *recvID,
 	// 	Msg:    "Deletion operation successful",
@@ -187,14 +290,29 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		post.Title = *payload.Title
 	}
 
+	previousMentions := post.Mentions
+	// the app webhooks are told about public posts going private too
+	wasPublic := post.Visibility == models.VisibilityPublic
+
 	if payload.Content != nil {
 		post.Content = *payload.Content
+
+		mentions, err := app.resolveMentions(r.Context(), post.UserID, post.Content)
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+		post.Mentions = mentions
 	}
 
 	if payload.Tags != nil {
 		post.Tags = *payload.Tags
 	}
 
+	if payload.Visibility != nil {
+		post.Visibility = *payload.Visibility
+	}
+
 	if err := app.store.Posts.Update(r.Context(), post); err != nil {
 		switch {
 		case errors.Is(err, store.ErrNotFound):
@@ -205,12 +323,117 @@ func (app *application) updatePostHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	app.notifyMentions(r.Context(), post.UserID, post, nil, post.Mentions, previousMentions, post)
+	app.notifyModeration(r, post.UserID, models.ActionPostUpdated, &post.ID, nil)
+
+	isPublic := wasPublic || post.Visibility == models.VisibilityPublic
+	app.emitWebhook(r.Context(), models.WebhookPostUpdated, []int64{post.UserID}, isPublic, post)
+
 	if err := app.JSONResponse(w, http.StatusOK, post); err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 }
 
+// RepostPost godoc
+//
+//	@Summary		Reposts a post
+//	@Description	Shares a post into the authenticated user's followers' feeds
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Success		204		{object}	string	"Repost successful"
+//	@Failure		400		{object}	error	"Cannot repost own or restricted post"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"Post not found"
+//	@Failure		409		{object}	error	"Already reposted"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/repost [put]
+func (app *application) repostHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+
+	if post.UserID == user.ID {
+		app.badRequestResponse(w, r, fmt.Errorf("cannot repost your own post"))
+		return
+	}
+
+	// reposts would show the post to the reposter's followers
+	if post.Visibility != models.VisibilityPublic && post.Visibility != models.VisibilityUnlisted {
+		app.badRequestResponse(w, r, fmt.Errorf("only public and unlisted posts can be reposted"))
+		return
+	}
+
+	err = app.store.Reposts.Repost(r.Context(), post.ID, user.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateKey):
+			app.conflictResponse(w, r, err)
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "post not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.timeline.OnRepost(r.Context(), user.ID, post.ID, time.Now()); err != nil {
+		app.logger.Errorw("error pushing repost to timelines", "postID", post.ID, "error", err)
+	}
+
+	app.notify(r.Context(), models.Notification{
+		UserID:   post.UserID,
+		ActorID:  user.ID,
+		Type:     models.NotificationRepost,
+		GroupKey: fmt.Sprintf("repost:%d", post.ID),
+		PostID:   &post.ID,
+	})
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnrepostPost godoc
+//
+//	@Summary		Removes a repost
+//	@Description	Removes the authenticated user's repost of a post
+//	@Tags			posts
+//	@Accept			json
+//	@Produce		json
+//	@Param			postID	path		int		true	"Post ID"
+//	@Success		204		{object}	string	"Unrepost successful"
+//	@Failure		401		{object}	error	"Unauthorized"
+//	@Failure		404		{object}	error	"Post not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/posts/{postID}/unrepost [put]
+func (app *application) unrepostHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	post := getPostFromCtx(r)
+
+	if err := app.store.Reposts.UnRepost(r.Context(), post.ID, user.ID); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.timeline.OnUnrepost(r.Context(), user.ID, post); err != nil {
+		app.logger.Errorw("error removing repost from timelines", "postID", post.ID, "error", err)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // Middlewares
 
 type ctxKeyPost string
@@ -227,9 +450,15 @@ func (app *application) postContextMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		user, err := getAuthUserFromContext(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
 		ctx := r.Context()
 
-		post, err := app.store.Posts.GetByID(ctx, postID)
+		post, err := app.getVisiblePost(ctx, user.ID, postID)
 
 		if err != nil {
 			switch {
@@ -247,6 +476,40 @@ func (app *application) postContextMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// getVisiblePost fetches a post the user can see. The posts they cannot see
+// are reported as not found, so as not to reveal they exist.
+func (app *application) getVisiblePost(ctx context.Context, userID, postID int64) (*models.Post, error) {
+	post, err := app.store.Posts.GetByID(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	visible, err := app.canSeePost(ctx, userID, post)
+	if err != nil {
+		return nil, err
+	}
+
+	if !visible {
+		return nil, store.ErrNotFound
+	}
+
+	return post, nil
+}
+
+// canSeePost tells whether the visibility of a post lets the user see it.
+func (app *application) canSeePost(ctx context.Context, userID int64, post *models.Post) (bool, error) {
+	switch {
+	case post.UserID == userID:
+		return true, nil
+	case post.Visibility == models.VisibilityPublic, post.Visibility == models.VisibilityUnlisted:
+		return true, nil
+	case post.Visibility == models.VisibilityFollowers:
+		return app.store.Followers.IsFollowing(ctx, userID, post.UserID)
+	default:
+		return false, nil
+	}
+}
+
 func getPostFromCtx(r *http.Request) *models.Post {
 	post, _ := r.Context().Value(PostContextKey).(*models.Post)
 
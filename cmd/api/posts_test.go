@@ -0,0 +1,338 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/timeline"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetPostVisibility(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetByID", int64(1)).Return(&models.Post{ID: 1, UserID: 2, Visibility: models.VisibilityPublic}, nil)
+	posts.On("GetByID", int64(2)).Return(&models.Post{ID: 2, UserID: 2, Visibility: models.VisibilityUnlisted}, nil)
+	posts.On("GetByID", int64(3)).Return(&models.Post{ID: 3, UserID: 2, Visibility: models.VisibilityFollowers}, nil)
+	posts.On("GetByID", int64(4)).Return(&models.Post{ID: 4, UserID: 3, Visibility: models.VisibilityFollowers}, nil)
+	posts.On("GetByID", int64(5)).Return(&models.Post{ID: 5, UserID: 2, Visibility: models.VisibilityPrivate}, nil)
+	posts.On("GetByID", int64(6)).Return(&models.Post{ID: 6, UserID: 1, Visibility: models.VisibilityPrivate}, nil)
+	posts.On("GetByID", int64(7)).Return(nil, store.ErrNotFound)
+
+	// the authenticated user 1 follows user 2 but not user 3
+	followers := app.store.Followers.(*store.MockFollowerStore)
+	followers.On("IsFollowing", int64(1), int64(2)).Return(true, nil)
+	followers.On("IsFollowing", int64(1), int64(3)).Return(false, nil)
+
+	app.store.Comments.(*store.MockCommentStore).On("GetByPostID", mock.Anything, mock.Anything).Return(nil, nil)
+
+	tests := []struct {
+		name   string
+		postID string
+		want   int
+	}{
+		{name: "public", postID: "1", want: http.StatusOK},
+		{name: "unlisted", postID: "2", want: http.StatusOK},
+		{name: "followers only, followed author", postID: "3", want: http.StatusOK},
+		{name: "followers only, unfollowed author", postID: "4", want: http.StatusNotFound},
+		{name: "private", postID: "5", want: http.StatusNotFound},
+		{name: "own private", postID: "6", want: http.StatusOK},
+		{name: "missing", postID: "7", want: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, "/v1/posts/"+tt.postID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	t.Run("should not tell hidden posts from missing ones", func(t *testing.T) {
+		bodies := make([]string, 0, 2)
+		for _, postID := range []string{"5", "7"} {
+			req, err := http.NewRequest(http.MethodGet, "/v1/posts/"+postID, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			bodies = append(bodies, executeRequest(mux, req).Body.String())
+		}
+
+		if bodies[0] != bodies[1] {
+			t.Errorf("got %q for a private post, want the same as a missing one, %q", bodies[0], bodies[1])
+		}
+	})
+}
+
+func TestRepostVisibility(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetByID", int64(1)).Return(&models.Post{ID: 1, UserID: 2, Visibility: models.VisibilityPublic}, nil)
+	posts.On("GetByID", int64(2)).Return(&models.Post{ID: 2, UserID: 2, Visibility: models.VisibilityUnlisted}, nil)
+	posts.On("GetByID", int64(3)).Return(&models.Post{ID: 3, UserID: 2, Visibility: models.VisibilityFollowers}, nil)
+	posts.On("GetByID", int64(4)).Return(&models.Post{ID: 4, UserID: 1, Visibility: models.VisibilityPublic}, nil)
+
+	app.store.Followers.(*store.MockFollowerStore).On("IsFollowing", int64(1), int64(2)).Return(true, nil)
+	app.store.Notifications.(*store.MockNotificationStore).On("Create", mock.Anything).Return(nil)
+
+	reposts := app.store.Reposts.(*store.MockRepostStore)
+	reposts.On("Repost", mock.Anything, int64(1)).Return(nil)
+
+	tests := []struct {
+		name   string
+		postID string
+		want   int
+	}{
+		{name: "public", postID: "1", want: http.StatusNoContent},
+		{name: "unlisted", postID: "2", want: http.StatusNoContent},
+		{name: "followers only", postID: "3", want: http.StatusBadRequest},
+		{name: "own post", postID: "4", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, "/v1/posts/"+tt.postID+"/repost", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	reposts.AssertNotCalled(t, "Repost", int64(3), int64(1))
+	reposts.AssertNotCalled(t, "Repost", int64(4), int64(1))
+}
+
+func TestCreatePostFanOut(t *testing.T) {
+	withTimeline := config{
+		timeline: timeline.Config{
+			IsEnabled:       true,
+			MaxLength:       800,
+			FanoutThreshold: 10000,
+		},
+	}
+
+	newApp := func(t *testing.T) (*application, http.Handler, string) {
+		app := newTestApplication(t, withTimeline)
+
+		testToken, err := app.authenticator.GenerateToken(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.store.Posts.(*store.MockPostStore).On("Create", mock.Anything).Return(nil)
+		app.store.Followers.(*store.MockFollowerStore).On("GetFollowerIDs", int64(1)).Return([]int64{2, 3}, nil)
+		app.store.Webhooks.(*store.MockWebhookStore).On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		app.cacheStorage.Timelines.(*cache.MockTimelineStore).On("Push", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		return app, app.mount(), testToken
+	}
+
+	createPost := func(t *testing.T, mux http.Handler, testToken, visibility string) {
+		body := `{"title": "gophers", "content": "hello", "tags": ["go"], "visibility": "` + visibility + `"}`
+
+		req, err := http.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		req.Header.Set("Authorization", "Bearer "+testToken)
+
+		rr := executeRequest(mux, req)
+
+		checkResponseCode(t, http.StatusCreated, rr.Code)
+	}
+
+	t.Run("should push public posts to the followers", func(t *testing.T) {
+		app, mux, testToken := newApp(t)
+
+		createPost(t, mux, testToken, models.VisibilityPublic)
+
+		timelines := app.cacheStorage.Timelines.(*cache.MockTimelineStore)
+		timelines.AssertCalled(t, "Push", []int64{2, 3}, mock.Anything, 800)
+	})
+
+	t.Run("should push followers only posts to the followers", func(t *testing.T) {
+		app, mux, testToken := newApp(t)
+
+		createPost(t, mux, testToken, models.VisibilityFollowers)
+
+		timelines := app.cacheStorage.Timelines.(*cache.MockTimelineStore)
+		timelines.AssertCalled(t, "Push", []int64{2, 3}, mock.Anything, 800)
+	})
+
+	t.Run("should NOT push private posts to the followers", func(t *testing.T) {
+		app, mux, testToken := newApp(t)
+
+		createPost(t, mux, testToken, models.VisibilityPrivate)
+
+		app.cacheStorage.Timelines.(*cache.MockTimelineStore).AssertNotCalled(t, "Push", mock.Anything, mock.Anything, mock.Anything)
+		app.store.Followers.(*store.MockFollowerStore).AssertNotCalled(t, "GetFollowerIDs", mock.Anything)
+	})
+
+	t.Run("should push private posts to the author only", func(t *testing.T) {
+		withOwnPosts := withTimeline
+		withOwnPosts.timeline.IncludeOwnPosts = true
+
+		app := newTestApplication(t, withOwnPosts)
+		mux := app.mount()
+
+		testToken, err := app.authenticator.GenerateToken(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		app.store.Posts.(*store.MockPostStore).On("Create", mock.Anything).Return(nil)
+		app.store.Webhooks.(*store.MockWebhookStore).On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		timelines := app.cacheStorage.Timelines.(*cache.MockTimelineStore)
+		timelines.On("Push", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		createPost(t, mux, testToken, models.VisibilityPrivate)
+
+		timelines.AssertCalled(t, "Push", []int64{1}, mock.Anything, 800)
+		timelines.AssertNumberOfCalls(t, "Push", 1)
+	})
+}
+
+func TestRepost(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	for id := int64(1); id <= 3; id++ {
+		posts.On("GetByID", id).Return(&models.Post{ID: id, UserID: 2, Visibility: models.VisibilityPublic}, nil)
+	}
+
+	reposts := app.store.Reposts.(*store.MockRepostStore)
+	reposts.On("Repost", int64(1), int64(1)).Return(nil)
+	reposts.On("Repost", int64(2), int64(1)).Return(store.ErrDuplicateKey)
+	reposts.On("Repost", int64(3), int64(1)).Return(store.ErrNotFound)
+	reposts.On("UnRepost", int64(1), int64(1)).Return(nil)
+
+	notifications := app.store.Notifications.(*store.MockNotificationStore)
+	notifications.On("Create", mock.Anything).Return(nil)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{name: "repost", path: "/v1/posts/1/repost", want: http.StatusNoContent},
+		{name: "already reposted", path: "/v1/posts/2/repost", want: http.StatusConflict},
+		{name: "deleted meanwhile", path: "/v1/posts/3/repost", want: http.StatusNotFound},
+		{name: "unrepost", path: "/v1/posts/1/unrepost", want: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	// only the successful repost tells the author
+	notifications.AssertNumberOfCalls(t, "Create", 1)
+	notifications.AssertCalled(t, "Create", mock.MatchedBy(func(n *models.Notification) bool {
+		return n.UserID == 2 && n.ActorID == 1 && n.Type == models.NotificationRepost && n.GroupKey == "repost:1"
+	}))
+}
+
+func TestQuotePost(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posts := app.store.Posts.(*store.MockPostStore)
+	posts.On("GetByID", int64(1)).Return(&models.Post{ID: 1, UserID: 2, Visibility: models.VisibilityPublic}, nil)
+	posts.On("GetByID", int64(2)).Return(&models.Post{ID: 2, UserID: 2, Visibility: models.VisibilityPrivate}, nil)
+	posts.On("GetByID", int64(3)).Return(nil, store.ErrNotFound)
+	posts.On("Create", mock.Anything).Return(nil)
+
+	app.store.Followers.(*store.MockFollowerStore).On("GetFollowerIDs", int64(1)).Return(nil, nil)
+	app.store.Webhooks.(*store.MockWebhookStore).On("Enqueue", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	tests := []struct {
+		name         string
+		quotedPostID string
+		want         int
+	}{
+		{name: "visible post", quotedPostID: "1", want: http.StatusCreated},
+		{name: "hidden post", quotedPostID: "2", want: http.StatusBadRequest},
+		{name: "missing post", quotedPostID: "3", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"title": "quote", "content": "look at this", "tags": ["go"], "quotedPostId": ` + tt.quotedPostID + `}`
+
+			req, err := http.NewRequest(http.MethodPost, "/v1/posts", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+
+	posts.AssertNumberOfCalls(t, "Create", 1)
+	posts.AssertCalled(t, "Create", mock.MatchedBy(func(p *models.Post) bool {
+		return p.QuotedPostID != nil && *p.QuotedPostID == 1 && p.QuotedPost != nil
+	}))
+}
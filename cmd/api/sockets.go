@@ -0,0 +1,254 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// maxFrameSize bounds the frames clients send, a message being at most
+	// 2000 characters.
+	maxFrameSize = 16 << 10
+	writeWait    = 10 * time.Second
+)
+
+// clientFrame is what clients send over the conversations socket.
+type clientFrame struct {
+	Type           string `json:"type" validate:"oneof=message typing read"`
+	ConversationID int64  `json:"conversationId" validate:"required,gt=0"`
+	Content        string `json:"content"`
+	MessageID      int64  `json:"messageId"`
+}
+
+// serverFrame is what the conversations socket sends to clients. Errors are
+// sent as frames of type "error", the socket stays open.
+type serverFrame struct {
+	Type string `json:"type"`
+	Data any    `json:"data"`
+}
+
+var conversationEvents = map[string]bool{
+	events.TypeMessage: true,
+	events.TypeTyping:  true,
+	events.TypeRead:    true,
+}
+
+// ConversationsSocket godoc
+//
+//	@Summary		Opens the conversations socket
+//	@Description	Upgrades to a WebSocket over which the authenticated user gets the messages, typing indicators and read receipts of their conversations, and sends their own as {"type": "message"|"typing"|"read", "conversationId", "content", "messageId"} frames. Browsers that can't set the Authorization header pass the token in the token query parameter instead
+//	@Tags			conversations
+//	@Param			token	query		string	false	"Authentication token"
+//	@Success		101		{string}	string	"Switching protocols"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/conversations/ws [get]
+func (app *application) conversationsSocketHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+			return origin == "" || origin == app.config.frontendURL
+		},
+	}
+
+	// Upgrade replies to the client itself when it fails
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// the request context isn't canceled once the connection is hijacked
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	_, sub, err := app.events.Subscribe(ctx, user.ID, "")
+	if err != nil {
+		app.logger.Errorw("error subscribing to events", "user", user.ID, "error", err)
+		return
+	}
+	defer sub.Close()
+
+	replies := make(chan serverFrame, 8)
+
+	go func() {
+		defer cancel()
+		// unblocks the reads below
+		defer conn.Close()
+
+		app.writeFrames(ctx, conn, sub, replies)
+	}()
+
+	pongWait := 2 * app.config.stream.heartbeat
+
+	conn.SetReadLimit(maxFrameSize)
+	conn.SetReadDeadline(time.Now().Add(pongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var frame clientFrame
+		err = json.Unmarshal(msg, &frame)
+		if err != nil {
+			err = fmt.Errorf("invalid frame")
+		} else {
+			err = app.handleFrame(ctx, user, frame)
+		}
+
+		if err != nil {
+			reply := serverFrame{Type: "error", Data: map[string]string{"error": err.Error()}}
+
+			select {
+			case replies <- reply:
+			default:
+				// the client isn't reading its replies
+			}
+		}
+	}
+}
+
+// writeFrames is the only writer of the connection: gorilla/websocket
+// doesn't allow concurrent writes.
+func (app *application) writeFrames(ctx context.Context, conn *websocket.Conn, sub *events.Subscription, replies <-chan serverFrame) {
+	ping := time.NewTicker(app.config.stream.heartbeat)
+	defer ping.Stop()
+
+	write := func(frame serverFrame) error {
+		conn.SetWriteDeadline(time.Now().Add(writeWait))
+		return conn.WriteJSON(frame)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(writeWait)); err != nil {
+				return
+			}
+		case reply := <-replies:
+			if err := write(reply); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events():
+			// dropped for lagging behind, the client reconnects
+			if !ok {
+				return
+			}
+
+			if !conversationEvents[e.Type] {
+				continue
+			}
+
+			if err := write(serverFrame{Type: e.Type, Data: e.Data}); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// handleFrame acts on a frame sent by a client. The errors it returns are
+// sent back to the client.
+func (app *application) handleFrame(ctx context.Context, user *models.User, frame clientFrame) error {
+	if err := Validate.Struct(frame); err != nil {
+		return fmt.Errorf("invalid frame")
+	}
+
+	conversation, err := app.getConversation(ctx, frame.ConversationID, user.ID)
+	if err != nil {
+		return app.frameError(err)
+	}
+
+	switch frame.Type {
+	case "message":
+		if err := Validate.Struct(SendMessagePayload{Content: frame.Content}); err != nil {
+			return fmt.Errorf("invalid message")
+		}
+
+		_, err = app.sendMessage(ctx, conversation, user.ID, frame.Content)
+	case "typing":
+		typing := models.Typing{ConversationID: conversation.ID, UserID: user.ID}
+		app.signalEvent(ctx, otherMemberIDs(conversation, user.ID), events.TypeTyping, typing)
+	case "read":
+		err = app.markRead(ctx, conversation, user.ID, frame.MessageID)
+	}
+
+	return app.frameError(err)
+}
+
+// frameError turns err into one that can be shown to the client, logging
+// the unexpected ones.
+func (app *application) frameError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, errConversationNotFound), errors.Is(err, errCannotMessage):
+		return err
+	case errors.Is(err, store.ErrNotFound):
+		return errors.New("message not found")
+	default:
+		app.logger.Errorw("socket error", "error", err.Error())
+		return errors.New("the server encountered a problem")
+	}
+}
+
+type ctxKeySocket string
+
+const SocketTokenContextKey = ctxKeySocket("socketToken")
+
+// StripTokenMiddleware takes the token query parameter off the URL before
+// the request is logged, keeping it aside for SocketTokenMiddleware.
+func StripTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		qs := r.URL.Query()
+		if !qs.Has("token") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token := qs.Get("token")
+		qs.Del("token")
+
+		r = r.WithContext(context.WithValue(r.Context(), SocketTokenContextKey, token))
+		r.URL.RawQuery = qs.Encode()
+		r.RequestURI = r.URL.RequestURI()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// SocketTokenMiddleware lets WebSocket clients pass their token in the token
+// query parameter, as browsers can't set headers on the handshake.
+func SocketTokenMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, _ := r.Context().Value(SocketTokenContextKey).(string)
+		if token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+)
+
+// StreamUserFeed godoc
+//
+//	@Summary		Streams the authenticated user's events
+//	@Description	Streams, as Server-Sent Events, the new posts of the users the authenticated user follows, the new comments on their posts and their new followers. A client that lost its stream resumes it by sending the ID of the last event it got in the Last-Event-ID header
+//	@Tags			feed
+//	@Produce		text/event-stream
+//	@Param			Last-Event-ID	header		string	false	"ID of the last event received"
+//	@Success		200				{string}	string	"Stream of events"
+//	@Failure		400				{object}	error
+//	@Failure		401				{object}	error
+//	@Failure		500				{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/feed/stream [get]
+func (app *application) streamUserFeedHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID != "" && !events.ValidID(lastEventID) {
+		app.badRequestResponse(w, r, fmt.Errorf("invalid Last-Event-ID"))
+		return
+	}
+
+	// the stream outlives the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	missed, sub, err := app.events.Subscribe(ctx, user.ID, lastEventID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	last := lastEventID
+	for _, e := range missed {
+		if err := writeEvent(w, e); err != nil {
+			return
+		}
+		last = e.ID
+	}
+
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(app.config.stream.heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case e, ok := <-sub.Events():
+			// dropped for lagging behind, the client resumes from last
+			if !ok {
+				return
+			}
+
+			// direct messages have their own socket
+			if !feedEvents[e.Type] {
+				continue
+			}
+
+			// already sent while replaying
+			if last != "" && !events.After(e.ID, last) {
+				continue
+			}
+
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			last = e.ID
+		}
+
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+var feedEvents = map[string]bool{
+	events.TypePost:    true,
+	events.TypeComment: true,
+	events.TypeFollow:  true,
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) error {
+	_, err := fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+	return err
+}
+
+// publishEvent sends an event to the streams of the given users. Streams are
+// best effort, so failures are only logged: clients catch up from the API.
+func (app *application) publishEvent(ctx context.Context, userIDs []int64, typ string, data any) {
+	if len(userIDs) == 0 {
+		return
+	}
+
+	e, err := events.NewEvent(typ, data)
+	if err == nil {
+		err = app.events.Publish(ctx, userIDs, e)
+	}
+
+	if err != nil {
+		app.logger.Errorw("error publishing event", "type", typ, "error", err)
+	}
+}
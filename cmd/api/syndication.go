@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/syndication"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// syndicationSize is the number of recent posts feed readers get.
+const syndicationSize = 20
+
+var syndicationQuery = utils.PaginatedFeedQuery{
+	Limit: syndicationSize,
+	Sort:  "desc",
+	Mode:  "chronological",
+}
+
+// GetUserPostsAtom godoc
+//
+//	@Summary		Fetches the Atom feed of a user
+//	@Description	Fetches the recent public posts of a user as an Atom feed, for feed readers. It doesn't require authentication, but is rate limited more strictly. Conditional requests are answered with 304 Not Modified when the feed didn't change
+//	@Tags			syndication
+//	@Produce		application/atom+xml
+//	@Param			userID				path		int		true	"User ID"
+//	@Param			If-None-Match		header		string	false	"ETag of the cached feed"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of the cached feed"
+//	@Success		200					{string}	string	"Atom feed"
+//	@Success		304					{string}	string	"Not modified"
+//	@Failure		400					{object}	error
+//	@Failure		404					{object}	error
+//	@Failure		429					{object}	error
+//	@Failure		500					{object}	error
+//	@Router			/users/{userID}/posts.atom [get]
+func (app *application) getUserPostsAtomHandler(w http.ResponseWriter, r *http.Request) {
+	app.userPostsSyndication(w, r, syndication.Atom, syndication.AtomContentType)
+}
+
+// GetUserPostsRSS godoc
+//
+//	@Summary		Fetches the RSS feed of a user
+//	@Description	Fetches the recent public posts of a user as an RSS feed, for feed readers. It doesn't require authentication, but is rate limited more strictly. Conditional requests are answered with 304 Not Modified when the feed didn't change
+//	@Tags			syndication
+//	@Produce		application/rss+xml
+//	@Param			userID				path		int		true	"User ID"
+//	@Param			If-None-Match		header		string	false	"ETag of the cached feed"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of the cached feed"
+//	@Success		200					{string}	string	"RSS feed"
+//	@Success		304					{string}	string	"Not modified"
+//	@Failure		400					{object}	error
+//	@Failure		404					{object}	error
+//	@Failure		429					{object}	error
+//	@Failure		500					{object}	error
+//	@Router			/users/{userID}/posts.rss [get]
+func (app *application) getUserPostsRSSHandler(w http.ResponseWriter, r *http.Request) {
+	app.userPostsSyndication(w, r, syndication.RSS, syndication.RSSContentType)
+}
+
+// GetTagAtom godoc
+//
+//	@Summary		Fetches the Atom feed of a tag
+//	@Description	Fetches the recent public posts tagged with the given tag as an Atom feed, for feed readers. It doesn't require authentication, but is rate limited more strictly. Conditional requests are answered with 304 Not Modified when the feed didn't change
+//	@Tags			syndication
+//	@Produce		application/atom+xml
+//	@Param			tag					path		string	true	"Tag"
+//	@Param			If-None-Match		header		string	false	"ETag of the cached feed"
+//	@Param			If-Modified-Since	header		string	false	"Last-Modified of the cached feed"
+//	@Success		200					{string}	string	"Atom feed"
+//	@Success		304					{string}	string	"Not modified"
+//	@Failure		400					{object}	error
+//	@Failure		429					{object}	error
+//	@Failure		500					{object}	error
+//	@Router			/tags/{tag}.atom [get]
+func (app *application) getTagAtomHandler(w http.ResponseWriter, r *http.Request) {
+	tag := chi.URLParam(r, "tag")
+	if err := Validate.Var(tag, "min=2,max=30"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq := syndicationQuery
+	fq.Tags = []string{tag}
+
+	posts, err := app.store.Posts.GetPublicFeed(r.Context(), fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/tags/%s", app.config.frontendURL, tag)
+	feed := app.syndicationFeed(r, link, "#"+tag, fmt.Sprintf("Recent posts tagged #%s", tag), posts)
+
+	app.serveSyndication(w, r, feed, syndication.Atom, syndication.AtomContentType)
+}
+
+func (app *application) userPostsSyndication(w http.ResponseWriter, r *http.Request, render func(syndication.Feed) ([]byte, error), contentType string) {
+	user := getUserFromCtx(r)
+
+	// reposts are the posts of other users, and unlisted posts are only
+	// shown on the profile itself
+	posts, err := app.store.Posts.GetUserPublicPosts(r.Context(), user.ID, syndicationQuery)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	link := fmt.Sprintf("%s/users/%d", app.config.frontendURL, user.ID)
+	feed := app.syndicationFeed(r, link, user.Username, fmt.Sprintf("Recent posts by %s", user.Username), posts)
+
+	app.serveSyndication(w, r, feed, render, contentType)
+}
+
+func (app *application) syndicationFeed(r *http.Request, link, title, subtitle string, posts []models.PostWithMetadata) syndication.Feed {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	feed := syndication.Feed{
+		ID:       link,
+		Title:    title,
+		Subtitle: subtitle,
+		Link:     link,
+		Self:     fmt.Sprintf("%s://%s%s", scheme, r.Host, r.URL.Path),
+	}
+
+	for _, p := range posts {
+		postLink := fmt.Sprintf("%s/posts/%d", app.config.frontendURL, p.ID)
+
+		feed.Entries = append(feed.Entries, syndication.Entry{
+			ID:        postLink,
+			Title:     p.Title,
+			Link:      postLink,
+			Author:    p.User.Username,
+			Content:   p.Content,
+			Tags:      p.Tags,
+			Published: p.CreatedAt,
+			Updated:   p.UpdatedAt,
+		})
+
+		if p.UpdatedAt.After(feed.Updated) {
+			feed.Updated = p.UpdatedAt
+		}
+	}
+
+	return feed
+}
+
+// serveSyndication renders the feed and serves it with http.ServeContent,
+// which answers conditional requests from its ETag and Last-Modified. The
+// ETag is a hash of the document, so that edited posts change it too.
+func (app *application) serveSyndication(w http.ResponseWriter, r *http.Request, feed syndication.Feed, render func(syndication.Feed) ([]byte, error), contentType string) {
+	doc, err := render(feed)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	sum := sha256.Sum256(doc)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Cache-Control", "public, max-age=300")
+
+	// Last-Modified is left out of empty feeds, their Updated being zero
+	http.ServeContent(w, r, "", feed.Updated, bytes.NewReader(doc))
+}
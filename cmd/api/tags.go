@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FollowTag godoc
+//
+//	@Summary		Follow a tag
+//	@Description	Follow a tag, adding the posts tagged with it to the authenticated user's home feed
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{object}	string	"Follow successful"
+//	@Failure		400	{object}	error	"Invalid tag"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		409	{object}	error	"Already following tag"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/follow [put]
+func (app *application) followTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tag := chi.URLParam(r, "tag")
+	if err := Validate.Var(tag, "min=2,max=30"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Tags.Follow(r.Context(), user.ID, tag); err != nil {
+		switch {
+		case errors.Is(err, store.ErrDuplicateKey):
+			app.conflictResponse(w, r, err)
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnfollowTag godoc
+//
+//	@Summary		Unfollow a tag
+//	@Description	Unfollow a tag
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Param			tag	path		string	true	"Tag"
+//	@Success		204	{object}	string	"Unfollow successful"
+//	@Failure		400	{object}	error	"Invalid tag"
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/{tag}/unfollow [put]
+func (app *application) unfollowTagHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tag := chi.URLParam(r, "tag")
+	if err := Validate.Var(tag, "min=2,max=30"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := app.store.Tags.UnFollow(r.Context(), user.ID, tag); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetFollowedTags godoc
+//
+//	@Summary		Fetches the followed tags
+//	@Description	Fetches the tags the authenticated user follows
+//	@Tags			tags
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]string
+//	@Failure		401	{object}	error	"Unauthorized"
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/tags/followed [get]
+func (app *application) getFollowedTagsHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Tags.GetFollowedTags(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
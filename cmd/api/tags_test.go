@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+)
+
+func TestFollowTag(t *testing.T) {
+	app := newTestApplication(t, config{})
+	mux := app.mount()
+
+	testToken, err := app.authenticator.GenerateToken(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tags := app.store.Tags.(*store.MockTagStore)
+	tags.On("Follow", int64(1), "golang").Return(nil)
+	tags.On("Follow", int64(1), "gophers").Return(store.ErrDuplicateKey)
+	tags.On("UnFollow", int64(1), "golang").Return(nil)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{name: "follow", path: "/v1/tags/golang/follow", want: http.StatusNoContent},
+		{name: "follow twice", path: "/v1/tags/gophers/follow", want: http.StatusConflict},
+		{name: "follow a tag too short", path: "/v1/tags/g/follow", want: http.StatusBadRequest},
+		{name: "follow a tag too long", path: "/v1/tags/" + strings.Repeat("g", 31) + "/follow", want: http.StatusBadRequest},
+		{name: "unfollow", path: "/v1/tags/golang/unfollow", want: http.StatusNoContent},
+		{name: "unfollow a tag too short", path: "/v1/tags/g/unfollow", want: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPut, tt.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req.Header.Set("Authorization", "Bearer "+testToken)
+
+			rr := executeRequest(mux, req)
+
+			checkResponseCode(t, tt.want, rr.Code)
+		})
+	}
+}
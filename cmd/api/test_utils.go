@@ -6,9 +6,12 @@ import (
 	"testing"
 
 	"github.com/sandoxlabs99/gopher_social/internal/auth"
+	"github.com/sandoxlabs99/gopher_social/internal/events"
+	"github.com/sandoxlabs99/gopher_social/internal/mailer"
 	"github.com/sandoxlabs99/gopher_social/internal/ratelimiter"
 	"github.com/sandoxlabs99/gopher_social/internal/store"
 	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/timeline"
 
 	"go.uber.org/zap"
 )
@@ -22,19 +25,33 @@ func newTestApplication(t *testing.T, cfg config) *application {
 	mockRedisStore := cache.NewMockRedisStorage()
 	testAuth := &auth.TestAuthenticator{}
 
+	// mails are kept for the tests to look at
+	testMailer, err := mailer.NewCaptureClient("", "no-reply@gophersocial.local", logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
 	// Rate limiter
 	rateLimiter := ratelimiter.NewFixedWindowLimiter(
 		cfg.rateLimiter.RequestsPerTimeFrame,
 		cfg.rateLimiter.TimeFrame,
 	)
+	publicRateLimiter := ratelimiter.NewFixedWindowLimiter(
+		cfg.rateLimiter.PublicRequestsPerTimeFrame,
+		cfg.rateLimiter.TimeFrame,
+	)
 
 	return &application{
-		config:        cfg,
-		logger:        logger,
-		store:         mockStore,
-		cacheStorage:  mockRedisStore,
-		authenticator: testAuth,
-		rateLimiter:   rateLimiter,
+		config:            cfg,
+		logger:            logger,
+		store:             mockStore,
+		mailer:            testMailer,
+		cacheStorage:      mockRedisStore,
+		authenticator:     testAuth,
+		rateLimiter:       rateLimiter,
+		publicRateLimiter: publicRateLimiter,
+		timeline:          timeline.NewService(mockStore, mockRedisStore, cfg.timeline),
+		events:            events.NewMemoryBroker(cfg.stream.replaySize),
 	}
 }
 
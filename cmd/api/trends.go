@@ -0,0 +1,101 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/sandoxlabs99/gopher_social/internal/trends"
+)
+
+// GetTrendingTags godoc
+//
+//	@Summary		Fetches the trending tags
+//	@Description	Fetches the tags used by more distinct users than usual over the given period, hottest first. It doesn't require authentication, but is rate limited more strictly
+//	@Tags			trends
+//	@Accept			json
+//	@Produce		json
+//	@Param			period	query		string	false	"Period the tags trend over"	default(24h)	enum(1h,24h,7d)
+//	@Param			limit	query		int		false	"Number of tags to return"		default(10)		minimum(1)	maximum(50)
+//	@Success		200		{object}	[]models.TrendingTag
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/trends/tags [get]
+func (app *application) getTrendingTagsHandler(w http.ResponseWriter, r *http.Request) {
+	period, limit, err := parseTrendsQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	tags, err := app.store.Trends.GetTags(r.Context(), period, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, tags); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetTrendingPosts godoc
+//
+//	@Summary		Fetches the trending posts
+//	@Description	Fetches the posts engaged with by more distinct users than usual over the given period, hottest first. It doesn't require authentication, but is rate limited more strictly
+//	@Tags			trends
+//	@Accept			json
+//	@Produce		json
+//	@Param			period	query		string	false	"Period the posts trend over"	default(24h)	enum(1h,24h,7d)
+//	@Param			limit	query		int		false	"Number of posts to return"		default(10)		minimum(1)	maximum(50)
+//	@Success		200		{object}	[]models.PostWithMetadata
+//	@Failure		400		{object}	error
+//	@Failure		429		{object}	error
+//	@Failure		500		{object}	error
+//	@Router			/trends/posts [get]
+func (app *application) getTrendingPostsHandler(w http.ResponseWriter, r *http.Request) {
+	period, limit, err := parseTrendsQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Trends.GetPosts(r.Context(), period, limit)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+func parseTrendsQuery(r *http.Request) (period string, limit int, err error) {
+	qs := r.URL.Query()
+
+	period, limit = "24h", 10
+
+	if p := qs.Get("period"); p != "" {
+		period = p
+	}
+
+	if l := qs.Get("limit"); l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	if err := Validate.Var(period, "oneof="+trends.PeriodNames); err != nil {
+		return "", 0, err
+	}
+
+	if err := Validate.Var(limit, "gte=1,lte=50"); err != nil {
+		return "", 0, err
+	}
+
+	return period, limit, nil
+}
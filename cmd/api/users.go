@@ -7,8 +7,11 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/sandoxlabs99/gopher_social/internal/events"
 	"github.com/sandoxlabs99/gopher_social/internal/models"
 	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+	"github.com/sandoxlabs99/gopher_social/internal/webhooks"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -41,6 +44,66 @@ func (app *application) getUserHandler(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// GetUserPosts godoc
+//
+//	@Summary		Fetches the posts of a user
+//	@Description	Fetches the profile timeline of a user: the posts they authored or reposted that the authenticated user can see, with optional filters
+//	@Tags			users
+//	@Accept			json
+//	@Produce		json
+//	@Param			userID	path		int			true	"User ID"
+//	@Param			limit	query		int			false	"Number of posts to return"		default(20)		minimum(1)	maximum(20)
+//	@Param			offset	query		int			false	"Number of posts to skip"		default(0)		minimum(0)
+//	@Param			cursor	query		string		false	"Opaque cursor taken from the Link header, replaces offset"
+//	@Param			sort	query		string		false	"Sort order: 'asc' or 'desc'"	default(desc)	enum(asc,desc)
+//	@Param			search	query		string		false	"Search term to filter posts by title or content"
+//	@Param			tags	query		[]string	false	"Filter posts by tags"	minItems(1)	maxItems(5)	items(minLength(2),maxLength(30))
+//	@Param			since	query		string		false	"Only posts from this time on, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Param			until	query		string		false	"Only posts before this time, RFC 3339 or 'YYYY-MM-DD hh:mm:ss' (UTC)"
+//	@Success		200		{object}	[]models.PostWithMetadata
+//	@Header			200		{string}	Link	"next and prev page links"
+//	@Failure		400		{object}	error
+//	@Failure		401		{object}	error
+//	@Failure		404		{object}	error	"User not found"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/users/{userID}/posts [get]
+func (app *application) getUserPostsHandler(w http.ResponseWriter, r *http.Request) {
+	user := getUserFromCtx(r)
+
+	viewer, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	fq, err := app.parseFeedQuery(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	posts, err := app.store.Posts.GetUserPosts(r.Context(), user.ID, viewer.ID, fq)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if len(posts) > 0 {
+		first, last := posts[0], posts[len(posts)-1]
+		app.setPaginationLinks(
+			w, r, fq.Keyset, fq.Offset, fq.Limit, len(posts),
+			utils.Cursor{CreatedAt: first.ActivityAt, ID: first.ID},
+			utils.Cursor{CreatedAt: last.ActivityAt, ID: last.ID},
+		)
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, posts); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
 // FollowUser godoc
 //
 //	@Summary		Follow a user
@@ -81,6 +144,26 @@ func (app *application) followUserHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	if err := app.timeline.OnFollow(r.Context(), followerUser.ID, followedUser.ID); err != nil {
+		app.logger.Errorw("error backfilling timeline", "userID", followerUser.ID, "error", err)
+	}
+
+	follower := models.User{ID: followerUser.ID, Username: followerUser.Username}
+	app.publishEvent(r.Context(), []int64{followedUser.ID}, events.TypeFollow, follower)
+
+	app.notify(r.Context(), models.Notification{
+		UserID:   followedUser.ID,
+		ActorID:  followerUser.ID,
+		Type:     models.NotificationFollow,
+		GroupKey: models.NotificationFollow,
+	})
+
+	app.emitWebhook(
+		r.Context(), models.WebhookUserFollowed, []int64{followerUser.ID, followedUser.ID},
+		!followerUser.IsPrivate && !followedUser.IsPrivate,
+		webhooks.FollowData{FollowerID: followerUser.ID, UserID: followedUser.ID},
+	)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -112,12 +195,22 @@ func (app *application) unfollowUserHandler(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	err = app.store.Followers.UnFollow(r.Context(), unfollowedUser.ID, unfollowedUser.ID)
+	err = app.store.Followers.UnFollow(r.Context(), unfollowerUser.ID, unfollowedUser.ID)
 	if err != nil {
 		app.internalServerError(w, r, err)
 		return
 	}
 
+	if err := app.timeline.OnUnfollow(r.Context(), unfollowerUser.ID, unfollowedUser.ID); err != nil {
+		app.logger.Errorw("error purging timeline", "userID", unfollowerUser.ID, "error", err)
+	}
+
+	app.emitWebhook(
+		r.Context(), models.WebhookUserUnfollowed, []int64{unfollowerUser.ID, unfollowedUser.ID},
+		!unfollowerUser.IsPrivate && !unfollowedUser.IsPrivate,
+		webhooks.FollowData{FollowerID: unfollowerUser.ID, UserID: unfollowedUser.ID},
+	)
+
 	w.WriteHeader(http.StatusNoContent)
 }
 
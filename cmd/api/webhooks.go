@@ -0,0 +1,400 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+	"github.com/sandoxlabs99/gopher_social/internal/webhooks"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type webhookKey string
+
+const webhookCtx webhookKey = "webhook"
+
+type CreateWebhookPayload struct {
+	URL    string   `json:"url" validate:"required,http_url,max=2048"`
+	Events []string `json:"events" validate:"required,unique,min=1,dive,oneof=post.created post.updated post.deleted user.followed user.unfollowed"`
+	// IsApp subscribes the webhook to the events of every user, admins only.
+	IsApp bool `json:"isApp"`
+}
+
+type UpdateWebhookPayload struct {
+	URL      *string   `json:"url" validate:"omitempty,http_url,max=2048"`
+	Events   *[]string `json:"events" validate:"omitempty,unique,min=1,dive,oneof=post.created post.updated post.deleted user.followed user.unfollowed"`
+	IsActive *bool     `json:"isActive"`
+}
+
+// CreateWebhook godoc
+//
+//	@Summary		Registers a webhook
+//	@Description	Registers a URL the authenticated user's events are posted to, or every public event for app webhooks, which only admins can register. Each payload is signed with the returned secret: the X-GopherSocial-Signature header is "sha256=" followed by the hex encoded HMAC-SHA256 of the X-GopherSocial-Timestamp header, a dot and the body. The secret is only shown once
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			payload	body		CreateWebhookPayload	true	"Webhook"
+//	@Success		201		{object}	models.Webhook
+//	@Failure		400		{object}	error	"Invalid payload"
+//	@Failure		401		{object}	error
+//	@Failure		403		{object}	error	"Only admins can register app webhooks"
+//	@Failure		500		{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks [post]
+func (app *application) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	var payload CreateWebhookPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+
+	if payload.IsApp {
+		allowed, err := app.checkRolePrecedence(ctx, user, "admin")
+		if err != nil {
+			app.internalServerError(w, r, err)
+			return
+		}
+
+		if !allowed {
+			app.forbiddenResponse(w, r)
+			return
+		}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	webhook := &models.Webhook{
+		UserID: user.ID,
+		IsApp:  payload.IsApp,
+		URL:    payload.URL,
+		Events: payload.Events,
+		Secret: hex.EncodeToString(secret),
+	}
+
+	if err := app.store.Webhooks.Create(ctx, webhook); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusCreated, webhook); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetWebhooks godoc
+//
+//	@Summary		Fetches the webhooks
+//	@Description	Fetches the webhooks registered by the authenticated user
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]models.Webhook
+//	@Failure		401	{object}	error
+//	@Failure		500	{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks [get]
+func (app *application) getWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := getAuthUserFromContext(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	webhooks, err := app.store.Webhooks.GetByUserID(r.Context(), user.ID)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, webhooks); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// GetWebhook godoc
+//
+//	@Summary		Fetches a webhook
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhookID	path		int	true	"Webhook ID"
+//	@Success		200			{object}	models.Webhook
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error	"Webhook not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks/{webhookID} [get]
+func (app *application) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	if err := app.JSONResponse(w, http.StatusOK, webhook); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// UpdateWebhook godoc
+//
+//	@Summary		Updates a webhook
+//	@Description	Changes the URL or the events of a webhook, or turns it on or off. The deliveries of a webhook turned off wait until it is turned back on
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhookID	path		int						true	"Webhook ID"
+//	@Param			payload		body		UpdateWebhookPayload	true	"Webhook"
+//	@Success		200			{object}	models.Webhook
+//	@Failure		400			{object}	error	"Invalid payload"
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error	"Webhook not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks/{webhookID} [patch]
+func (app *application) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	var payload UpdateWebhookPayload
+	if err := readJSON(w, r, &payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(payload); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if payload.URL != nil {
+		webhook.URL = *payload.URL
+	}
+
+	if payload.Events != nil {
+		webhook.Events = *payload.Events
+	}
+
+	if payload.IsActive != nil {
+		webhook.IsActive = *payload.IsActive
+	}
+
+	if err := app.store.Webhooks.Update(r.Context(), webhook); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "webhook not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, webhook); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// DeleteWebhook godoc
+//
+//	@Summary		Deletes a webhook
+//	@Description	Deletes a webhook along with its deliveries
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhookID	path		int		true	"Webhook ID"
+//	@Success		204			{object}	string	"Webhook deleted"
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error	"Webhook not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks/{webhookID} [delete]
+func (app *application) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	if err := app.store.Webhooks.Delete(r.Context(), webhook.ID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "webhook not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetWebhookDeliveries godoc
+//
+//	@Summary		Fetches the deliveries of a webhook
+//	@Description	Fetches the log of the events sent to a webhook, the latest first, with their attempts. Pending deliveries are retried with an exponential backoff, dead ones gave up and can only be redelivered by hand
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhookID	path		int		true	"Webhook ID"
+//	@Param			limit		query		int		false	"Number of deliveries to return"	default(20)	minimum(1)	maximum(50)
+//	@Param			offset		query		int		false	"Number of deliveries to skip"		default(0)	minimum(0)
+//	@Param			status		query		string	false	"Only the deliveries with this status"	enum(pending,delivered,dead)
+//	@Success		200			{object}	[]models.WebhookDelivery
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error	"Webhook not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks/{webhookID}/deliveries [get]
+func (app *application) getWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	pq := utils.PaginatedQuery{Limit: 20}
+
+	pq, err := pq.Parse(r)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	if err := Validate.Struct(pq); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	if err := Validate.Var(status, "omitempty,oneof=pending delivered dead"); err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	deliveries, err := app.store.Webhooks.GetDeliveries(r.Context(), webhook.ID, status, pq.Limit, pq.Offset)
+	if err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusOK, deliveries); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// RedeliverWebhook godoc
+//
+//	@Summary		Redelivers an event
+//	@Description	Queues the payload of a past delivery of a webhook again, as a new delivery. The payload keeps its ID, for the receiver to tell it apart from a new event
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhookID	path		int	true	"Webhook ID"
+//	@Param			deliveryID	path		int	true	"Delivery ID"
+//	@Success		202			{object}	models.WebhookDelivery
+//	@Failure		400			{object}	error
+//	@Failure		401			{object}	error
+//	@Failure		404			{object}	error	"Webhook or delivery not found"
+//	@Failure		500			{object}	error
+//	@Security		ApiKeyAuth
+//	@Router			/webhooks/{webhookID}/deliveries/{deliveryID}/redeliver [post]
+func (app *application) redeliverWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	webhook := getWebhookFromCtx(r)
+
+	deliveryID, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		app.badRequestResponse(w, r, err)
+		return
+	}
+
+	delivery, err := app.store.Webhooks.Redeliver(r.Context(), webhook.ID, deliveryID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			app.notFoundResponse(w, r, err, "delivery not found")
+		default:
+			app.internalServerError(w, r, err)
+		}
+		return
+	}
+
+	if err := app.JSONResponse(w, http.StatusAccepted, delivery); err != nil {
+		app.internalServerError(w, r, err)
+		return
+	}
+}
+
+// emitWebhook queues an event for the webhooks of userIDs, and for the app
+// webhooks when it is public. Like publishEvent, failures are only logged.
+func (app *application) emitWebhook(ctx context.Context, typ string, userIDs []int64, isPublic bool, data any) {
+	payload, err := webhooks.NewPayload(typ, data)
+	if err == nil {
+		err = app.store.Webhooks.Enqueue(ctx, typ, userIDs, isPublic, payload)
+	}
+
+	if err != nil {
+		app.logger.Errorw("error queuing webhook event", "type", typ, "error", err)
+	}
+}
+
+// webhookContextMiddleware loads a webhook of the authenticated user. The
+// webhooks of other users are reported as not found.
+func (app *application) webhookContextMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		webhookID, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		user, err := getAuthUserFromContext(r)
+		if err != nil {
+			app.badRequestResponse(w, r, err)
+			return
+		}
+
+		webhook, err := app.store.Webhooks.GetByID(r.Context(), webhookID)
+		if err == nil && webhook.UserID != user.ID {
+			err = store.ErrNotFound
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrNotFound):
+				app.notFoundResponse(w, r, err, "webhook not found")
+			default:
+				app.internalServerError(w, r, err)
+			}
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), webhookCtx, webhook)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func getWebhookFromCtx(r *http.Request) *models.Webhook {
+	webhook, _ := r.Context().Value(webhookCtx).(*models.Webhook)
+
+	return webhook
+}
@@ -0,0 +1,6 @@
+ALTER TABLE
+    posts DROP COLUMN IF EXISTS quoted_post_id;
+
+DROP INDEX IF EXISTS idx_reposts_post_id;
+
+DROP TABLE IF EXISTS reposts;
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS reposts (
+    user_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT reposts_pk PRIMARY KEY(user_id, post_id),
+    CONSTRAINT fk_reposts_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reposts_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_post_id ON reposts(post_id);
+
+ALTER TABLE
+    posts
+ADD
+    COLUMN IF NOT EXISTS quoted_post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL;
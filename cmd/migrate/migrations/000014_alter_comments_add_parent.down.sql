@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_comments_parent_id;
+
+ALTER TABLE
+    comments DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS parent_id;
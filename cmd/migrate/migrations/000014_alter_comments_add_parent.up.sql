@@ -0,0 +1,10 @@
+ALTER TABLE
+    comments
+ADD
+    COLUMN IF NOT EXISTS parent_id INTEGER REFERENCES comments(id) ON DELETE CASCADE,
+ADD
+    COLUMN IF NOT EXISTS depth INTEGER NOT NULL DEFAULT 0,
+ADD
+    COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_comments_parent_id ON comments(parent_id);
@@ -0,0 +1,3 @@
+ALTER TABLE
+    comments DROP COLUMN IF EXISTS edited_at,
+    DROP COLUMN IF EXISTS version;
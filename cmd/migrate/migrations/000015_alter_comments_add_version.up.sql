@@ -0,0 +1,6 @@
+ALTER TABLE
+    comments
+ADD
+    COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 0,
+ADD
+    COLUMN IF NOT EXISTS edited_at TIMESTAMPTZ;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_posts_user_id_created_at;
+DROP INDEX IF EXISTS idx_reposts_user_id_created_at;
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_posts_user_id_created_at ON posts(user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_reposts_user_id_created_at ON reposts(user_id, created_at);
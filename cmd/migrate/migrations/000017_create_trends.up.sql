@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS trending_tags (
+    period VARCHAR(8) NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    posts INTEGER NOT NULL,
+    authors INTEGER NOT NULL,
+    velocity DOUBLE PRECISION NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT trending_tags_pk PRIMARY KEY(period, tag)
+);
+
+CREATE TABLE IF NOT EXISTS trending_posts (
+    period VARCHAR(8) NOT NULL,
+    post_id INTEGER NOT NULL,
+    engagers INTEGER NOT NULL,
+    velocity DOUBLE PRECISION NOT NULL,
+    score DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT trending_posts_pk PRIMARY KEY(period, post_id),
+    CONSTRAINT fk_trending_posts_post FOREIGN KEY (post_id) REFERENCES posts(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_posts_created_at ON posts(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_comments_created_at ON comments(created_at);
+
+CREATE INDEX IF NOT EXISTS idx_reposts_created_at ON reposts(created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS tag_follows (
+    user_id INTEGER NOT NULL,
+    tag VARCHAR(100) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT tag_follows_pk PRIMARY KEY(user_id, tag),
+    CONSTRAINT fk_tag_follows_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
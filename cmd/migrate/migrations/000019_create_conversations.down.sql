@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_messages_conversation_id_created_at;
+
+DROP TABLE IF EXISTS messages;
+
+DROP INDEX IF EXISTS idx_conversation_members_user_id;
+
+DROP TABLE IF EXISTS conversation_members;
+
+DROP TABLE IF EXISTS conversations;
+
+DROP INDEX IF EXISTS idx_blocks_blocked_id;
+
+DROP TABLE IF EXISTS blocks;
+
+ALTER TABLE
+    users DROP COLUMN IF EXISTS is_private;
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_posts_public_created_at;
+
+ALTER TABLE
+    posts DROP COLUMN IF EXISTS visibility;
@@ -0,0 +1,7 @@
+ALTER TABLE
+    posts
+ADD
+    COLUMN IF NOT EXISTS visibility VARCHAR(10) NOT NULL DEFAULT 'public'
+    CHECK (visibility IN ('public', 'followers', 'unlisted', 'private'));
+
+CREATE INDEX IF NOT EXISTS idx_posts_public_created_at ON posts(created_at) WHERE visibility = 'public';
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_media_post_id;
+
+DROP TABLE IF EXISTS media;
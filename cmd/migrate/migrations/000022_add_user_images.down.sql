@@ -0,0 +1,3 @@
+ALTER TABLE
+    users DROP COLUMN IF EXISTS avatar,
+    DROP COLUMN IF EXISTS banner;
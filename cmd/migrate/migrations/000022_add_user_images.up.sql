@@ -0,0 +1,6 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN IF NOT EXISTS avatar JSONB,
+ADD
+    COLUMN IF NOT EXISTS banner JSONB;
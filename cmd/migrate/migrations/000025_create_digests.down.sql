@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS digest_deliveries;
+
+DROP TABLE IF EXISTS digest_settings;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS digest_settings (
+    user_id INTEGER PRIMARY KEY,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'off',
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT digest_settings_frequency CHECK (frequency IN ('off', 'daily', 'weekly')),
+    CONSTRAINT fk_digest_settings_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_digest_settings_frequency ON digest_settings(frequency, user_id);
+
+-- one row per digest ever claimed, so that a period is never mailed twice
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+    user_id INTEGER NOT NULL,
+    frequency VARCHAR(10) NOT NULL,
+    period_start TIMESTAMPTZ NOT NULL,
+    -- sending, sent or empty
+    status VARCHAR(10) NOT NULL DEFAULT 'sending',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (user_id, frequency, period_start),
+    CONSTRAINT fk_digest_deliveries_user FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
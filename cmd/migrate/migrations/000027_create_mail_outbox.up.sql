@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS mail_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    template VARCHAR(100) NOT NULL,
+    username VARCHAR(255) NOT NULL,
+    email CITEXT NOT NULL,
+    -- the template data, emptied once sent as it can carry tokens
+    data JSONB NOT NULL,
+    is_sandbox BOOLEAN NOT NULL DEFAULT FALSE,
+    -- pending, sent, failed or expired
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    -- mails not sent by then are useless and dropped
+    expires_at TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mail_outbox_due ON mail_outbox(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,7 @@
+ALTER TABLE
+    users
+ADD
+    COLUMN IF NOT EXISTS follower_count INTEGER NOT NULL DEFAULT 0;
+
+UPDATE users u
+SET follower_count = (SELECT COUNT(*) FROM followers f WHERE f.user_id = u.id);
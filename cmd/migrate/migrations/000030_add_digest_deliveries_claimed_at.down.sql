@@ -0,0 +1,2 @@
+ALTER TABLE
+    digest_deliveries DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,7 @@
+ALTER TABLE
+    digest_deliveries
+ADD
+    COLUMN IF NOT EXISTS claimed_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+-- the digests left sending are claimed since their last update
+UPDATE digest_deliveries SET claimed_at = updated_at;
@@ -706,8 +706,7 @@ const docTemplate = `{
         "main.CreateCommentsPayload": {
             "type": "object",
             "required": [
-                "content",
-                "user_id"
+                "content"
             ],
             "properties": {
                 "content": {
@@ -715,8 +714,9 @@ const docTemplate = `{
                     "maxLength": 1000,
                     "minLength": 3
                 },
-                "user_id": {
-                    "type": "integer"
+                "parent_id": {
+                    "type": "integer",
+                    "minimum": 1
                 }
             }
         },
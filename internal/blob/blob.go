@@ -0,0 +1,64 @@
+// Package blob stores the files users upload, on the local filesystem or in
+// an S3 compatible bucket.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps binary objects under slash separated keys, and tells the URL
+// clients fetch them from. Keys are never reused, so objects can be cached
+// for good once stored.
+type Store interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Get opens the object stored under key, ErrNotFound if there is none.
+	// The caller closes it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key, if any.
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// CacheControl is the Cache-Control header objects are served with.
+const CacheControl = "public, max-age=31536000, immutable"
+
+type Config struct {
+	// Provider is either "local" or "s3".
+	Provider string
+	// PublicURL is the base URL objects are served from. Left empty, S3
+	// objects are served from the bucket's URL.
+	PublicURL string
+	Local     LocalConfig
+	S3        S3Config
+}
+
+type LocalConfig struct {
+	Dir string
+}
+
+type S3Config struct {
+	// Endpoint is the base URL of the S3 API, e.g.
+	// https://s3.eu-west-1.amazonaws.com or http://localhost:9000 for a
+	// local MinIO. Buckets are addressed by path.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+}
+
+// New returns the store of the configured provider.
+func New(cfg Config) (Store, error) {
+	switch cfg.Provider {
+	case "local":
+		return NewLocalStore(cfg.Local.Dir, cfg.PublicURL)
+	case "s3":
+		return NewS3Store(cfg.S3, cfg.PublicURL)
+	default:
+		return nil, errors.New("unknown blob provider " + cfg.Provider)
+	}
+}
@@ -0,0 +1,71 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LocalStore keeps objects as files under a directory, for development and
+// single instance deployments. The API serves them itself.
+type LocalStore struct {
+	root      *os.Root
+	publicURL string
+}
+
+func NewLocalStore(dir, publicURL string) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	// keys can't escape the directory through the root
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LocalStore{root: root, publicURL: strings.TrimSuffix(publicURL, "/")}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := s.root.MkdirAll(filepath.Dir(filepath.FromSlash(key)), 0o755); err != nil {
+		return err
+	}
+
+	// written aside then renamed, so that readers never see part of it
+	tmp := filepath.FromSlash(key) + ".tmp"
+	if err := s.root.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+
+	return s.root.Rename(tmp, filepath.FromSlash(key))
+}
+
+func (s *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	f, err := s.root.Open(filepath.FromSlash(key))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	err := s.root.Remove(filepath.FromSlash(key))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalStore) URL(key string) string {
+	return s.publicURL + "/" + key
+}
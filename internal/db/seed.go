@@ -171,7 +171,8 @@ func generatePosts(num int, users []*models.User) []*models.Post {
 				postsdata.tags[rand.Intn(len(postsdata.tags))],
 				postsdata.tags[rand.Intn(len(postsdata.tags))],
 			},
-			UserID: user.ID,
+			UserID:     user.ID,
+			Visibility: models.VisibilityPublic,
 		}
 	}
 
@@ -0,0 +1,176 @@
+package digest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/jobs"
+	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"go.uber.org/zap"
+)
+
+// lease is how long a claimed digest is left to its sender before others
+// take it over. Compiling and mailing a digest takes well under that.
+const lease = 10 * time.Minute
+
+type Config struct {
+	IsEnabled bool
+	// Schedule is the cron expression the due digests are sent on, by the
+	// job queue. The digests of a period go out by the first run after its
+	// end.
+	Schedule string
+	// BatchSize is the number of users loaded at once.
+	BatchSize int
+	// Secret signs the unsubscribe tokens.
+	Secret string
+	// FrontendURL is where the links of the digests point to.
+	FrontendURL string
+	// IsSandbox is passed on to the mailer.
+	IsSandbox bool
+}
+
+// Service mails the users who opted in a digest of what happened to them
+// during the last day or week. Each digest is claimed in the database before
+// it is sent, so that restarts and other instances never send it again. A
+// digest that fails to send is released and tried again on the next run,
+// and one interrupted by a crash once its claim's lease is over.
+type Service struct {
+	store  store.Storage
+	mailer mailer.Client
+	config Config
+	logger *zap.SugaredLogger
+}
+
+func NewService(store store.Storage, mailer mailer.Client, config Config, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		store:  store,
+		mailer: mailer,
+		config: config,
+		logger: logger,
+	}
+}
+
+// sendArgs is the job sending the due digests.
+type sendArgs struct{}
+
+func (sendArgs) Kind() string { return "digest.send" }
+
+// Register schedules the sending of the due digests on the job queue.
+func (s *Service) Register(q *jobs.Queue) error {
+	if !s.config.IsEnabled {
+		return nil
+	}
+
+	jobs.Register(q, func(ctx context.Context, _ sendArgs) error {
+		var errs []error
+		for _, frequency := range []string{models.DigestDaily, models.DigestWeekly} {
+			if err := s.SendDue(ctx, frequency, time.Now()); err != nil {
+				errs = append(errs, fmt.Errorf("failed to send %s digests: %w", frequency, err))
+			}
+		}
+
+		return errors.Join(errs...)
+	})
+
+	return q.Schedule(s.config.Schedule, sendArgs{})
+}
+
+// SendDue sends the digests of the last period of the given frequency ended
+// by now to the users who haven't had it yet.
+func (s *Service) SendDue(ctx context.Context, frequency string, now time.Time) error {
+	since, until := LastPeriod(frequency, now)
+
+	var afterID int64
+	for {
+		users, err := s.store.Digests.GetDue(ctx, frequency, since, lease, afterID, s.config.BatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, u := range users {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			if err := s.send(ctx, &u, frequency, since, until); err != nil {
+				s.logger.Errorw("failed to send digest", "userID", u.ID, "frequency", frequency, "error", err)
+			}
+		}
+
+		if len(users) < s.config.BatchSize {
+			return nil
+		}
+
+		afterID = users[len(users)-1].ID
+	}
+}
+
+func (s *Service) send(ctx context.Context, user *models.User, frequency string, since, until time.Time) error {
+	claimed, err := s.store.Digests.Claim(ctx, user.ID, frequency, since, lease)
+	if err != nil || !claimed {
+		return err
+	}
+
+	digest, err := s.store.Digests.Get(ctx, user.ID, since, until)
+	if err != nil {
+		s.release(user.ID, frequency, since)
+		return err
+	}
+
+	// nothing happened, there is nothing to send
+	if digest.IsEmpty() {
+		return s.store.Digests.Finish(ctx, user.ID, frequency, since, models.DigestEmpty)
+	}
+
+	vars := struct {
+		Username       string
+		Frequency      string
+		Digest         *models.Digest
+		FrontendURL    string
+		SettingsURL    string
+		UnsubscribeURL string
+	}{
+		Username:       user.Username,
+		Frequency:      frequency,
+		Digest:         digest,
+		FrontendURL:    s.config.FrontendURL,
+		SettingsURL:    s.config.FrontendURL + "/settings/notifications",
+		UnsubscribeURL: fmt.Sprintf("%s/unsubscribe/%s", s.config.FrontendURL, UnsubscribeToken(user.ID, s.config.Secret)),
+	}
+
+	if err := s.mailer.Send(mailer.DigestTemplate, user.Username, user.Email, vars, s.config.IsSandbox); err != nil {
+		s.release(user.ID, frequency, since)
+		return err
+	}
+
+	return s.store.Digests.Finish(ctx, user.ID, frequency, since, models.DigestSent)
+}
+
+// release lets a digest be tried again. It runs without the service's
+// context, so that digests interrupted by a shutdown aren't left claimed.
+func (s *Service) release(userID int64, frequency string, since time.Time) {
+	if err := s.store.Digests.Release(context.Background(), userID, frequency, since); err != nil {
+		s.logger.Errorw("failed to release digest", "userID", userID, "frequency", frequency, "error", err)
+	}
+}
+
+// LastPeriod returns the bounds of the last day or week, in UTC, ended by
+// now. Weeks start on Mondays.
+func LastPeriod(frequency string, now time.Time) (since, until time.Time) {
+	now = now.UTC()
+	until = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	if frequency == models.DigestWeekly {
+		sinceMonday := (int(until.Weekday()) + 6) % 7
+		until = until.AddDate(0, 0, -sinceMonday)
+
+		return until.AddDate(0, 0, -7), until
+	}
+
+	return until.AddDate(0, 0, -1), until
+}
@@ -0,0 +1,94 @@
+package digest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+func TestLastPeriod(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2026, 3, 4, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		frequency string
+		now       time.Time
+		since     time.Time
+		until     time.Time
+	}{
+		{
+			name:      "daily is yesterday",
+			frequency: models.DigestDaily,
+			now:       now,
+			since:     time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily at midnight is the day just ended",
+			frequency: models.DigestDaily,
+			now:       time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+			since:     time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "daily is in UTC",
+			frequency: models.DigestDaily,
+			now:       time.Date(2026, 3, 4, 1, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)),
+			since:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "weekly is last week",
+			frequency: models.DigestWeekly,
+			now:       now,
+			since:     time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "weekly on a sunday",
+			frequency: models.DigestWeekly,
+			now:       time.Date(2026, 3, 8, 23, 0, 0, 0, time.UTC),
+			since:     time.Date(2026, 2, 23, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "weekly on a monday is the week just ended",
+			frequency: models.DigestWeekly,
+			now:       time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+			since:     time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+			until:     time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, until := LastPeriod(tt.frequency, tt.now)
+			if !since.Equal(tt.since) || !until.Equal(tt.until) {
+				t.Errorf("got [%v, %v), want [%v, %v)", since, until, tt.since, tt.until)
+			}
+		})
+	}
+}
+
+func TestUnsubscribeToken(t *testing.T) {
+	token := UnsubscribeToken(42, "secret")
+
+	userID, err := ParseUnsubscribeToken(token, "secret")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if userID != 42 {
+		t.Errorf("got user %d, want 42", userID)
+	}
+
+	forged := UnsubscribeToken(43, "other")
+	invalid := []string{"", "42", token + "x", token[:len(token)-2], forged}
+
+	for _, token := range invalid {
+		if _, err := ParseUnsubscribeToken(token, "secret"); err != ErrInvalidToken {
+			t.Errorf("token %q: got error %v, want %v", token, err, ErrInvalidToken)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package digest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidToken = errors.New("invalid unsubscribe token")
+
+// UnsubscribeToken returns the token turning off the digests of a user in
+// one click, without them signing in. It is signed rather than stored, and
+// stays valid for good.
+func UnsubscribeToken(userID int64, secret string) string {
+	payload := strconv.FormatInt(userID, 10)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." +
+		base64.RawURLEncoding.EncodeToString(sign(payload, secret))
+}
+
+// ParseUnsubscribeToken returns the ID of the user a token was made for.
+func ParseUnsubscribeToken(token, secret string) (int64, error) {
+	encPayload, encSig, ok := strings.Cut(token, ".")
+	if !ok {
+		return 0, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	if !hmac.Equal(sig, sign(string(payload), secret)) {
+		return 0, ErrInvalidToken
+	}
+
+	userID, err := strconv.ParseInt(string(payload), 10, 64)
+	if err != nil {
+		return 0, ErrInvalidToken
+	}
+
+	return userID, nil
+}
+
+// sign signs a payload for unsubscribing only, so that no other signature
+// made with the same secret passes for it.
+func sign(payload, secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("unsubscribe:" + payload))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,175 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	TypePost    = "post"
+	TypeComment = "comment"
+	TypeFollow  = "follow"
+	TypeMention = "mention"
+
+	TypeMessage = "message"
+	TypeTyping  = "typing"
+	TypeRead    = "read"
+)
+
+// Event is something that happened which a user is told about as it happens.
+// Its ID orders it among the events of that user, and lets a client that lost
+// its stream resume it where it left off.
+type Event struct {
+	ID   string          `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+func NewEvent(typ string, data any) (Event, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{Type: typ, Data: raw}, nil
+}
+
+// Broker delivers events to the users they are meant for.
+type Broker interface {
+	// Publish sends an event to every user in userIDs.
+	Publish(ctx context.Context, userIDs []int64, e Event) error
+	// Signal sends an event to the users in userIDs that are subscribed
+	// right now, without retaining it for replay nor giving it an ID.
+	Signal(ctx context.Context, userIDs []int64, e Event) error
+	// Subscribe starts listening to the events of a user. The events it
+	// returns are the ones published after lastEventID that are still
+	// retained, none when lastEventID is empty.
+	Subscribe(ctx context.Context, userID int64, lastEventID string) ([]Event, *Subscription, error)
+}
+
+// SubscriptionBuffer is the number of events a subscriber can lag behind
+// before it is dropped.
+const SubscriptionBuffer = 64
+
+// Subscription receives the events of a user as they are published. It is
+// closed if its subscriber doesn't keep up, the client then has to resume
+// from the last event it got.
+type Subscription struct {
+	userID int64
+	events chan Event
+	hub    *hub
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// hub keeps track of the subscriptions of this instance.
+type hub struct {
+	mu   sync.Mutex
+	subs map[int64]map[*Subscription]struct{}
+}
+
+func newHub() *hub {
+	return &hub{subs: make(map[int64]map[*Subscription]struct{})}
+}
+
+func (h *hub) add(userID int64) *Subscription {
+	s := &Subscription{
+		userID: userID,
+		events: make(chan Event, SubscriptionBuffer),
+		hub:    h,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subs[userID] == nil {
+		h.subs[userID] = make(map[*Subscription]struct{})
+	}
+	h.subs[userID][s] = struct{}{}
+
+	return s
+}
+
+func (h *hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.removeLocked(s)
+}
+
+func (h *hub) removeLocked(s *Subscription) {
+	subs, ok := h.subs[s.userID]
+	if !ok {
+		return
+	}
+
+	if _, ok := subs[s]; !ok {
+		return
+	}
+
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.subs, s.userID)
+	}
+
+	close(s.events)
+}
+
+func (h *hub) deliver(userID int64, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for s := range h.subs[userID] {
+		select {
+		case s.events <- e:
+		default:
+			h.removeLocked(s)
+		}
+	}
+}
+
+// After reports whether the event id comes after the event other. IDs are
+// made of a millisecond timestamp and a sequence number, "<ms>-<seq>".
+func After(id, other string) bool {
+	ms, seq := parseID(id)
+	otherMs, otherSeq := parseID(other)
+
+	if ms != otherMs {
+		return ms > otherMs
+	}
+	return seq > otherSeq
+}
+
+func parseID(id string) (ms, seq uint64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+
+	ms, _ = strconv.ParseUint(msPart, 10, 64)
+	seq, _ = strconv.ParseUint(seqPart, 10, 64)
+
+	return ms, seq
+}
+
+// ValidID reports whether id is a well formed event ID.
+func ValidID(id string) bool {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return false
+	}
+
+	_, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	_, err = strconv.ParseUint(seqPart, 10, 64)
+	return err == nil
+}
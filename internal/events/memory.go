@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MemoryBroker delivers events within a single instance, for deployments
+// without Redis. Each user's most recent events are retained for replay.
+type MemoryBroker struct {
+	hub    *hub
+	replay int
+
+	mu     sync.Mutex
+	logs   map[int64][]Event
+	lastMs uint64
+	seq    uint64
+}
+
+func NewMemoryBroker(replay int) *MemoryBroker {
+	return &MemoryBroker{
+		hub:    newHub(),
+		replay: replay,
+		logs:   make(map[int64][]Event),
+	}
+}
+
+func (b *MemoryBroker) Publish(ctx context.Context, userIDs []int64, e Event) error {
+	b.mu.Lock()
+	e.ID = b.nextID()
+
+	for _, userID := range userIDs {
+		log := append(b.logs[userID], e)
+		if len(log) > b.replay {
+			log = log[len(log)-b.replay:]
+		}
+		b.logs[userID] = log
+	}
+	b.mu.Unlock()
+
+	for _, userID := range userIDs {
+		b.hub.deliver(userID, e)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Signal(ctx context.Context, userIDs []int64, e Event) error {
+	e.ID = ""
+
+	for _, userID := range userIDs {
+		b.hub.deliver(userID, e)
+	}
+
+	return nil
+}
+
+func (b *MemoryBroker) Subscribe(ctx context.Context, userID int64, lastEventID string) ([]Event, *Subscription, error) {
+	sub := b.hub.add(userID)
+
+	if lastEventID == "" {
+		return nil, sub, nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var missed []Event
+	for _, e := range b.logs[userID] {
+		if After(e.ID, lastEventID) {
+			missed = append(missed, e)
+		}
+	}
+
+	return missed, sub, nil
+}
+
+// nextID generates IDs the way Redis does for streams, so that they sort the
+// same way whichever broker is used.
+func (b *MemoryBroker) nextID() string {
+	ms := uint64(time.Now().UnixMilli())
+
+	if ms > b.lastMs {
+		b.lastMs, b.seq = ms, 0
+	} else {
+		b.seq++
+	}
+
+	return fmt.Sprintf("%d-%d", b.lastMs, b.seq)
+}
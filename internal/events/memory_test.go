@@ -0,0 +1,90 @@
+package events
+
+import (
+	"context"
+	"testing"
+)
+
+func TestMemoryBroker(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("replays the events after the last one received", func(t *testing.T) {
+		b := NewMemoryBroker(2)
+
+		var ids []string
+		for range 3 {
+			if err := b.Publish(ctx, []int64{1}, Event{Type: TypePost}); err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, b.logs[1][len(b.logs[1])-1].ID)
+		}
+
+		missed, sub, err := b.Subscribe(ctx, 1, ids[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sub.Close()
+
+		if len(missed) != 2 || missed[0].ID != ids[1] || missed[1].ID != ids[2] {
+			t.Errorf("missed = %v, want the events %v", missed, ids[1:])
+		}
+	})
+
+	t.Run("delivers to the subscribers of the user only", func(t *testing.T) {
+		b := NewMemoryBroker(10)
+
+		_, sub, _ := b.Subscribe(ctx, 1, "")
+		defer sub.Close()
+		_, other, _ := b.Subscribe(ctx, 2, "")
+		defer other.Close()
+
+		b.Publish(ctx, []int64{1}, Event{Type: TypeFollow})
+
+		if e := <-sub.Events(); e.Type != TypeFollow {
+			t.Errorf("got %q event, want %q", e.Type, TypeFollow)
+		}
+
+		if len(other.Events()) != 0 {
+			t.Error("event delivered to another user")
+		}
+	})
+
+	t.Run("drops subscribers that lag behind", func(t *testing.T) {
+		b := NewMemoryBroker(10)
+
+		_, sub, _ := b.Subscribe(ctx, 1, "")
+		defer sub.Close()
+
+		for range SubscriptionBuffer + 1 {
+			b.Publish(ctx, []int64{1}, Event{Type: TypePost})
+		}
+
+		n := 0
+		for range sub.Events() {
+			n++
+		}
+
+		if n != SubscriptionBuffer {
+			t.Errorf("got %d events before the subscription closed, want %d", n, SubscriptionBuffer)
+		}
+	})
+}
+
+func TestAfter(t *testing.T) {
+	tests := []struct {
+		id, other string
+		want      bool
+	}{
+		{"2-0", "1-5", true},
+		{"1-5", "1-4", true},
+		{"1-4", "1-4", false},
+		{"10-0", "9-0", true},
+		{"1-0", "2-0", false},
+	}
+
+	for _, tt := range tests {
+		if got := After(tt.id, tt.other); got != tt.want {
+			t.Errorf("After(%q, %q) = %v, want %v", tt.id, tt.other, got, tt.want)
+		}
+	}
+}
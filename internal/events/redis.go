@@ -0,0 +1,147 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const channelPrefix = "events-"
+
+// RedisBroker delivers events across instances: each user's events are
+// appended to a capped stream, which they are replayed from, and published on
+// a channel every instance listens to and dispatches to its own subscribers.
+type RedisBroker struct {
+	rdb    *redis.Client
+	hub    *hub
+	replay int
+	logger *zap.SugaredLogger
+}
+
+// NewRedisBroker returns a broker listening to the events published by every
+// instance until ctx is done.
+func NewRedisBroker(ctx context.Context, rdb *redis.Client, replay int, logger *zap.SugaredLogger) *RedisBroker {
+	b := &RedisBroker{
+		rdb:    rdb,
+		hub:    newHub(),
+		replay: replay,
+		logger: logger,
+	}
+
+	go b.listen(ctx)
+
+	return b
+}
+
+func (b *RedisBroker) Publish(ctx context.Context, userIDs []int64, e Event) error {
+	for _, userID := range userIDs {
+		id, err := b.rdb.XAdd(ctx, &redis.XAddArgs{
+			Stream: logKey(userID),
+			MaxLen: int64(b.replay),
+			Approx: true,
+			Values: map[string]any{"type": e.Type, "data": string(e.Data)},
+		}).Result()
+		if err != nil {
+			return err
+		}
+
+		e.ID = id
+
+		msg, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+
+		if err := b.rdb.Publish(ctx, channelKey(userID), msg).Err(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (b *RedisBroker) Signal(ctx context.Context, userIDs []int64, e Event) error {
+	e.ID = ""
+
+	msg, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pipe.Publish(ctx, channelKey(userID), msg)
+		}
+		return nil
+	})
+
+	return err
+}
+
+func (b *RedisBroker) Subscribe(ctx context.Context, userID int64, lastEventID string) ([]Event, *Subscription, error) {
+	sub := b.hub.add(userID)
+
+	if lastEventID == "" {
+		return nil, sub, nil
+	}
+
+	msgs, err := b.rdb.XRangeN(ctx, logKey(userID), "("+lastEventID, "+", int64(b.replay)).Result()
+	if err != nil {
+		sub.Close()
+		return nil, nil, err
+	}
+
+	missed := make([]Event, len(msgs))
+	for i, msg := range msgs {
+		typ, _ := msg.Values["type"].(string)
+		data, _ := msg.Values["data"].(string)
+
+		missed[i] = Event{ID: msg.ID, Type: typ, Data: json.RawMessage(data)}
+	}
+
+	return missed, sub, nil
+}
+
+func (b *RedisBroker) listen(ctx context.Context) {
+	pubsub := b.rdb.PSubscribe(ctx, channelPrefix+"*")
+	defer pubsub.Close()
+
+	ch := pubsub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			userID, err := strconv.ParseInt(strings.TrimPrefix(msg.Channel, channelPrefix), 10, 64)
+			if err != nil {
+				continue
+			}
+
+			var e Event
+			if err := json.Unmarshal([]byte(msg.Payload), &e); err != nil {
+				b.logger.Errorw("invalid event received", "channel", msg.Channel, "error", err)
+				continue
+			}
+
+			b.hub.deliver(userID, e)
+		}
+	}
+}
+
+func channelKey(userID int64) string {
+	return fmt.Sprintf("%s%d", channelPrefix, userID)
+}
+
+func logKey(userID int64) string {
+	return fmt.Sprintf("events-log-%d", userID)
+}
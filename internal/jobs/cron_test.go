@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// a Wednesday
+	now := time.Date(2026, 3, 4, 15, 30, 20, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "* * * * *", want: time.Date(2026, 3, 4, 15, 31, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2026, 3, 4, 15, 45, 0, 0, time.UTC)},
+		{spec: "30 15 * * *", want: time.Date(2026, 3, 5, 15, 30, 0, 0, time.UTC)},
+		{spec: "@hourly", want: time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC)},
+		{spec: "@daily", want: time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)},
+		{spec: "@weekly", want: time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 7", want: time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 9 * * 1-5", want: time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1,15 * *", want: time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 1 1 *", want: time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// either the day of month or the day of week
+		{spec: "0 0 20 * 5", want: time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 0 30 2 *", want: time.Time{}},
+	}
+
+	for _, tt := range tests {
+		cron, err := parseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", tt.spec, err)
+		}
+
+		if got := cron.Next(now); !got.Equal(tt.want) {
+			t.Errorf("Next of %q = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseCronInvalid(t *testing.T) {
+	specs := []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@yearly"}
+
+	for _, spec := range specs {
+		if _, err := parseCron(spec); !errors.Is(err, ErrInvalidSchedule) {
+			t.Errorf("parseCron(%q) = %v, want %v", spec, err, ErrInvalidSchedule)
+		}
+	}
+}
@@ -0,0 +1,385 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+type Config struct {
+	IsEnabled bool
+	// PollInterval is how often the queue is checked for due jobs, and the
+	// schedules for jobs to enqueue.
+	PollInterval time.Duration
+	// Concurrency is the number of jobs run at once.
+	Concurrency int
+	// Timeout bounds each run of a job.
+	Timeout time.Duration
+	// MaxAttempts is the number of runs after which a job is failed, unless
+	// it is enqueued with its own.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, it doubles with every
+	// failed run up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Retention is how long finished jobs are kept, and their unique keys
+	// taken. They are kept forever when zero.
+	Retention time.Duration
+}
+
+// Args are the arguments of a job, encoded as JSON. Their kind names the
+// handler the job is run by, and must be defined on the value rather than
+// on a pointer.
+type Args interface {
+	Kind() string
+}
+
+type Options struct {
+	// RunAt delays the job until then, instead of running it right away.
+	RunAt time.Time
+	// UniqueKey keeps the job from being enqueued when one of the same kind
+	// already has that key, until it is pruned.
+	UniqueKey string
+	// MaxAttempts overrides the Config's.
+	MaxAttempts int
+}
+
+// Stats are a snapshot of the queue, for the metrics.
+type Stats struct {
+	// Pending counts the jobs waiting to run, of every instance, by kind.
+	Pending   map[string]int `json:"pending"`
+	Running   int64          `json:"running"`
+	Completed int64          `json:"completed"`
+	Retried   int64          `json:"retried"`
+	Failed    int64          `json:"failed"`
+}
+
+type handler func(ctx context.Context, job *models.Job) error
+
+type schedule struct {
+	cron *cronSchedule
+	args Args
+	next time.Time
+}
+
+// Queue runs background jobs stored in Postgres. Jobs are claimed with SKIP
+// LOCKED so that several instances can work side by side, and retried with
+// an exponential backoff until they run out of attempts.
+type Queue struct {
+	store  store.Storage
+	config Config
+	logger *zap.SugaredLogger
+
+	handlers  map[string]handler
+	schedules []*schedule
+
+	// slots holds a token for every job running
+	slots chan struct{}
+	wg    sync.WaitGroup
+	// pollCtx stops the claiming of jobs, workCtx the jobs running
+	pollCtx    context.Context
+	stopPoll   context.CancelFunc
+	workCtx    context.Context
+	cancelWork context.CancelFunc
+	done       chan struct{}
+
+	completed, retried, failed atomic.Int64
+}
+
+func New(store store.Storage, config Config, logger *zap.SugaredLogger) *Queue {
+	q := &Queue{
+		store:    store,
+		config:   config,
+		logger:   logger,
+		handlers: map[string]handler{},
+		slots:    make(chan struct{}, max(config.Concurrency, 1)),
+	}
+
+	Register(q, q.prune)
+	if config.Retention > 0 {
+		_ = q.Schedule("@hourly", pruneArgs{})
+	}
+
+	return q
+}
+
+// Register sets the handler of the jobs of T's kind. Handlers are registered
+// before the queue is started.
+func Register[T Args](q *Queue, fn func(ctx context.Context, args T) error) {
+	var kind T
+
+	q.handlers[kind.Kind()] = func(ctx context.Context, job *models.Job) error {
+		var args T
+		if err := json.Unmarshal(job.Args, &args); err != nil {
+			return err
+		}
+
+		return fn(ctx, args)
+	}
+}
+
+// Schedule enqueues a job with args on a cron schedule, in UTC. Every
+// instance enqueues it, the unique key keeping only one of them.
+func (q *Queue) Schedule(spec string, args Args) error {
+	cron, err := parseCron(spec)
+	if err != nil {
+		return err
+	}
+
+	q.schedules = append(q.schedules, &schedule{cron: cron, args: args})
+	return nil
+}
+
+// Enqueue queues a job with args. It returns store.ErrDuplicateKey when the
+// unique key of the options is taken.
+func (q *Queue) Enqueue(ctx context.Context, args Args, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+
+	job := &models.Job{
+		Kind:        args.Kind(),
+		Args:        data,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+
+	if job.MaxAttempts == 0 {
+		job.MaxAttempts = q.config.MaxAttempts
+	}
+
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	return q.store.Jobs.Enqueue(ctx, job)
+}
+
+// Start runs the jobs in the background until Shutdown.
+func (q *Queue) Start() {
+	if !q.config.IsEnabled {
+		return
+	}
+
+	q.pollCtx, q.stopPoll = context.WithCancel(context.Background())
+	q.workCtx, q.cancelWork = context.WithCancel(context.Background())
+	q.done = make(chan struct{})
+
+	now := time.Now().UTC()
+	for _, s := range q.schedules {
+		s.next = s.cron.Next(now)
+	}
+
+	go q.poll()
+}
+
+// Shutdown stops claiming jobs and waits for the running ones to finish.
+// When ctx is done first they are cancelled, to be run again once their
+// lease is over.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	if q.done == nil {
+		return nil
+	}
+
+	q.stopPoll()
+	<-q.done
+
+	drained := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		q.cancelWork()
+		return nil
+	case <-ctx.Done():
+		q.cancelWork()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the queue.
+func (q *Queue) Stats(ctx context.Context) (Stats, error) {
+	pending, err := q.store.Jobs.CountPending(ctx)
+	if err != nil {
+		return Stats{}, err
+	}
+
+	return Stats{
+		Pending:   pending,
+		Running:   int64(len(q.slots)),
+		Completed: q.completed.Load(),
+		Retried:   q.retried.Load(),
+		Failed:    q.failed.Load(),
+	}, nil
+}
+
+func (q *Queue) poll() {
+	defer close(q.done)
+
+	ticker := time.NewTicker(q.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		q.enqueueScheduled(q.pollCtx, time.Now().UTC())
+
+		if err := q.claim(q.pollCtx); err != nil && q.pollCtx.Err() == nil {
+			q.logger.Errorw("failed to claim jobs", "error", err)
+		}
+
+		select {
+		case <-q.pollCtx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// enqueueScheduled enqueues the scheduled jobs due by now. Their unique key
+// is their time, so that only one instance enqueues them.
+func (q *Queue) enqueueScheduled(ctx context.Context, now time.Time) {
+	for _, s := range q.schedules {
+		if s.next.IsZero() || now.Before(s.next) {
+			continue
+		}
+
+		err := q.Enqueue(ctx, s.args, &Options{
+			RunAt:     s.next,
+			UniqueKey: "cron:" + s.next.Format(time.RFC3339),
+		})
+		if err != nil && !errors.Is(err, store.ErrDuplicateKey) {
+			// tried again on the next poll
+			q.logger.Errorw("failed to enqueue scheduled job", "kind", s.args.Kind(), "error", err)
+			continue
+		}
+
+		s.next = s.cron.Next(now)
+	}
+}
+
+// claim runs as many due jobs as there are free slots.
+func (q *Queue) claim(ctx context.Context) error {
+	free := cap(q.slots) - len(q.slots)
+	if free == 0 {
+		return nil
+	}
+
+	kinds := make([]string, 0, len(q.handlers))
+	for kind := range q.handlers {
+		kinds = append(kinds, kind)
+	}
+
+	// the lease outlasts the run, its timeout included
+	jobs, err := q.store.Jobs.ClaimDue(ctx, kinds, free, 2*q.config.Timeout)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		q.slots <- struct{}{}
+		q.wg.Go(func() {
+			defer func() { <-q.slots }()
+			q.run(&job)
+		})
+	}
+
+	return nil
+}
+
+func (q *Queue) run(job *models.Job) {
+	// the attempt was counted when claimed, a job past its last one crashed
+	// or outlived its lease
+	if job.Attempts > job.MaxAttempts {
+		q.finish(job, models.JobFailed, "ran out of attempts")
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(q.workCtx, q.config.Timeout)
+	defer cancel()
+
+	err := q.call(ctx, job)
+
+	switch {
+	case err == nil:
+		q.finish(job, models.JobDone, "")
+	case q.workCtx.Err() != nil:
+		// cancelled by the shutdown, it will run again
+	case job.Attempts >= job.MaxAttempts:
+		q.logger.Errorw("job failed", "jobID", job.ID, "kind", job.Kind, "error", err)
+		q.finish(job, models.JobFailed, err.Error())
+	default:
+		q.logger.Warnw("job will be retried", "jobID", job.ID, "kind", job.Kind, "attempt", job.Attempts, "error", err)
+		q.retried.Add(1)
+
+		runAt := time.Now().Add(utils.Backoff(q.config.BaseDelay, q.config.MaxDelay, job.Attempts))
+		if err := q.store.Jobs.Retry(context.Background(), job.ID, err.Error(), runAt); err != nil {
+			q.logger.Errorw("failed to record job run", "jobID", job.ID, "error", err)
+		}
+	}
+}
+
+// call runs the handler of a job, turning its panics into errors.
+func (q *Queue) call(ctx context.Context, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+
+	h, ok := q.handlers[job.Kind]
+	if !ok {
+		return fmt.Errorf("no handler for jobs of kind %q", job.Kind)
+	}
+
+	return h(ctx, job)
+}
+
+func (q *Queue) finish(job *models.Job, status, lastError string) {
+	if status == models.JobDone {
+		q.completed.Add(1)
+	} else {
+		q.failed.Add(1)
+	}
+
+	// recorded even while shutting down
+	if err := q.store.Jobs.Finish(context.Background(), job.ID, status, lastError); err != nil {
+		q.logger.Errorw("failed to record job run", "jobID", job.ID, "error", err)
+	}
+}
+
+// pruneArgs is the job deleting the jobs finished for longer than Retention.
+type pruneArgs struct{}
+
+func (pruneArgs) Kind() string { return "jobs.prune" }
+
+func (q *Queue) prune(ctx context.Context, _ pruneArgs) error {
+	n, err := q.store.Jobs.Prune(ctx, time.Now().Add(-q.config.Retention))
+	if err != nil {
+		return err
+	}
+
+	q.logger.Infow("pruned finished jobs", "count", n)
+	return nil
+}
@@ -0,0 +1,235 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"go.uber.org/zap"
+)
+
+type testJobs struct {
+	mu       sync.Mutex
+	enqueued []*models.Job
+	keys     map[string]bool
+	claim    []models.Job
+	status   map[int64]string
+	retried  map[int64]time.Time
+}
+
+func newTestJobs() *testJobs {
+	return &testJobs{keys: map[string]bool{}, status: map[int64]string{}, retried: map[int64]time.Time{}}
+}
+
+func (s *testJobs) Enqueue(_ context.Context, j *models.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j.UniqueKey != nil {
+		if s.keys[j.Kind+*j.UniqueKey] {
+			return store.ErrDuplicateKey
+		}
+		s.keys[j.Kind+*j.UniqueKey] = true
+	}
+
+	s.enqueued = append(s.enqueued, j)
+	return nil
+}
+
+func (s *testJobs) ClaimDue(context.Context, []string, int, time.Duration) ([]models.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs := s.claim
+	s.claim = nil
+	return jobs, nil
+}
+
+func (s *testJobs) Finish(_ context.Context, jobID int64, status, _ string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.status[jobID] = status
+	return nil
+}
+
+func (s *testJobs) Retry(_ context.Context, jobID int64, _ string, runAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.retried[jobID] = runAt
+	return nil
+}
+
+func (s *testJobs) Prune(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (s *testJobs) CountPending(context.Context) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+type greetArgs struct {
+	Name string `json:"name"`
+}
+
+func (greetArgs) Kind() string { return "test.greet" }
+
+func newTestQueue(jobs *testJobs, cfg Config) *Queue {
+	return New(store.Storage{Jobs: jobs}, cfg, zap.NewNop().Sugar())
+}
+
+func TestRun(t *testing.T) {
+	cfg := Config{Timeout: time.Second, MaxAttempts: 3, BaseDelay: time.Minute, MaxDelay: time.Hour}
+
+	tests := []struct {
+		name        string
+		attempts    int
+		err         error
+		panics      bool
+		wantStatus  string
+		wantRetried bool
+	}{
+		{name: "done", attempts: 1, wantStatus: models.JobDone},
+		{name: "retried", attempts: 1, err: errors.New("unavailable"), wantRetried: true},
+		{name: "failed", attempts: 3, err: errors.New("unavailable"), wantStatus: models.JobFailed},
+		{name: "panicked", attempts: 1, panics: true, wantRetried: true},
+		{name: "out of attempts", attempts: 4, wantStatus: models.JobFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := newTestJobs()
+			q := newTestQueue(jobs, cfg)
+			q.workCtx = context.Background()
+
+			var got string
+			Register(q, func(_ context.Context, args greetArgs) error {
+				if tt.panics {
+					panic("boom")
+				}
+
+				got = args.Name
+				return tt.err
+			})
+
+			job := &models.Job{
+				ID:          1,
+				Kind:        greetArgs{}.Kind(),
+				Args:        []byte(`{"name":"gopher"}`),
+				Attempts:    tt.attempts,
+				MaxAttempts: cfg.MaxAttempts,
+			}
+			q.run(job)
+
+			if jobs.status[1] != tt.wantStatus {
+				t.Errorf("got status %q, want %q", jobs.status[1], tt.wantStatus)
+			}
+
+			if _, retried := jobs.retried[1]; retried != tt.wantRetried {
+				t.Errorf("got retried %v, want %v", retried, tt.wantRetried)
+			}
+
+			if tt.attempts <= cfg.MaxAttempts && !tt.panics && got != "gopher" {
+				t.Errorf("got args %q, want %q", got, "gopher")
+			}
+		})
+	}
+}
+
+func TestEnqueueScheduled(t *testing.T) {
+	jobs := newTestJobs()
+
+	// two instances share the schedule
+	var queues []*Queue
+	for range 2 {
+		q := newTestQueue(jobs, Config{MaxAttempts: 3})
+		if err := q.Schedule("@hourly", greetArgs{Name: "gopher"}); err != nil {
+			t.Fatal(err)
+		}
+
+		q.schedules[0].next = time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC)
+		queues = append(queues, q)
+	}
+
+	now := time.Date(2026, 3, 4, 16, 0, 5, 0, time.UTC)
+	for _, q := range queues {
+		q.enqueueScheduled(context.Background(), now)
+		q.enqueueScheduled(context.Background(), now)
+	}
+
+	if len(jobs.enqueued) != 1 {
+		t.Fatalf("got %d jobs enqueued, want 1", len(jobs.enqueued))
+	}
+
+	if want := time.Date(2026, 3, 4, 16, 0, 0, 0, time.UTC); !jobs.enqueued[0].RunAt.Equal(want) {
+		t.Errorf("got run at %v, want %v", jobs.enqueued[0].RunAt, want)
+	}
+
+	for _, q := range queues {
+		if want := time.Date(2026, 3, 4, 17, 0, 0, 0, time.UTC); !q.schedules[0].next.Equal(want) {
+			t.Errorf("got next run %v, want %v", q.schedules[0].next, want)
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	cfg := Config{IsEnabled: true, PollInterval: time.Millisecond, Concurrency: 2, Timeout: time.Minute, MaxAttempts: 3}
+
+	t.Run("drains", func(t *testing.T) {
+		jobs := newTestJobs()
+		jobs.claim = []models.Job{{ID: 1, Kind: greetArgs{}.Kind(), Args: []byte(`{}`), Attempts: 1, MaxAttempts: 3}}
+
+		started := make(chan struct{})
+		q := newTestQueue(jobs, cfg)
+		Register(q, func(context.Context, greetArgs) error {
+			close(started)
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		})
+
+		q.Start()
+		<-started
+
+		if err := q.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if jobs.status[1] != models.JobDone {
+			t.Errorf("got status %q, want %q", jobs.status[1], models.JobDone)
+		}
+	})
+
+	t.Run("cancels", func(t *testing.T) {
+		jobs := newTestJobs()
+		jobs.claim = []models.Job{{ID: 1, Kind: greetArgs{}.Kind(), Args: []byte(`{}`), Attempts: 1, MaxAttempts: 3}}
+
+		started := make(chan struct{})
+		q := newTestQueue(jobs, cfg)
+		Register(q, func(ctx context.Context, _ greetArgs) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		q.Start()
+		<-started
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		if err := q.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("got error %v, want %v", err, context.DeadlineExceeded)
+		}
+
+		// left to run again
+		if _, ok := jobs.status[1]; ok || len(jobs.retried) != 0 {
+			t.Errorf("got status %q and %d retries, want the job left pending", jobs.status[1], len(jobs.retried))
+		}
+	})
+}
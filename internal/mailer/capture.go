@@ -0,0 +1,147 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// maxCaptured is the number of mails the CaptureClient keeps in memory, the
+// oldest being dropped first.
+const maxCaptured = 100
+
+// CapturedMail is a mail the CaptureClient kept instead of sending it.
+type CapturedMail struct {
+	ID        string    `json:"id"`
+	Template  string    `json:"template"`
+	Username  string    `json:"username"`
+	Email     string    `json:"email"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"-"`
+	IsSandbox bool      `json:"isSandbox"`
+	SentAt    time.Time `json:"sentAt"`
+}
+
+// CaptureClient renders mails without sending them, for development and
+// tests. They are kept in memory and, when it has a directory, written to it
+// as an .eml message and an .html body.
+type CaptureClient struct {
+	fromEmail string
+	dir       string
+	logger    *zap.SugaredLogger
+
+	mu    sync.RWMutex
+	mails []CapturedMail
+}
+
+func NewCaptureClient(dir, fromEmail string, logger *zap.SugaredLogger) (*CaptureClient, error) {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+
+	return &CaptureClient{
+		fromEmail: fromEmail,
+		dir:       dir,
+		logger:    logger,
+	}, nil
+}
+
+func (m *CaptureClient) Send(templateFile, username, email string, data any, isSandbox bool) error {
+	subject, body, err := Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	mail := CapturedMail{
+		ID:        id.String(),
+		Template:  templateFile,
+		Username:  username,
+		Email:     email,
+		Subject:   subject,
+		Body:      body,
+		IsSandbox: isSandbox,
+		SentAt:    time.Now(),
+	}
+
+	if m.dir != "" {
+		if err := m.write(&mail); err != nil {
+			return err
+		}
+	}
+
+	m.mu.Lock()
+	m.mails = append(m.mails, mail)
+	if len(m.mails) > maxCaptured {
+		m.mails = slices.Delete(m.mails, 0, len(m.mails)-maxCaptured)
+	}
+	m.mu.Unlock()
+
+	m.logger.Infow("Email captured", "id", mail.ID, "email", email, "subject", subject)
+	return nil
+}
+
+// write saves a mail as <id>.eml and <id>.html.
+func (m *CaptureClient) write(mail *CapturedMail) error {
+	f, err := os.Create(filepath.Join(m.dir, mail.ID+".eml"))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	message := newMessage(m.fromEmail, mail.Username, mail.Email, mail.Subject, mail.Body)
+	message.SetDateHeader("Date", mail.SentAt)
+
+	if _, err := message.WriteTo(f); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(m.dir, mail.ID+".html"), []byte(mail.Body), 0o644)
+}
+
+// Mails returns the captured mails, the latest first.
+func (m *CaptureClient) Mails() []CapturedMail {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	mails := slices.Clone(m.mails)
+	slices.Reverse(mails)
+
+	return mails
+}
+
+// Mail returns the captured mail with the given id, if it is still kept.
+func (m *CaptureClient) Mail(id string) (CapturedMail, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, mail := range m.mails {
+		if mail.ID == id {
+			return mail, true
+		}
+	}
+
+	return CapturedMail{}, false
+}
+
+// Reset forgets the mails kept in memory.
+func (m *CaptureClient) Reset() {
+	m.mu.Lock()
+	m.mails = nil
+	m.mu.Unlock()
+}
@@ -0,0 +1,105 @@
+package mailer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestCaptureClient(t *testing.T) {
+	dir := t.TempDir()
+
+	client, err := NewCaptureClient(dir, "GopherSocial <no-reply@gophersocial.local>", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, username := range []string{"rob", "ken"} {
+		data := map[string]any{"Username": username, "ActivationURL": "http://localhost:3000/confirm/" + username}
+		if err := client.Send(UserWelcomeTemplate, username, username+"@example.com", data, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	mails := client.Mails()
+	if len(mails) != 2 || mails[0].Username != "ken" {
+		t.Fatalf("got %d mails, want 2 with ken's first", len(mails))
+	}
+
+	if !strings.Contains(mails[0].Body, "http://localhost:3000/confirm/ken") {
+		t.Errorf("body lacks the activation link:\n%s", mails[0].Body)
+	}
+
+	if mail, ok := client.Mail(mails[1].ID); !ok || mail.Username != "rob" {
+		t.Errorf("got mail %+v, want rob's", mail)
+	}
+
+	eml, err := os.ReadFile(filepath.Join(dir, mails[0].ID+".eml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Subject: Finish registration with GopherSocial", `To: "ken" <ken@example.com>`, "Date: "} {
+		if !strings.Contains(string(eml), want) {
+			t.Errorf("message lacks %q:\n%s", want, eml)
+		}
+	}
+
+	html, err := os.ReadFile(filepath.Join(dir, mails[0].ID+".html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(html) != mails[0].Body {
+		t.Errorf("got html file %q, want the body", html)
+	}
+
+	client.Reset()
+	if len(client.Mails()) != 0 {
+		t.Errorf("got %d mails after reset, want none", len(client.Mails()))
+	}
+}
+
+func TestCaptureClientLimit(t *testing.T) {
+	client, err := NewCaptureClient("", "no-reply@gophersocial.local", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := SampleData(UserWelcomeTemplate)
+	for range maxCaptured + 5 {
+		if err := client.Send(UserWelcomeTemplate, "gopher", "gopher@example.com", data, true); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(client.Mails()); got != maxCaptured {
+		t.Errorf("got %d mails, want %d", got, maxCaptured)
+	}
+}
+
+func TestSampleData(t *testing.T) {
+	templates, err := Templates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(templates) == 0 {
+		t.Fatal("got no templates")
+	}
+
+	for _, name := range templates {
+		subject, body, err := Render(name, SampleData(name))
+		if err != nil {
+			t.Errorf("Render(%q): %v", name, err)
+			continue
+		}
+
+		if subject == "" || strings.Contains(subject+body, "<no value>") {
+			t.Errorf("sample data of %q is missing values:\n%s\n%s", name, subject, body)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package mailer
 
-import "embed"
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"text/template"
+
+	"go.uber.org/zap"
+)
 
 const (
 	FromName            = "GopherSocial"
-	maxRetries          = 3
 	UserWelcomeTemplate = "user_invitation.tmpl"
+	DigestTemplate      = "digest.tmpl"
 )
 
 //go:embed "templates"
@@ -14,3 +21,56 @@ var FS embed.FS
 type Client interface {
 	Send(templateFile, username, email string, data any, isSandbox bool) error
 }
+
+type Config struct {
+	// Provider is either "smtp", "capture", "resend", "sendgrid" or
+	// "mailtrap".
+	Provider  string
+	FromEmail string
+	SMTP      SMTPConfig
+	// CaptureDir is where captured mails are written, they are only kept in
+	// memory when empty.
+	CaptureDir string
+	// The API keys of the cloud providers.
+	ResendAPIKey   string
+	SendGridAPIKey string
+	MailTrapAPIKey string
+}
+
+// New returns the client of the configured provider.
+func New(cfg Config, logger *zap.SugaredLogger) (Client, error) {
+	switch cfg.Provider {
+	case "smtp":
+		return NewSMTPClient(cfg.SMTP, cfg.FromEmail, logger)
+	case "capture":
+		return NewCaptureClient(cfg.CaptureDir, cfg.FromEmail, logger)
+	case "resend":
+		return NewResendClient(cfg.ResendAPIKey, cfg.FromEmail, logger)
+	case "sendgrid":
+		return NewSendGrid(cfg.SendGridAPIKey, cfg.FromEmail, logger), nil
+	case "mailtrap":
+		return NewMailTrapClient(cfg.MailTrapAPIKey, cfg.FromEmail)
+	default:
+		return nil, errors.New("unknown mail provider " + cfg.Provider)
+	}
+}
+
+// Render executes the subject and body of a template with data.
+func Render(templateFile string, data any) (subject, body string, err error) {
+	tmpl, err := template.ParseFS(FS, "templates/"+templateFile)
+	if err != nil {
+		return "", "", err
+	}
+
+	subjectBuf := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(subjectBuf, "subject", data); err != nil {
+		return "", "", err
+	}
+
+	bodyBuf := new(bytes.Buffer)
+	if err := tmpl.ExecuteTemplate(bodyBuf, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return subjectBuf.String(), bodyBuf.String(), nil
+}
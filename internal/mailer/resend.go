@@ -6,7 +6,6 @@ import (
 	"errors"
 	"fmt"
 	"text/template"
-	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/store"
 	_ "github.com/sandoxlabs99/gopher_social/internal/store"
@@ -67,23 +66,11 @@ func (m *ResendClient) Send(templateFile, username, email string, data any, isSa
 	// ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond) // use to test for email failure
 	defer cancel()
 
-	for i := range maxRetries {
-		response, err := m.client.Emails.SendWithContext(ctx, params)
-		if err != nil {
-			errMsg := fmt.Sprintf("failed to send an email, attempt %d of %d", i+1, maxRetries)
-			m.logger.Warnw(
-				errMsg,
-				"error", err.Error(),
-			)
-
-			// exponential backoff
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
-		}
-
-		m.logger.Infof("Email sent with response id %v", response.Id)
-		return nil
+	response, err := m.client.Emails.SendWithContext(ctx, params)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	return fmt.Errorf("failed to send email after %d attempts, error: %v", maxRetries, err)
+	m.logger.Infof("Email sent with response id %v", response.Id)
+	return nil
 }
@@ -0,0 +1,76 @@
+package mailer
+
+import (
+	"io/fs"
+	"path"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+// samples make up the data of each template, for previews.
+var samples = map[string]func() any{
+	UserWelcomeTemplate: func() any {
+		return struct {
+			Username      string
+			ActivationURL string
+		}{
+			Username:      "gopher",
+			ActivationURL: "http://localhost:3000/confirm/00000000-0000-0000-0000-000000000000",
+		}
+	},
+	DigestTemplate: func() any {
+		return struct {
+			Username       string
+			Frequency      string
+			Digest         *models.Digest
+			FrontendURL    string
+			SettingsURL    string
+			UnsubscribeURL string
+		}{
+			Username:  "gopher",
+			Frequency: models.DigestWeekly,
+			Digest: &models.Digest{
+				Followers:     []models.User{{ID: 2, Username: "rob"}, {ID: 3, Username: "ken"}},
+				FollowerCount: 7,
+				TopPosts: []models.DigestPost{
+					{ID: 1, Title: "Generics, a year later", Author: "rob", CommentCount: 12, RepostCount: 4},
+				},
+				Replies: []models.DigestReply{
+					{CommentID: 5, PostID: 9, PostTitle: "Hello, gophers", Author: "ken", Content: "Welcome aboard!", CreatedAt: time.Now()},
+				},
+				ReplyCount: 3,
+			},
+			FrontendURL:    "http://localhost:3000",
+			SettingsURL:    "http://localhost:3000/settings/notifications",
+			UnsubscribeURL: "http://localhost:3000/unsubscribe/token",
+		}
+	},
+}
+
+// SampleData returns the sample data of a template. The templates without
+// any are previewed with an empty map.
+func SampleData(templateFile string) any {
+	if sample, ok := samples[templateFile]; ok {
+		return sample()
+	}
+
+	return map[string]any{}
+}
+
+// Templates lists the templates in FS.
+func Templates() ([]string, error) {
+	entries, err := fs.ReadDir(FS, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	var templates []string
+	for _, entry := range entries {
+		if !entry.IsDir() && path.Ext(entry.Name()) == ".tmpl" {
+			templates = append(templates, entry.Name())
+		}
+	}
+
+	return templates, nil
+}
@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"fmt"
 	"text/template"
-	"time"
 
 	"github.com/sendgrid/sendgrid-go"
 	"github.com/sendgrid/sendgrid-go/helpers/mail"
@@ -59,23 +58,11 @@ func (m *SendGridMailer) Send(templateFile, username, email string, data any, is
 		},
 	})
 
-	for i := range maxRetries {
-		response, err := m.client.Send(message)
-		if err != nil {
-			errMsg := fmt.Sprintf("failed to send email to %v, attempt %d of %d", email, i+1, maxRetries)
-			m.logger.Warnw(
-				errMsg,
-				"error", err.Error(),
-			)
-
-			// exponential backoff
-			time.Sleep(time.Second * time.Duration(i+1))
-			continue
-		}
-
-		m.logger.Infof("Email sent with status code %v", response.StatusCode)
-		return nil
+	response, err := m.client.Send(message)
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
 	}
 
-	return fmt.Errorf("failed to send email after %d attempts", maxRetries)
+	m.logger.Infof("Email sent with status code %v", response.StatusCode)
+	return nil
 }
@@ -0,0 +1,206 @@
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/smtp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	gomail "gopkg.in/mail.v2"
+)
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate to the server, unless Username is
+	// empty.
+	Username string
+	Password string
+	// Security is either "starttls", upgrading the connection and failing
+	// if the server can't, "tls" for implicit TLS, usually on port 465, or
+	// "none" for local servers.
+	Security string
+	// Auth is the authentication mechanism, either "plain" or "login".
+	Auth string
+	// IdleTimeout is how long the connection is kept open, for the next
+	// mails, after the last one is sent.
+	IdleTimeout time.Duration
+}
+
+// SMTPClient sends mails through an SMTP server, such as a local MailHog.
+// Mails are sent one at a time over a connection kept open between them.
+type SMTPClient struct {
+	fromEmail   string
+	dialer      *gomail.Dialer
+	idleTimeout time.Duration
+	logger      *zap.SugaredLogger
+
+	mu     sync.Mutex
+	sender gomail.SendCloser
+	idle   *time.Timer
+}
+
+func NewSMTPClient(cfg SMTPConfig, fromEmail string, logger *zap.SugaredLogger) (*SMTPClient, error) {
+	if cfg.Host == "" {
+		return nil, errors.New("smtp host is required")
+	}
+
+	dialer := gomail.NewDialer(cfg.Host, cfg.Port, cfg.Username, cfg.Password)
+
+	switch cfg.Security {
+	case "starttls":
+		dialer.SSL = false
+		dialer.StartTLSPolicy = gomail.MandatoryStartTLS
+	case "tls":
+		dialer.SSL = true
+	case "none":
+		dialer.SSL = false
+		dialer.StartTLSPolicy = gomail.NoStartTLS
+	default:
+		return nil, errors.New("unknown smtp security " + cfg.Security)
+	}
+
+	// set rather than negotiated, the dialer would pick CRAM-MD5 otherwise
+	if cfg.Username != "" {
+		switch cfg.Auth {
+		case "plain":
+			dialer.Auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+		case "login":
+			dialer.Auth = &loginAuth{username: cfg.Username, password: cfg.Password, host: cfg.Host}
+		default:
+			return nil, errors.New("unknown smtp auth " + cfg.Auth)
+		}
+	}
+
+	return &SMTPClient{
+		fromEmail:   fromEmail,
+		dialer:      dialer,
+		idleTimeout: cfg.IdleTimeout,
+		logger:      logger,
+	}, nil
+}
+
+// Send sends a mail, isSandbox aside: mails to a local server go nowhere
+// anyway.
+func (m *SMTPClient) Send(templateFile, username, email string, data any, isSandbox bool) error {
+	subject, body, err := Render(templateFile, data)
+	if err != nil {
+		return err
+	}
+
+	message := newMessage(m.fromEmail, username, email, subject, body)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.sender == nil {
+		sender, err := m.dialer.Dial()
+		if err != nil {
+			return fmt.Errorf("failed to connect to the smtp server: %w", err)
+		}
+
+		m.sender = sender
+	}
+
+	if err := gomail.Send(m.sender, message); err != nil {
+		// the session may be left mid-transaction, the next mail gets a new
+		// connection
+		_ = m.closeSender()
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	m.resetIdle()
+
+	m.logger.Infof("Email sent to %v through smtp", email)
+	return nil
+}
+
+// Close closes the connection to the server, if open.
+func (m *SMTPClient) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.idle != nil {
+		m.idle.Stop()
+	}
+
+	return m.closeSender()
+}
+
+// resetIdle closes the connection once it has been idle for IdleTimeout.
+func (m *SMTPClient) resetIdle() {
+	if m.idle != nil {
+		m.idle.Reset(m.idleTimeout)
+		return
+	}
+
+	m.idle = time.AfterFunc(m.idleTimeout, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+
+		_ = m.closeSender()
+	})
+}
+
+func (m *SMTPClient) closeSender() error {
+	if m.sender == nil {
+		return nil
+	}
+
+	err := m.sender.Close()
+	m.sender = nil
+
+	return err
+}
+
+func newMessage(fromEmail, username, email, subject, body string) *gomail.Message {
+	message := gomail.NewMessage()
+	message.SetHeader("From", fromEmail)
+	message.SetAddressHeader("To", email, username)
+	message.SetHeader("Subject", subject)
+	message.SetBody("text/html", body)
+
+	return message
+}
+
+// loginAuth implements the LOGIN mechanism, which net/smtp lacks. Like
+// smtp.PlainAuth, it only sends the credentials over TLS or to localhost.
+type loginAuth struct {
+	username string
+	password string
+	host     string
+}
+
+func (a *loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+
+	return "LOGIN", nil, nil
+}
+
+func (a *loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+
+	switch {
+	case bytes.EqualFold(fromServer, []byte("Username:")):
+		return []byte(a.username), nil
+	case bytes.EqualFold(fromServer, []byte("Password:")):
+		return []byte(a.password), nil
+	default:
+		return nil, fmt.Errorf("unexpected server challenge %q", fromServer)
+	}
+}
+
+func isLocalhost(name string) bool {
+	return name == "localhost" || name == "127.0.0.1" || name == "::1"
+}
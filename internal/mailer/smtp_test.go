@@ -0,0 +1,237 @@
+package mailer
+
+import (
+	"encoding/base64"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// smtpServer is a bare SMTP server accepting any mail, with LOGIN and PLAIN
+// authentication.
+type smtpServer struct {
+	listener net.Listener
+
+	mu       sync.Mutex
+	conns    int
+	users    []string
+	messages []string
+}
+
+func newSMTPServer(t *testing.T) *smtpServer {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	s := &smtpServer{listener: l}
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+
+	tp := textproto.NewConn(conn)
+	_ = tp.PrintfLine("220 localhost ready")
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			_ = tp.PrintfLine("250-localhost")
+			_ = tp.PrintfLine("250 AUTH PLAIN LOGIN")
+		case "AUTH":
+			s.auth(tp, arg)
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			_ = tp.PrintfLine("250 OK")
+		case "DATA":
+			_ = tp.PrintfLine("354 go ahead")
+
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+
+			s.mu.Lock()
+			s.messages = append(s.messages, string(data))
+			s.mu.Unlock()
+
+			_ = tp.PrintfLine("250 OK")
+		case "QUIT":
+			_ = tp.PrintfLine("221 bye")
+			return
+		default:
+			_ = tp.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+func (s *smtpServer) auth(tp *textproto.Conn, arg string) {
+	mechanism, initial, _ := strings.Cut(arg, " ")
+
+	var user string
+	switch mechanism {
+	case "PLAIN":
+		decoded, _ := base64.StdEncoding.DecodeString(initial)
+		parts := strings.Split(string(decoded), "\x00")
+		if len(parts) == 3 && parts[2] == "secret" {
+			user = parts[1]
+		}
+	case "LOGIN":
+		read := func(prompt string) string {
+			_ = tp.PrintfLine("334 %s", base64.StdEncoding.EncodeToString([]byte(prompt)))
+			line, _ := tp.ReadLine()
+			decoded, _ := base64.StdEncoding.DecodeString(line)
+			return string(decoded)
+		}
+
+		if name := read("Username:"); read("Password:") == "secret" {
+			user = name
+		}
+	}
+
+	if user == "" {
+		_ = tp.PrintfLine("535 authentication failed")
+		return
+	}
+
+	s.mu.Lock()
+	s.users = append(s.users, mechanism+":"+user)
+	s.mu.Unlock()
+
+	_ = tp.PrintfLine("235 authenticated")
+}
+
+func TestSMTPClient(t *testing.T) {
+	data := struct {
+		Username      string
+		ActivationURL string
+	}{
+		Username:      "gopher",
+		ActivationURL: "http://localhost:3000/confirm/token",
+	}
+
+	for _, auth := range []string{"plain", "login"} {
+		t.Run(auth, func(t *testing.T) {
+			srv := newSMTPServer(t)
+
+			cfg := SMTPConfig{
+				Host:        "127.0.0.1",
+				Port:        srv.port(),
+				Username:    "social",
+				Password:    "secret",
+				Security:    "none",
+				Auth:        auth,
+				IdleTimeout: time.Minute,
+			}
+
+			client, err := NewSMTPClient(cfg, "GopherSocial <no-reply@gophersocial.local>", zap.NewNop().Sugar())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+
+			for range 2 {
+				if err := client.Send(UserWelcomeTemplate, "gopher", "gopher@example.com", data, true); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			srv.mu.Lock()
+			defer srv.mu.Unlock()
+
+			if srv.conns != 1 {
+				t.Errorf("got %d connections, want the one reused", srv.conns)
+			}
+
+			if len(srv.users) != 1 || srv.users[0] != strings.ToUpper(auth)+":social" {
+				t.Errorf("got authentications %v, want one with %s", srv.users, auth)
+			}
+
+			if len(srv.messages) != 2 {
+				t.Fatalf("got %d messages, want 2", len(srv.messages))
+			}
+
+			message := srv.messages[0]
+			for _, want := range []string{"Subject: Finish registration with GopherSocial", `To: "gopher" <gopher@example.com>`} {
+				if !strings.Contains(message, want) {
+					t.Errorf("message lacks %q:\n%s", want, message)
+				}
+			}
+		})
+	}
+}
+
+func TestSMTPClientIdle(t *testing.T) {
+	srv := newSMTPServer(t)
+
+	cfg := SMTPConfig{Host: "127.0.0.1", Port: srv.port(), Security: "none", IdleTimeout: 10 * time.Millisecond}
+	client, err := NewSMTPClient(cfg, "no-reply@gophersocial.local", zap.NewNop().Sugar())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	data := map[string]any{"Username": "gopher", "ActivationURL": "http://localhost:3000/confirm/token"}
+
+	for range 2 {
+		if err := client.Send(UserWelcomeTemplate, "gopher", "gopher@example.com", data, true); err != nil {
+			t.Fatal(err)
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+
+	if srv.conns != 2 {
+		t.Errorf("got %d connections, want a new one after the idle timeout", srv.conns)
+	}
+}
+
+func TestNewSMTPClientInvalid(t *testing.T) {
+	tests := []SMTPConfig{
+		{Port: 25, Security: "none"},
+		{Host: "localhost", Port: 25, Security: "ssl"},
+		{Host: "localhost", Port: 25, Security: "none", Username: "social", Auth: "cram-md5"},
+	}
+
+	for _, cfg := range tests {
+		if _, err := NewSMTPClient(cfg, "no-reply@gophersocial.local", zap.NewNop().Sugar()); err == nil {
+			t.Errorf("NewSMTPClient(%+v) succeeded, want an error", cfg)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package media validates the images users upload and prepares them for
+// serving: their metadata is stripped and a thumbnail is generated.
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported media type")
+	ErrTooLarge        = errors.New("image dimensions are too large")
+	ErrTooSmall        = errors.New("image dimensions are too small")
+)
+
+// MaxPixels bounds the size of the images accepted once decoded, so that
+// small files can't exhaust the memory of the server.
+const MaxPixels = 40_000_000
+
+// Image is an uploaded image ready to be stored: re-encoded without its
+// metadata, along with its thumbnail.
+type Image struct {
+	Data        []byte
+	ContentType string
+	// Ext is the file extension matching ContentType, dot included.
+	Ext    string
+	Width  int
+	Height int
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExt         string
+}
+
+// Process checks that data is an image of a supported type, sniffed from its
+// content rather than trusted from the client, and re-encodes it. Decoding
+// and encoding it again drops every metadata it carries, EXIF included, so
+// its orientation is applied to the pixels first. The thumbnail fits in a
+// thumbSize square.
+func Process(data []byte, thumbSize int) (*Image, error) {
+	contentType, _, err := check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType == "image/gif" {
+		return processGIF(data, thumbSize)
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := encode(img, contentType == "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	thumb, err := encodeThumbnail(img, thumbSize, contentType == "image/jpeg")
+	if err != nil {
+		return nil, err
+	}
+
+	out.Thumbnail, out.ThumbnailContentType, out.ThumbnailExt = thumb.Data, thumb.ContentType, thumb.Ext
+
+	return out, nil
+}
+
+// Variant is a size an image is resized to.
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+}
+
+// Resize checks that data is an image of a supported type, at least
+// minWidth by minHeight, and makes a version of it for each variant: cropped
+// around its center to the aspect ratio of the variant, then scaled down to
+// its size. Images are never scaled up, the versions of a variant larger than
+// the image are as large as the crop. Only the first frame of GIFs is kept.
+func Resize(data []byte, minWidth, minHeight int, variants []Variant) (map[string]*Image, error) {
+	contentType, cfg, err := check(data)
+	if err != nil {
+		return nil, err
+	}
+
+	w, h := cfg.Width, cfg.Height
+	if contentType == "image/jpeg" && exifOrientation(data) >= 5 {
+		w, h = h, w
+	}
+
+	if w < minWidth || h < minHeight {
+		return nil, ErrTooSmall
+	}
+
+	img, err := decode(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make(map[string]*Image, len(variants))
+	for _, v := range variants {
+		resized, err := encode(crop(img, v.Width, v.Height), contentType == "image/jpeg")
+		if err != nil {
+			return nil, err
+		}
+
+		out[v.Name] = resized
+	}
+
+	return out, nil
+}
+
+// check sniffs the type of data from its content rather than trusting the
+// client, and checks that it is a supported image of a reasonable size.
+func check(data []byte) (string, image.Config, error) {
+	contentType := http.DetectContentType(data)
+
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return "", image.Config{}, ErrUnsupportedType
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return "", image.Config{}, ErrUnsupportedType
+	}
+
+	if cfg.Width*cfg.Height > MaxPixels {
+		return "", image.Config{}, ErrTooLarge
+	}
+
+	return contentType, cfg, nil
+}
+
+// decode decodes a checked image, turning photos upright.
+func decode(data []byte, contentType string) (image.Image, error) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if contentType == "image/jpeg" {
+		img = orient(img, exifOrientation(data))
+	}
+
+	return img, nil
+}
+
+// encode encodes photos as JPEG. There is no WebP encoder, so everything else
+// is encoded as PNG, which keeps its transparency.
+func encode(img image.Image, isPhoto bool) (*Image, error) {
+	if isPhoto {
+		return encodeJPEG(img)
+	}
+	return encodePNG(img)
+}
+
+// processGIF re-encodes every frame of a GIF, keeping it animated. Its
+// thumbnail is made from the first frame.
+func processGIF(data []byte, thumbSize int) (*Image, error) {
+	g, err := gif.DecodeAll(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrUnsupportedType
+	}
+
+	if len(g.Image)*g.Config.Width*g.Config.Height > MaxPixels {
+		return nil, ErrTooLarge
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, g); err != nil {
+		return nil, err
+	}
+
+	thumb, err := encodeThumbnail(g.Image[0], thumbSize, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Image{
+		Data:                 buf.Bytes(),
+		ContentType:          "image/gif",
+		Ext:                  ".gif",
+		Width:                g.Config.Width,
+		Height:               g.Config.Height,
+		Thumbnail:            thumb.Data,
+		ThumbnailContentType: thumb.ContentType,
+		ThumbnailExt:         thumb.Ext,
+	}, nil
+}
+
+func encodeJPEG(img image.Image) (*Image, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+
+	return &Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/jpeg",
+		Ext:         ".jpg",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+func encodePNG(img image.Image) (*Image, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	b := img.Bounds()
+
+	return &Image{
+		Data:        buf.Bytes(),
+		ContentType: "image/png",
+		Ext:         ".png",
+		Width:       b.Dx(),
+		Height:      b.Dy(),
+	}, nil
+}
+
+// encodeThumbnail scales img down to fit in a size square. Smaller images
+// are left as they are.
+func encodeThumbnail(img image.Image, size int, isPhoto bool) (*Image, error) {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+
+		img = scale(img, b, w, h)
+	}
+
+	return encode(img, isPhoto)
+}
+
+// crop cuts the largest width:height area out of the center of img and
+// scales it down to width by height if it is larger.
+func crop(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// the largest area of the variant's aspect ratio
+	cw, ch := w, w*height/width
+	if ch > h {
+		cw, ch = h*width/height, h
+	}
+	cw, ch = max(1, cw), max(1, ch)
+
+	x, y := b.Min.X+(w-cw)/2, b.Min.Y+(h-ch)/2
+	area := image.Rect(x, y, x+cw, y+ch)
+
+	if cw > width {
+		cw, ch = width, height
+	}
+
+	return scale(img, area, cw, ch)
+}
+
+// scale draws the area of img into a new w by h image.
+func scale(img image.Image, area image.Rectangle, w, h int) image.Image {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, area, draw.Src, nil)
+
+	return dst
+}
@@ -0,0 +1,143 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// withExif inserts an EXIF segment holding the given orientation and a
+// camera make right after the start of a JPEG.
+func withExif(t *testing.T, jpg []byte, orientation uint16) []byte {
+	t.Helper()
+
+	var tiff bytes.Buffer
+	tiff.WriteString("MM")
+	binary.Write(&tiff, binary.BigEndian, uint16(42))
+	binary.Write(&tiff, binary.BigEndian, uint32(8))
+	binary.Write(&tiff, binary.BigEndian, uint16(2))
+	// orientation, SHORT
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x0112, 3})
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, []uint16{orientation, 0})
+	// make, ASCII, stored right after the IFD
+	binary.Write(&tiff, binary.BigEndian, []uint16{0x010F, 2})
+	binary.Write(&tiff, binary.BigEndian, uint32(12))
+	binary.Write(&tiff, binary.BigEndian, uint32(8+2+2*12+4))
+	binary.Write(&tiff, binary.BigEndian, uint32(0))
+	tiff.WriteString("GopherCam\x00\x00\x00")
+
+	segment := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(jpg[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(segment)+2))
+	out.Write(segment)
+	out.Write(jpg[2:])
+
+	return out.Bytes()
+}
+
+func testJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 0, 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestProcessStripsExif(t *testing.T) {
+	data := withExif(t, testJPEG(t, 600, 400), 6)
+
+	if got := exifOrientation(data); got != 6 {
+		t.Fatalf("orientation = %d, want 6", got)
+	}
+
+	img, err := Process(data, 320)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(img.Data, []byte("Exif")) || bytes.Contains(img.Data, []byte("GopherCam")) {
+		t.Error("metadata was not stripped")
+	}
+
+	// rotated a quarter turn to be upright
+	if img.Width != 400 || img.Height != 600 {
+		t.Errorf("size = %dx%d, want 400x600", img.Width, img.Height)
+	}
+
+	thumb, _, err := image.DecodeConfig(bytes.NewReader(img.Thumbnail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if thumb.Width != 213 || thumb.Height != 320 {
+		t.Errorf("thumbnail size = %dx%d, want 213x320", thumb.Width, thumb.Height)
+	}
+}
+
+func TestProcessRejectsOtherTypes(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("<svg xmlns='http://www.w3.org/2000/svg'></svg>"),
+		[]byte("%PDF-1.4"),
+		// a JPEG header followed by garbage
+		{0xFF, 0xD8, 0xFF, 0xE0, 0, 0, 0, 0},
+	} {
+		if _, err := Process(data, 320); !errors.Is(err, ErrUnsupportedType) {
+			t.Errorf("Process(%q) = %v, want ErrUnsupportedType", data, err)
+		}
+	}
+}
+
+func TestResize(t *testing.T) {
+	data := testJPEG(t, 600, 400)
+
+	out, err := Resize(data, 100, 100, []Variant{
+		{Name: "small", Width: 100, Height: 100},
+		{Name: "huge", Width: 1000, Height: 1000},
+		{Name: "wide", Width: 300, Height: 100},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range map[string][2]int{
+		"small": {100, 100},
+		// never scaled up
+		"huge": {400, 400},
+		"wide": {300, 100},
+	} {
+		img := out[name]
+		if img == nil {
+			t.Fatalf("missing variant %q", name)
+		}
+
+		if img.Width != want[0] || img.Height != want[1] {
+			t.Errorf("%s: size = %dx%d, want %dx%d", name, img.Width, img.Height, want[0], want[1])
+		}
+
+		if img.ContentType != "image/jpeg" {
+			t.Errorf("%s: content type = %q", name, img.ContentType)
+		}
+	}
+
+	if _, err := Resize(data, 500, 500, nil); !errors.Is(err, ErrTooSmall) {
+		t.Errorf("Resize of a small image = %v, want ErrTooSmall", err)
+	}
+}
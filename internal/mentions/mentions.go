@@ -0,0 +1,88 @@
+// Package mentions finds the @username mentions in the content users write.
+package mentions
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxUsers bounds the number of distinct users a single content can mention,
+// the mentions of any other user are ignored.
+const MaxUsers = 10
+
+// Mention is a @username found in a content. Start and End delimit it, '@'
+// included, in characters (Unicode code points) rather than bytes so that
+// clients can use them as they are.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// Parse returns the mentions of content in order. A mention starts with an
+// '@' that does not follow a letter, a digit or an underscore, so e-mail
+// addresses are left alone. The username is made of letters, digits and
+// underscores, with dots and hyphens allowed between them.
+func Parse(content string) []Mention {
+	var mentions []Mention
+	seen := make(map[string]bool)
+
+	// pos is the index of the character at byte i
+	pos := 0
+	prev := rune(0)
+
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRuneInString(content[i:])
+
+		if r != '@' || isNameChar(prev) || prev == '@' {
+			prev = r
+			i += size
+			pos++
+			continue
+		}
+
+		name, chars := scanName(content[i+1:])
+		if name == "" {
+			prev = r
+			i += size
+			pos++
+			continue
+		}
+
+		if seen[name] || len(seen) < MaxUsers {
+			seen[name] = true
+			mentions = append(mentions, Mention{Username: name, Start: pos, End: pos + 1 + chars})
+		}
+
+		i += 1 + len(name)
+		pos += 1 + chars
+		prev, _ = utf8.DecodeLastRuneInString(name)
+	}
+
+	return mentions
+}
+
+// scanName returns the username s starts with, and its length in characters.
+func scanName(s string) (string, int) {
+	end, chars := 0, 0
+	n := 0
+
+	for i, r := range s {
+		switch {
+		case isNameChar(r):
+			n++
+			end, chars = i+utf8.RuneLen(r), n
+		case r == '.' || r == '-':
+			// only kept when followed by more of the name
+			n++
+		default:
+			return s[:end], chars
+		}
+	}
+
+	return s[:end], chars
+}
+
+func isNameChar(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,41 @@
+package mentions
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []Mention
+	}{
+		{"hi @alice!", []Mention{{"alice", 3, 9}}},
+		{"@bob.smith and @carol.", []Mention{{"bob.smith", 0, 10}, {"carol", 15, 21}}},
+		{"mail me at me@example.com", nil},
+		{"@@nope @ alone", nil},
+		{"héllo @josé-luis, @alice @alice", []Mention{{"josé-luis", 6, 16}, {"alice", 18, 24}, {"alice", 25, 31}}},
+	}
+
+	for _, tt := range tests {
+		if got := Parse(tt.content); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
+
+func TestParseBoundsUsers(t *testing.T) {
+	content := ""
+	for _, name := range []string{"a1", "a2", "a3", "a4", "a5", "a6", "a7", "a8", "a9", "a10", "a11", "a1"} {
+		content += "@" + name + " "
+	}
+
+	got := Parse(content)
+	if len(got) != MaxUsers+1 {
+		t.Fatalf("got %d mentions, want %d", len(got), MaxUsers+1)
+	}
+
+	if got[MaxUsers].Username != "a1" {
+		t.Errorf("last mention = %q, want a1", got[MaxUsers].Username)
+	}
+}
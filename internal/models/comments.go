@@ -5,10 +5,18 @@ import (
 )
 
 type Comment struct {
-	ID        int64     `json:"id"`
-	PostID    int64     `json:"postID"`
-	UserID    int64     `json:"userID"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-	User      User      `json:"user"`
+	ID         int64      `json:"id"`
+	PostID     int64      `json:"postID"`
+	UserID     int64      `json:"userID"`
+	ParentID   *int64     `json:"parentID"`
+	Depth      int        `json:"depth"`
+	Content    string     `json:"content"`
+	ReplyCount int        `json:"replyCount"`
+	IsDeleted  bool       `json:"isDeleted"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	EditedAt   *time.Time `json:"editedAt"`
+	Version    int        `json:"version"`
+	User       User       `json:"user"`
+	// Mentions are the users mentioned in the content, in order.
+	Mentions []Mention `json:"mentions"`
 }
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+type Conversation struct {
+	ID        int64                `json:"id"`
+	IsGroup   bool                 `json:"isGroup"`
+	CreatedBy int64                `json:"createdBy"`
+	CreatedAt time.Time            `json:"createdAt"`
+	UpdatedAt time.Time            `json:"updatedAt"`
+	Members   []ConversationMember `json:"members"`
+	// LastMessage and UnreadCount are only set when listing the
+	// conversations of a user.
+	LastMessage *Message `json:"lastMessage,omitempty"`
+	UnreadCount int      `json:"unreadCount"`
+}
+
+// ConversationMember is a user taking part in a conversation, along with the
+// last message they read in it.
+type ConversationMember struct {
+	ID                int64         `json:"id"`
+	Username          string        `json:"username"`
+	Avatar            ImageVariants `json:"avatar"`
+	LastReadMessageID *int64        `json:"lastReadMessageId"`
+}
+
+type Message struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversationId"`
+	SenderID       int64     `json:"senderId"`
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+// ReadReceipt tells that a member read a conversation up to a message.
+type ReadReceipt struct {
+	ConversationID int64 `json:"conversationId"`
+	UserID         int64 `json:"userId"`
+	MessageID      int64 `json:"messageId"`
+}
+
+// Typing tells that a member is typing in a conversation.
+type Typing struct {
+	ConversationID int64 `json:"conversationId"`
+	UserID         int64 `json:"userId"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Digest frequencies. Users get no digest until they opt in.
+const (
+	DigestOff    = "off"
+	DigestDaily  = "daily"
+	DigestWeekly = "weekly"
+)
+
+// Digest delivery statuses. A delivery stays sending if the server stopped
+// while mailing it, and is then never retried.
+const (
+	DigestSending = "sending"
+	DigestSent    = "sent"
+	DigestEmpty   = "empty"
+)
+
+type DigestSettings struct {
+	Frequency string `json:"frequency"`
+}
+
+// Digest sums up what happened to a user during a period.
+type Digest struct {
+	// Followers are the latest new followers, FollowerCount counts them all.
+	Followers     []User
+	FollowerCount int
+	// TopPosts are the posts of the users they follow that got the most
+	// comments and reposts.
+	TopPosts []DigestPost
+	// Replies are the latest comments on their posts and replies to their
+	// comments, ReplyCount counts them all.
+	Replies    []DigestReply
+	ReplyCount int
+}
+
+func (d *Digest) IsEmpty() bool {
+	return d.FollowerCount == 0 && len(d.TopPosts) == 0 && d.ReplyCount == 0
+}
+
+// MoreFollowers counts the new followers left out of Followers.
+func (d *Digest) MoreFollowers() int {
+	return d.FollowerCount - len(d.Followers)
+}
+
+// MoreReplies counts the replies left out of Replies.
+func (d *Digest) MoreReplies() int {
+	return d.ReplyCount - len(d.Replies)
+}
+
+type DigestPost struct {
+	ID           int64
+	Title        string
+	Author       string
+	CommentCount int
+	RepostCount  int
+}
+
+type DigestReply struct {
+	CommentID int64
+	PostID    int64
+	PostTitle string
+	Content   string
+	Author    string
+	CreatedAt time.Time
+}
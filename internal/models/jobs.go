@@ -0,0 +1,30 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Job is a unit of background work, run by the handler of its kind.
+type Job struct {
+	ID   int64
+	Kind string
+	// Args are the JSON encoded arguments of the handler.
+	Args json.RawMessage
+	// UniqueKey, when set, keeps other jobs of the same kind and key from
+	// being enqueued.
+	UniqueKey   *string
+	Status      string
+	Attempts    int
+	MaxAttempts int
+	LastError   string
+	RunAt       time.Time
+	CreatedAt   time.Time
+}
+
+// Job statuses.
+const (
+	JobPending = "pending"
+	JobDone    = "done"
+	JobFailed  = "failed"
+)
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Media is an image uploaded by a user, attached to one of their posts once
+// they publish it.
+type Media struct {
+	ID           int64     `json:"id"`
+	ContentType  string    `json:"contentType"`
+	Width        int       `json:"width"`
+	Height       int       `json:"height"`
+	Size         int       `json:"size"`
+	URL          string    `json:"url"`
+	ThumbnailURL string    `json:"thumbnailUrl"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UserID       int64     `json:"-"`
+	// Key and ThumbnailKey are where the files are kept in the blob store.
+	Key          string `json:"-"`
+	ThumbnailKey string `json:"-"`
+}
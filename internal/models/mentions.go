@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Mention is a user mentioned in the content of a post or a comment. Start
+// and End delimit the @username in the content, in characters (Unicode code
+// points).
+type Mention struct {
+	UserID   int64  `json:"userId"`
+	Username string `json:"username"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+}
+
+// MentionOfUser is a mention as listed to the user mentioned, with the
+// content it was made in.
+type MentionOfUser struct {
+	ID     int64 `json:"id"`
+	PostID int64 `json:"postId"`
+	// CommentID is set when the mention was made in a comment of the post.
+	CommentID *int64    `json:"commentId,omitempty"`
+	Content   string    `json:"content"`
+	Start     int       `json:"start"`
+	End       int       `json:"end"`
+	Author    User      `json:"author"`
+	CreatedAt time.Time `json:"createdAt"`
+}
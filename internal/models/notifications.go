@@ -0,0 +1,67 @@
+package models
+
+import "time"
+
+// Notification tells a user about something that happened to them. The
+// unread notifications of a group, such as the comments on one post, are
+// merged into one listing its latest actors.
+type Notification struct {
+	ID        int64  `json:"id"`
+	Type      string `json:"type"`
+	PostID    *int64 `json:"postId,omitempty"`
+	CommentID *int64 `json:"commentId,omitempty"`
+	// Action is what a moderator did, for moderation notifications.
+	Action string `json:"action,omitempty"`
+	// Actors are the last users who acted, the most recent first, up to
+	// three. ActorCount counts them all.
+	Actors     []User    `json:"actors"`
+	ActorCount int       `json:"actorCount"`
+	Summary    string    `json:"summary"`
+	IsRead     bool      `json:"isRead"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	UserID     int64     `json:"-"`
+	// ActorID is the user who acted, when creating the notification.
+	ActorID int64 `json:"-"`
+	// GroupKey is what the notifications merged together share, empty for
+	// the ones never merged.
+	GroupKey string `json:"-"`
+}
+
+// NotificationPage is a page of notifications along with the number of
+// unread ones.
+type NotificationPage struct {
+	Notifications []Notification `json:"notifications"`
+	UnreadCount   int            `json:"unreadCount"`
+}
+
+// Notification types.
+const (
+	NotificationFollow     = "follow"
+	NotificationComment    = "comment"
+	NotificationReply      = "reply"
+	NotificationMention    = "mention"
+	NotificationRepost     = "repost"
+	NotificationReaction   = "reaction"
+	NotificationModeration = "moderation"
+)
+
+// NotificationTypes lists every notification type, which users can turn off
+// one by one.
+var NotificationTypes = []string{
+	NotificationFollow,
+	NotificationComment,
+	NotificationReply,
+	NotificationMention,
+	NotificationRepost,
+	NotificationReaction,
+	NotificationModeration,
+}
+
+// Moderation actions.
+const (
+	ActionPostUpdated    = "post_updated"
+	ActionPostDeleted    = "post_deleted"
+	ActionCommentUpdated = "comment_updated"
+	ActionCommentDeleted = "comment_deleted"
+)
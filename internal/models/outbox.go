@@ -0,0 +1,29 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// OutboxMail is a mail queued in the same transaction as the change it is
+// about, to be sent once that change is committed.
+type OutboxMail struct {
+	ID       int64
+	Template string
+	Username string
+	Email    string
+	// Data is the JSON encoded data of the template.
+	Data      json.RawMessage
+	IsSandbox bool
+	Attempts  int
+	// ExpiresAt is when the mail stops being worth sending, if ever.
+	ExpiresAt *time.Time
+}
+
+// Outbox mail statuses.
+const (
+	OutboxPending = "pending"
+	OutboxSent    = "sent"
+	OutboxFailed  = "failed"
+	OutboxExpired = "expired"
+)
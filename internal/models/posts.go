@@ -3,19 +3,59 @@ package models
 import "time"
 
 type Post struct {
-	ID        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Content   string    `json:"content"`
-	Tags      []string  `json:"tags"`
-	UserID    int64     `json:"userId"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Version   int       `json:"version"`
-	Comments  []Comment `json:"comments"`
-	User      User      `json:"user"`
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Content      string    `json:"content"`
+	Tags         []string  `json:"tags"`
+	UserID       int64     `json:"userId"`
+	QuotedPostID *int64    `json:"quotedPostId,omitempty"`
+	Visibility   string    `json:"visibility"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+	Version      int       `json:"version"`
+	Comments     []Comment `json:"comments"`
+	User         User      `json:"user"`
+	QuotedPost   *Post     `json:"quotedPost,omitempty"`
+	// Attachments are the media of the post, in order. When creating a post
+	// they only need their ID.
+	Attachments []Media `json:"attachments"`
+	// Mentions are the users mentioned in the content, in order.
+	Mentions []Mention `json:"mentions"`
 }
 
+// Visibility levels of a post. Public posts are listed everywhere, unlisted
+// ones only on their author's profile and their followers' feeds, and
+// followers-only ones can only be seen by those followers. Private posts are
+// only seen by their author.
+const (
+	VisibilityPublic    = "public"
+	VisibilityUnlisted  = "unlisted"
+	VisibilityFollowers = "followers"
+	VisibilityPrivate   = "private"
+)
+
 type PostWithMetadata struct {
 	Post
-	CommentCount int `json:"comments_count"`
+	CommentCount int       `json:"comments_count"`
+	RepostCount  int       `json:"reposts_count"`
+	RepostedBy   *User     `json:"reposted_by,omitempty"`
+	ActivityAt   time.Time `json:"activity_at"`
+	// Reason tells why a post is on the viewer's home feed, one of the
+	// FeedReason constants.
+	Reason string  `json:"reason,omitempty"`
+	Score  float64 `json:"score,omitempty"`
+}
+
+const (
+	FeedReasonFollowedUser = "followed_user"
+	FeedReasonFollowedTag  = "followed_tag"
+	FeedReasonOwnPost      = "own_post"
+)
+
+// RankCandidate is a post considered for a ranked feed along with the
+// signals it is scored on.
+type RankCandidate struct {
+	PostWithMetadata
+	ReactionCount int
+	Affinity      int
 }
@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type Repost struct {
+	UserID    int64     `json:"userID"`
+	PostID    int64     `json:"postID"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// TimelineEntry is a post showing up in a home timeline, at the time it was
+// either posted or last reposted.
+type TimelineEntry struct {
+	PostID     int64     `json:"postID"`
+	ActivityAt time.Time `json:"activityAt"`
+	// Reason is set on entries merged into the timeline as it is read, the
+	// ones stored on it come from followed users.
+	Reason string `json:"-"`
+}
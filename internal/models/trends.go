@@ -0,0 +1,31 @@
+package models
+
+// TagActivity is how much a tag was used over a period of time.
+type TagActivity struct {
+	Tag     string
+	Posts   int
+	Authors int
+}
+
+// PostActivity is how many distinct users, other than its author, commented
+// on or reposted a post over a period of time.
+type PostActivity struct {
+	PostID   int64
+	Engagers int
+}
+
+type TrendingTag struct {
+	Tag     string `json:"tag"`
+	Posts   int    `json:"posts"`
+	Authors int    `json:"authors"`
+	// Velocity is how much more the tag is used than it usually is.
+	Velocity float64 `json:"velocity"`
+	Score    float64 `json:"score"`
+}
+
+type TrendingPost struct {
+	PostID   int64   `json:"postID"`
+	Engagers int     `json:"engagers"`
+	Velocity float64 `json:"velocity"`
+	Score    float64 `json:"score"`
+}
@@ -7,18 +7,38 @@ import (
 )
 
 type User struct {
-	ID        int64     `json:"id"`
-	FirstName string    `json:"firstName"`
-	LastName  string    `json:"lastName"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  Password  `json:"-"`
-	IsActive  bool      `json:"isActive"`
+	ID        int64    `json:"id"`
+	FirstName string   `json:"firstName"`
+	LastName  string   `json:"lastName"`
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	Password  Password `json:"-"`
+	IsActive  bool     `json:"isActive"`
+	// IsPrivate restricts who can reach the user to the users they follow.
+	IsPrivate bool      `json:"isPrivate"`
 	CreatedAt time.Time `json:"createdAt"`
 	RoleID    int64     `json:"roleID"`
 	Role      Role      `json:"role"`
+	// Avatar and Banner are the URLs of the resized versions of the user's
+	// images, nil when they have not uploaded any. Users embedded in other
+	// objects only carry their avatar.
+	Avatar ImageVariants `json:"avatar"`
+	Banner ImageVariants `json:"banner,omitempty"`
 }
 
+// ImageVariants maps the names of the sizes an image is resized to, such as
+// AvatarSmall, to their URLs.
+type ImageVariants map[string]string
+
+// Sizes of the user images.
+const (
+	AvatarSmall  = "small"
+	AvatarMedium = "medium"
+	AvatarLarge  = "large"
+	BannerSmall  = "small"
+	BannerLarge  = "large"
+)
+
 type Password struct {
 	Text *string
 	Hash []byte
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Webhook is an endpoint the events of a user, or of every user for app
+// webhooks, are posted to.
+type Webhook struct {
+	ID     int64  `json:"id"`
+	UserID int64  `json:"userId"`
+	IsApp  bool   `json:"isApp"`
+	URL    string `json:"url"`
+	// Events are the event types the webhook is subscribed to.
+	Events   []string `json:"events"`
+	IsActive bool     `json:"isActive"`
+	// Secret signs the payloads. It is only shown when the webhook is
+	// created.
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Webhook event types.
+const (
+	WebhookPostCreated    = "post.created"
+	WebhookPostUpdated    = "post.updated"
+	WebhookPostDeleted    = "post.deleted"
+	WebhookUserFollowed   = "user.followed"
+	WebhookUserUnfollowed = "user.unfollowed"
+)
+
+var WebhookEvents = []string{
+	WebhookPostCreated,
+	WebhookPostUpdated,
+	WebhookPostDeleted,
+	WebhookUserFollowed,
+	WebhookUserUnfollowed,
+}
+
+// WebhookDelivery is an event queued for a webhook. It is retried until the
+// webhook accepts it, or dead-lettered after too many attempts.
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     int64           `json:"webhookId"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	NextAttemptAt *time.Time      `json:"nextAttemptAt,omitempty"`
+	DeliveredAt   *time.Time      `json:"deliveredAt,omitempty"`
+	CreatedAt     time.Time       `json:"createdAt"`
+	// AttemptLog lists the attempts made, the latest first.
+	AttemptLog []WebhookAttempt `json:"attemptLog"`
+	// URL and Secret are the webhook's, when delivering.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// Webhook delivery statuses.
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookDead      = "dead"
+)
+
+type WebhookAttempt struct {
+	ID         int64 `json:"id"`
+	DeliveryID int64 `json:"-"`
+	// StatusCode is the status of the response, zero when none came back.
+	StatusCode int       `json:"statusCode"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"durationMs"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
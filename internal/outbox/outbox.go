@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/mailer"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+// lease is how long a claimed mail is left to its dispatcher before others
+// try it again. The mail clients give up on a send well before that.
+const lease = time.Minute
+
+type Config struct {
+	IsEnabled bool
+	// PollInterval is how often the outbox is checked for due mails.
+	PollInterval time.Duration
+	// BatchSize is the number of mails claimed, and sent concurrently, at
+	// once.
+	BatchSize int
+	// MaxAttempts is the number of attempts after which a mail is given up.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, it doubles with every
+	// failed attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Backoff returns the wait before retrying a mail that failed its
+// attempt-th attempt.
+func (c Config) Backoff(attempt int) time.Duration {
+	return utils.Backoff(c.BaseDelay, c.MaxDelay, attempt)
+}
+
+// Dispatcher sends the mails queued in the outbox, retrying the failed ones
+// with an exponential backoff. Mails are claimed with SKIP LOCKED so that
+// several instances can dispatch side by side.
+type Dispatcher struct {
+	store  store.Storage
+	mailer mailer.Client
+	config Config
+	logger *zap.SugaredLogger
+}
+
+func NewDispatcher(store store.Storage, mailer mailer.Client, config Config, logger *zap.SugaredLogger) *Dispatcher {
+	return &Dispatcher{
+		store:  store,
+		mailer: mailer,
+		config: config,
+		logger: logger,
+	}
+}
+
+// Run dispatches the due mails every PollInterval until ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if !d.config.IsEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Errorw("failed to dispatch mails", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the mails due now, batch by batch.
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	for {
+		mails, err := d.store.Outbox.ClaimDue(ctx, d.config.BatchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, mail := range mails {
+			wg.Go(func() {
+				d.dispatch(ctx, &mail)
+			})
+		}
+		wg.Wait()
+
+		if len(mails) < d.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, mail *models.OutboxMail) {
+	if mail.ExpiresAt != nil && time.Now().After(*mail.ExpiresAt) {
+		d.logger.Warnw("mail expired before being sent", "mailID", mail.ID, "template", mail.Template)
+		d.finish(ctx, mail, models.OutboxExpired, "")
+		return
+	}
+
+	err := d.Send(mail)
+	if err == nil {
+		d.finish(ctx, mail, models.OutboxSent, "")
+		return
+	}
+
+	attempts := mail.Attempts + 1
+	if attempts >= d.config.MaxAttempts {
+		d.logger.Errorw("mail given up", "mailID", mail.ID, "template", mail.Template, "error", err)
+		d.finish(ctx, mail, models.OutboxFailed, err.Error())
+		return
+	}
+
+	d.logger.Warnw("failed to send mail", "mailID", mail.ID, "attempt", attempts, "error", err)
+
+	nextAttemptAt := time.Now().Add(d.config.Backoff(attempts))
+	if err := d.store.Outbox.Retry(ctx, mail.ID, err.Error(), nextAttemptAt); err != nil {
+		d.logger.Errorw("failed to record mail attempt", "mailID", mail.ID, "error", err)
+	}
+}
+
+func (d *Dispatcher) finish(ctx context.Context, mail *models.OutboxMail, status, lastError string) {
+	if err := d.store.Outbox.Finish(ctx, mail.ID, status, lastError); err != nil {
+		d.logger.Errorw("failed to record mail attempt", "mailID", mail.ID, "error", err)
+	}
+}
+
+// Send sends a mail once through the mailer.
+func (d *Dispatcher) Send(mail *models.OutboxMail) error {
+	var data map[string]any
+	if err := json.Unmarshal(mail.Data, &data); err != nil {
+		return err
+	}
+
+	return d.mailer.Send(mail.Template, mail.Username, mail.Email, data, mail.IsSandbox)
+}
@@ -0,0 +1,106 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"go.uber.org/zap"
+)
+
+type testMailer struct {
+	err  error
+	sent []map[string]any
+}
+
+func (m *testMailer) Send(templateFile, username, email string, data any, isSandbox bool) error {
+	if m.err != nil {
+		return m.err
+	}
+
+	m.sent = append(m.sent, data.(map[string]any))
+	return nil
+}
+
+type testOutbox struct {
+	status  string
+	retried bool
+}
+
+func (o *testOutbox) ClaimDue(context.Context, int, time.Duration) ([]models.OutboxMail, error) {
+	return nil, nil
+}
+
+func (o *testOutbox) Finish(_ context.Context, _ int64, status, _ string) error {
+	o.status = status
+	return nil
+}
+
+func (o *testOutbox) Retry(context.Context, int64, string, time.Time) error {
+	o.retried = true
+	return nil
+}
+
+func TestDispatch(t *testing.T) {
+	past := time.Now().Add(-time.Minute)
+	cfg := Config{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: time.Minute}
+
+	tests := []struct {
+		name        string
+		mail        models.OutboxMail
+		err         error
+		wantStatus  string
+		wantRetried bool
+		wantSent    bool
+	}{
+		{
+			name:       "sent",
+			mail:       models.OutboxMail{Data: []byte(`{"Username":"gopher"}`)},
+			wantStatus: models.OutboxSent,
+			wantSent:   true,
+		},
+		{
+			name:        "retried",
+			mail:        models.OutboxMail{Data: []byte(`{}`), Attempts: 1},
+			err:         errors.New("unavailable"),
+			wantRetried: true,
+		},
+		{
+			name:       "given up",
+			mail:       models.OutboxMail{Data: []byte(`{}`), Attempts: 2},
+			err:        errors.New("unavailable"),
+			wantStatus: models.OutboxFailed,
+		},
+		{
+			name:       "expired",
+			mail:       models.OutboxMail{Data: []byte(`{}`), ExpiresAt: &past},
+			wantStatus: models.OutboxExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outbox := &testOutbox{}
+			mailer := &testMailer{err: tt.err}
+			d := NewDispatcher(store.Storage{Outbox: outbox}, mailer, cfg, zap.NewNop().Sugar())
+
+			d.dispatch(context.Background(), &tt.mail)
+
+			if outbox.status != tt.wantStatus || outbox.retried != tt.wantRetried {
+				t.Errorf("got status %q and retried %v, want %q and %v", outbox.status, outbox.retried, tt.wantStatus, tt.wantRetried)
+			}
+
+			if sent := len(mailer.sent) == 1; sent != tt.wantSent {
+				t.Fatalf("got sent %v, want %v", sent, tt.wantSent)
+			}
+
+			if tt.wantSent && mailer.sent[0]["Username"] != "gopher" {
+				t.Errorf("got data %v, want the mail's", mailer.sent[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package ranking
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+// Weights tune how much each signal contributes to the score of a post.
+type Weights struct {
+	Comment  float64
+	Repost   float64
+	Reaction float64
+	// Affinity scales the boost given to authors the viewer interacts with.
+	Affinity float64
+	// Gravity is how fast a post sinks as it gets older.
+	Gravity float64
+}
+
+var DefaultWeights = Weights{
+	Comment:  2,
+	Repost:   3,
+	Reaction: 1,
+	Affinity: 0.5,
+	Gravity:  1.5,
+}
+
+// Window is how far back in time the candidates of a ranked feed go.
+const Window = 7 * 24 * time.Hour
+
+// MaxCandidates is the number of most recent posts a ranked feed is built from.
+const MaxCandidates = 500
+
+// Signals are what a post is scored on, all counted as of the same time.
+type Signals struct {
+	Comments  int
+	Reposts   int
+	Reactions int
+	// Affinity is how many times the viewer recently interacted with the
+	// author of the post.
+	Affinity  int
+	CreatedAt time.Time
+}
+
+// Score weighs the engagement of a post and the viewer's affinity with its
+// author against the age of the post at asOf:
+//
+//	(1 + engagement) * (1 + w.Affinity * ln(1 + affinity)) / (ageHours + 2)^w.Gravity
+func Score(s Signals, asOf time.Time, w Weights) float64 {
+	engagement := w.Comment*float64(s.Comments) +
+		w.Repost*float64(s.Reposts) +
+		w.Reaction*float64(s.Reactions)
+
+	affinity := 1 + w.Affinity*math.Log1p(float64(s.Affinity))
+
+	ageHours := max(asOf.Sub(s.CreatedAt).Hours(), 0)
+
+	return (1 + engagement) * affinity / math.Pow(ageHours+2, w.Gravity)
+}
+
+// Rank scores the candidates as of asOf and sorts them best first. Posts with
+// the same score are ordered newest first, then by descending ID, so that the
+// same candidates always come out in the same order.
+func Rank(candidates []models.RankCandidate, asOf time.Time, w Weights) []models.PostWithMetadata {
+	ranked := make([]models.PostWithMetadata, len(candidates))
+
+	for i, c := range candidates {
+		ranked[i] = c.PostWithMetadata
+		ranked[i].Score = Score(Signals{
+			Comments:  c.CommentCount,
+			Reposts:   c.RepostCount,
+			Reactions: c.ReactionCount,
+			Affinity:  c.Affinity,
+			CreatedAt: c.CreatedAt,
+		}, asOf, w)
+	}
+
+	slices.SortFunc(ranked, Compare)
+
+	return ranked
+}
+
+// Compare orders ranked posts best first.
+func Compare(a, b models.PostWithMetadata) int {
+	if c := cmp.Compare(b.Score, a.Score); c != 0 {
+		return c
+	}
+	if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+		return c
+	}
+	return cmp.Compare(b.ID, a.ID)
+}
+
+// After returns the posts ranked after the given one, which doesn't have to
+// be part of the list anymore.
+func After(ranked []models.PostWithMetadata, last models.PostWithMetadata) []models.PostWithMetadata {
+	i, _ := slices.BinarySearchFunc(ranked, last, Compare)
+	if i < len(ranked) && Compare(ranked[i], last) == 0 {
+		i++
+	}
+
+	return ranked[i:]
+}
+
+// Before returns the posts ranked before the given one, which doesn't have
+// to be part of the list anymore.
+func Before(ranked []models.PostWithMetadata, first models.PostWithMetadata) []models.PostWithMetadata {
+	i, _ := slices.BinarySearchFunc(ranked, first, Compare)
+
+	return ranked[:i]
+}
@@ -0,0 +1,160 @@
+package ranking
+
+import (
+	"math"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+var asOf = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func TestScore(t *testing.T) {
+	tests := []struct {
+		name    string
+		signals Signals
+		want    float64
+	}{
+		{
+			name:    "fresh post without engagement",
+			signals: Signals{CreatedAt: asOf},
+			want:    1 / math.Pow(2, 1.5),
+		},
+		{
+			name:    "engagement is weighted",
+			signals: Signals{Comments: 2, Reposts: 1, Reactions: 3, CreatedAt: asOf.Add(-2 * time.Hour)},
+			want:    (1 + 2*2 + 3*1 + 1*3) / math.Pow(4, 1.5),
+		},
+		{
+			name:    "affinity boosts the author's posts",
+			signals: Signals{Comments: 1, Affinity: 3, CreatedAt: asOf.Add(-7 * time.Hour)},
+			want:    3 * (1 + 0.5*math.Log(4)) / 27,
+		},
+		{
+			name:    "posts from the future are as fresh as new ones",
+			signals: Signals{CreatedAt: asOf.Add(time.Hour)},
+			want:    1 / math.Pow(2, 1.5),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Score(tt.signals, asOf, DefaultWeights)
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreIsMonotonic(t *testing.T) {
+	base := Signals{Comments: 3, Reposts: 1, Affinity: 2, CreatedAt: asOf.Add(-5 * time.Hour)}
+	score := Score(base, asOf, DefaultWeights)
+
+	more := map[string]func(s *Signals){
+		"comments":  func(s *Signals) { s.Comments++ },
+		"reposts":   func(s *Signals) { s.Reposts++ },
+		"reactions": func(s *Signals) { s.Reactions++ },
+		"affinity":  func(s *Signals) { s.Affinity++ },
+		"newer":     func(s *Signals) { s.CreatedAt = s.CreatedAt.Add(time.Hour) },
+	}
+
+	for name, change := range more {
+		t.Run(name, func(t *testing.T) {
+			s := base
+			change(&s)
+
+			if got := Score(s, asOf, DefaultWeights); got <= score {
+				t.Errorf("Score() = %v, want more than %v", got, score)
+			}
+		})
+	}
+}
+
+func TestRankBreaksTies(t *testing.T) {
+	candidates := []models.RankCandidate{
+		candidate(1, 2*time.Hour, 0),
+		candidate(3, time.Hour, 0),
+		candidate(2, 2*time.Hour, 0),
+		candidate(4, 2*time.Hour, 1),
+	}
+
+	got := ids(Rank(candidates, asOf, DefaultWeights))
+	want := []int64{4, 3, 2, 1}
+
+	if !slices.Equal(got, want) {
+		t.Errorf("Rank() = %v, want %v", got, want)
+	}
+}
+
+func TestPagesAreStable(t *testing.T) {
+	var candidates []models.RankCandidate
+	for i := range 50 {
+		// plenty of equal scores to tie-break on
+		candidates = append(candidates, candidate(int64(i+1), time.Duration(i%7)*time.Hour, i%3))
+	}
+
+	ranked := Rank(candidates, asOf, DefaultWeights)
+
+	t.Run("next", func(t *testing.T) {
+		var seen []int64
+
+		page := ranked[:7]
+		for len(page) > 0 {
+			seen = append(seen, ids(page)...)
+
+			// the post the cursor points to is not needed to find the next page
+			rest := After(ranked, page[len(page)-1])
+			page = rest[:min(7, len(rest))]
+		}
+
+		if !slices.Equal(seen, ids(ranked)) {
+			t.Errorf("pages = %v, want %v", seen, ids(ranked))
+		}
+	})
+
+	t.Run("prev", func(t *testing.T) {
+		var seen []int64
+
+		page := ranked[len(ranked)-7:]
+		for len(page) > 0 {
+			seen = append(ids(page), seen...)
+
+			rest := Before(ranked, page[0])
+			page = rest[max(len(rest)-7, 0):]
+		}
+
+		if !slices.Equal(seen, ids(ranked)) {
+			t.Errorf("pages = %v, want %v", seen, ids(ranked))
+		}
+	})
+
+	t.Run("removed cursor post", func(t *testing.T) {
+		last := ranked[9]
+		without := slices.Delete(slices.Clone(ranked), 9, 10)
+
+		if got, want := ids(After(without, last)), ids(ranked[10:]); !slices.Equal(got, want) {
+			t.Errorf("After() = %v, want %v", got, want)
+		}
+	})
+}
+
+func candidate(id int64, age time.Duration, comments int) models.RankCandidate {
+	var c models.RankCandidate
+	c.ID = id
+	c.CreatedAt = asOf.Add(-age)
+	c.CommentCount = comments
+
+	return c
+}
+
+func ids(posts []models.PostWithMetadata) []int64 {
+	ids := make([]int64, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	return ids
+}
@@ -8,6 +8,9 @@ type Limiter interface {
 
 type Config struct {
 	RequestsPerTimeFrame int
-	TimeFrame            time.Duration
-	IsEnabled            bool
+	// PublicRequestsPerTimeFrame is the limit of the routes that can be used
+	// without authentication.
+	PublicRequestsPerTimeFrame int
+	TimeFrame                  time.Duration
+	IsEnabled                  bool
 }
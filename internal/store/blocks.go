@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type BlockStore struct {
+	db *sql.DB
+}
+
+func (s *BlockStore) Block(ctx context.Context, blockerID, blockedID int64) error {
+	query := `
+	INSERT INTO blocks (blocker_id, blocked_id)
+	VALUES ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, blockerID, blockedID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrDuplicateKey
+			case "23503":
+				return ErrNotFound
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *BlockStore) UnBlock(ctx context.Context, blockerID, blockedID int64) error {
+	query := `
+	DELETE FROM blocks
+	WHERE blocker_id = $1 AND blocked_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, blockerID, blockedID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetBlockedBetween returns the users of otherIDs that either blocked userID
+// or were blocked by them.
+func (s *BlockStore) GetBlockedBetween(ctx context.Context, userID int64, otherIDs []int64) ([]int64, error) {
+	query := `
+	SELECT blocked_id FROM blocks WHERE blocker_id = $1 AND blocked_id = ANY($2)
+	UNION
+	SELECT blocker_id FROM blocks WHERE blocked_id = $1 AND blocker_id = ANY($2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, pq.Array(otherIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
 	"github.com/stretchr/testify/mock"
@@ -9,7 +10,8 @@ import (
 
 func NewMockRedisStorage() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Users:     &MockUserStore{},
+		Timelines: &MockTimelineStore{},
 	}
 }
 
@@ -26,3 +28,38 @@ func (m *MockUserStore) Set(ctx context.Context, user *models.User) error {
 	args := m.Called(user)
 	return args.Error(0)
 }
+
+func (m *MockUserStore) Delete(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+type MockTimelineStore struct {
+	mock.Mock
+}
+
+func (m *MockTimelineStore) Push(ctx context.Context, userIDs []int64, entries []models.TimelineEntry, maxLen int) error {
+	args := m.Called(userIDs, entries, maxLen)
+	return args.Error(0)
+}
+
+func (m *MockTimelineStore) Build(ctx context.Context, userID int64, entries []models.TimelineEntry, maxLen int) error {
+	args := m.Called(userID, entries, maxLen)
+	return args.Error(0)
+}
+
+func (m *MockTimelineStore) Remove(ctx context.Context, userIDs []int64, postIDs []int64) error {
+	args := m.Called(userIDs, postIDs)
+	return args.Error(0)
+}
+
+func (m *MockTimelineStore) Get(ctx context.Context, userID int64, since, until *time.Time, count int) ([]models.TimelineEntry, error) {
+	args := m.Called(userID, since, until, count)
+	entries, _ := args.Get(0).([]models.TimelineEntry)
+	return entries, args.Error(1)
+}
+
+func (m *MockTimelineStore) Exists(ctx context.Context, userID int64) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
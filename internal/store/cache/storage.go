@@ -2,6 +2,7 @@ package cache
 
 import (
 	"context"
+	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
 
@@ -12,11 +13,20 @@ type Storage struct {
 	Users interface {
 		Get(context.Context, int64) (*models.User, error)
 		Set(context.Context, *models.User) error
+		Delete(context.Context, int64) error
+	}
+	Timelines interface {
+		Push(ctx context.Context, userIDs []int64, entries []models.TimelineEntry, maxLen int) error
+		Build(ctx context.Context, userID int64, entries []models.TimelineEntry, maxLen int) error
+		Remove(ctx context.Context, userIDs []int64, postIDs []int64) error
+		Get(ctx context.Context, userID int64, since, until *time.Time, count int) ([]models.TimelineEntry, error)
+		Exists(ctx context.Context, userID int64) (bool, error)
 	}
 }
 
 func NewRedisStorage(rdb *redis.Client) Storage {
 	return Storage{
-		Users: &UserStore{rdb},
+		Users:     &UserStore{rdb},
+		Timelines: &TimelineStore{rdb},
 	}
 }
@@ -0,0 +1,160 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type TimelineStore struct {
+	rdb *redis.Client
+}
+
+// timelines of users that stop reading them eventually expire and get
+// rebuilt from the database on their next read
+const TimelineExpTime = time.Hour * 24 * 7
+
+func timelineKey(userID int64) string {
+	return fmt.Sprintf("timeline-%v", userID)
+}
+
+// pushScript adds entries to a timeline only if it exists, so that a
+// timeline holding a few pushed entries is never mistaken for a built one.
+// ARGV holds maxLen, the TTL in seconds, then the score and member pairs.
+var pushScript = redis.NewScript(`
+if redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("ZADD", KEYS[1], "GT", unpack(ARGV, 3))
+redis.call("ZREMRANGEBYRANK", KEYS[1], 0, -tonumber(ARGV[1]) - 1)
+redis.call("EXPIRE", KEYS[1], ARGV[2])
+return 1
+`)
+
+// Push adds the entries to the timelines of every user in userIDs that are
+// already built, keeping only the maxLen most recent entries of each. An
+// entry that is already on a timeline is only moved if it became more
+// recent. Timelines that aren't built get the entries when they are.
+func (rds *TimelineStore) Push(ctx context.Context, userIDs []int64, entries []models.TimelineEntry, maxLen int) error {
+	if len(userIDs) == 0 || len(entries) == 0 {
+		return nil
+	}
+
+	args := make([]any, 0, 2+2*len(entries))
+	args = append(args, maxLen, int64(TimelineExpTime/time.Second))
+	for _, e := range entries {
+		args = append(args, e.ActivityAt.UnixMicro(), strconv.FormatInt(e.PostID, 10))
+	}
+
+	// loaded once so that the pipeline can refer to it by its hash
+	if err := pushScript.Load(ctx, rds.rdb).Err(); err != nil {
+		return err
+	}
+
+	_, err := rds.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pushScript.EvalSha(ctx, pipe, []string{timelineKey(userID)}, args...)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// Build creates the timeline of a user from the entries, keeping only the
+// maxLen most recent ones. Entries pushed while it was being built are kept.
+func (rds *TimelineStore) Build(ctx context.Context, userID int64, entries []models.TimelineEntry, maxLen int) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	members := make([]redis.Z, len(entries))
+	for i, e := range entries {
+		members[i] = redis.Z{
+			Score:  float64(e.ActivityAt.UnixMicro()),
+			Member: strconv.FormatInt(e.PostID, 10),
+		}
+	}
+
+	key := timelineKey(userID)
+
+	_, err := rds.rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.ZAddArgs(ctx, key, redis.ZAddArgs{GT: true, Members: members})
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-maxLen-1))
+		pipe.Expire(ctx, key, TimelineExpTime)
+		return nil
+	})
+
+	return err
+}
+
+// Remove takes the posts off the timelines of every user in userIDs.
+func (rds *TimelineStore) Remove(ctx context.Context, userIDs []int64, postIDs []int64) error {
+	if len(userIDs) == 0 || len(postIDs) == 0 {
+		return nil
+	}
+
+	members := make([]any, len(postIDs))
+	for i, id := range postIDs {
+		members[i] = strconv.FormatInt(id, 10)
+	}
+
+	_, err := rds.rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, userID := range userIDs {
+			pipe.ZRem(ctx, timelineKey(userID), members...)
+		}
+		return nil
+	})
+
+	return err
+}
+
+// Get returns up to count of the most recent entries of a timeline between
+// since and until, both inclusive and both optional.
+func (rds *TimelineStore) Get(ctx context.Context, userID int64, since, until *time.Time, count int) ([]models.TimelineEntry, error) {
+	opt := &redis.ZRangeBy{Min: "-inf", Max: "+inf", Count: int64(count)}
+	if since != nil {
+		opt.Min = strconv.FormatInt(since.UnixMicro(), 10)
+	}
+	if until != nil {
+		opt.Max = strconv.FormatInt(until.UnixMicro(), 10)
+	}
+
+	key := timelineKey(userID)
+
+	res, err := rds.rdb.ZRevRangeByScoreWithScores(ctx, key, opt).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	rds.rdb.Expire(ctx, key, TimelineExpTime)
+
+	entries := make([]models.TimelineEntry, 0, len(res))
+	for _, z := range res {
+		postID, err := strconv.ParseInt(z.Member.(string), 10, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, models.TimelineEntry{
+			PostID:     postID,
+			ActivityAt: time.UnixMicro(int64(z.Score)).UTC(),
+		})
+	}
+
+	return entries, nil
+}
+
+func (rds *TimelineStore) Exists(ctx context.Context, userID int64) (bool, error) {
+	n, err := rds.rdb.Exists(ctx, timelineKey(userID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
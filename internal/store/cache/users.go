@@ -48,3 +48,10 @@ func (rds *UserStore) Set(ctx context.Context, user *models.User) error {
 
 	return rds.rdb.SetEx(ctx, cacheKey, json, UserExpTime).Err()
 }
+
+// Delete drops a user from the cache, for the next read to load it again.
+func (rds *UserStore) Delete(ctx context.Context, userID int64) error {
+	cacheKey := fmt.Sprintf("user-%v", userID)
+
+	return rds.rdb.Del(ctx, cacheKey).Err()
+}
@@ -3,29 +3,232 @@ package store
 import (
 	"context"
 	"database/sql"
+	"errors"
+	"slices"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
 )
 
 type CommentStore struct {
 	db *sql.DB
 }
 
-func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]models.Comment, error) {
+const commentColumns = `
+	c.id, c.post_id, c.user_id, c.parent_id, c.depth, c.content, c.created_at,
+	c.edited_at, c.version, c.deleted_at IS NOT NULL AS is_deleted,
+	(SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id) AS reply_count,
+	u.id, u.first_name, u.last_name, u.username, u.email, u.created_at, u.avatar,
+	` + commentMentionsColumn
+
+// GetByPostID returns a page of the top-level comments of a post, each with
+// the number of direct replies it has.
+func (s *CommentStore) GetByPostID(ctx context.Context, postID int64, cq utils.PaginatedCommentQuery) ([]models.Comment, error) {
+	where, orderBy, keysetArgs, reversed := keyset(cq.Keyset, cq.Sort, "c.created_at", "c.id", 4)
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.post_id = $1 AND c.parent_id IS NULL AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $2
+		OFFSET $3
+	`
+
+	return s.page(ctx, query, reversed, append([]any{postID, cq.Limit, cq.Offset}, keysetArgs...)...)
+}
+
+// GetReplies returns a page of the direct replies to a comment.
+func (s *CommentStore) GetReplies(ctx context.Context, commentID int64, cq utils.PaginatedCommentQuery) ([]models.Comment, error) {
+	where, orderBy, keysetArgs, reversed := keyset(cq.Keyset, cq.Sort, "c.created_at", "c.id", 4)
+
+	query := `
+		SELECT ` + commentColumns + `
+		FROM comments c
+		JOIN users u ON u.id = c.user_id
+		WHERE c.parent_id = $1 AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $2
+		OFFSET $3
+	`
+
+	return s.page(ctx, query, reversed, append([]any{commentID, cq.Limit, cq.Offset}, keysetArgs...)...)
+}
+
+func (s *CommentStore) GetByID(ctx context.Context, commentID int64) (*models.Comment, error) {
 	query := `
-		SELECT 
-			c.id, c.post_id, c.user_id, c.content, c.created_at,
-			u.id, u.first_name, u.last_name, u.username, u.email, u.created_at
+		SELECT ` + commentColumns + `
 		FROM comments c
 		JOIN users u ON u.id = c.user_id
-		WHERE c.post_id = $1
-		ORDER BY c.created_at DESC
+		WHERE c.id = $1
+	`
+
+	comments, err := s.list(ctx, query, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(comments) == 0 {
+		return nil, ErrNotFound
+	}
+
+	return &comments[0], nil
+}
+
+func (s *CommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	query := `
+		INSERT INTO comments (post_id, user_id, content, parent_id, depth)
+		VALUES ($1, $2, $3, $4, COALESCE((SELECT depth + 1 FROM comments WHERE id = $4), 0))
+		RETURNING id, depth, created_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, postID)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			comment.PostID,
+			comment.UserID,
+			comment.Content,
+			comment.ParentID,
+		).Scan(&comment.ID, &comment.Depth, &comment.CreatedAt)
+
+		if err != nil {
+			return err
+		}
+
+		if len(comment.Mentions) == 0 {
+			return nil
+		}
+
+		return saveMentions(ctx, tx, comment.UserID, comment.PostID, &comment.ID, comment.Mentions)
+	})
+}
+
+// Performs Optimistic Locking/Concurrency
+func (s *CommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	query := `
+		UPDATE comments
+		SET
+			content = $1,
+			version = version + 1,
+			edited_at = NOW()
+		WHERE id = $2 AND version = $3 AND deleted_at IS NULL
+		RETURNING version, edited_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx, query, comment.Content, comment.ID, comment.Version,
+		).Scan(&comment.Version, &comment.EditedAt)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrUpdateConflict
+			default:
+				return err
+			}
+		}
+
+		return saveMentions(ctx, tx, comment.UserID, comment.PostID, &comment.ID, comment.Mentions)
+	})
+}
+
+// Delete removes a comment. A comment that still has replies is only blanked
+// out and kept as a placeholder so that its thread stays intact; once a
+// placeholder loses its last reply it is removed as well.
+func (s *CommentStore) Delete(ctx context.Context, commentID int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		var parentID sql.NullInt64
+		var replyCount int
+
+		err := tx.QueryRowContext(ctx, `
+			SELECT c.parent_id, (SELECT COUNT(*) FROM comments r WHERE r.parent_id = c.id)
+			FROM comments c
+			WHERE c.id = $1
+			FOR UPDATE
+		`, commentID).Scan(&parentID, &replyCount)
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrNotFound
+			default:
+				return err
+			}
+		}
+
+		if replyCount > 0 {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE comments
+				SET content = '', deleted_at = NOW()
+				WHERE id = $1 AND deleted_at IS NULL
+			`, commentID)
+			if err != nil {
+				return err
+			}
+
+			// the placeholder doesn't mention anyone anymore
+			_, err = tx.ExecContext(ctx, `DELETE FROM mentions WHERE comment_id = $1`, commentID)
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM comments WHERE id = $1`, commentID); err != nil {
+			return err
+		}
+
+		// prune the placeholders left without any replies
+		for parentID.Valid {
+			var next sql.NullInt64
+
+			err := tx.QueryRowContext(ctx, `
+				DELETE FROM comments c
+				WHERE c.id = $1
+					AND c.deleted_at IS NOT NULL
+					AND NOT EXISTS (SELECT 1 FROM comments r WHERE r.parent_id = c.id)
+				RETURNING c.parent_id
+			`, parentID.Int64).Scan(&next)
+			if err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return nil
+				}
+				return err
+			}
+
+			parentID = next
+		}
+
+		return nil
+	})
+}
+
+func (s *CommentStore) page(ctx context.Context, query string, reversed bool, args ...any) ([]models.Comment, error) {
+	comments, err := s.list(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if reversed {
+		slices.Reverse(comments)
+	}
+
+	return comments, nil
+}
+
+func (s *CommentStore) list(ctx context.Context, query string, args ...any) ([]models.Comment, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -37,42 +240,25 @@ func (s *CommentStore) GetByPostID(ctx context.Context, postID int64) ([]models.
 		var c models.Comment
 		c.User = models.User{}
 		err := rows.Scan(
-			&c.ID, &c.PostID, &c.UserID, &c.Content, &c.CreatedAt,
+			&c.ID, &c.PostID, &c.UserID, &c.ParentID, &c.Depth, &c.Content, &c.CreatedAt,
+			&c.EditedAt, &c.Version, &c.IsDeleted, &c.ReplyCount,
 			&c.User.ID, &c.User.FirstName, &c.User.LastName,
-			&c.User.Username, &c.User.Email, &c.User.CreatedAt,
+			&c.User.Username, &c.User.Email, &c.User.CreatedAt, (*imageVariants)(&c.User.Avatar),
+			(*mentionList)(&c.Mentions),
 		)
 
 		if err != nil {
 			return nil, err
 		}
 
-		comments = append(comments, c)
-	}
-
-	return comments, nil
-}
-
-func (s *CommentStore) Create(ctx context.Context, comment *models.Comment) error {
-	query := `
-		INSERT INTO comments (post_id, user_id, content)
-		VALUES ($1, $2, $3)
-		RETURNING id, created_at
-	`
-
-	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
-	defer cancel()
-
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		comment.PostID,
-		comment.UserID,
-		comment.Content,
-	).Scan(&comment.ID, &comment.CreatedAt)
+		// placeholders of deleted comments don't reveal who wrote them
+		if c.IsDeleted {
+			c.UserID = 0
+			c.User = models.User{}
+		}
 
-	if err != nil {
-		return err
+		comments = append(comments, c)
 	}
 
-	return nil
+	return comments, rows.Err()
 }
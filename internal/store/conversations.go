@@ -0,0 +1,255 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type ConversationStore struct {
+	db *sql.DB
+}
+
+func (s *ConversationStore) Create(ctx context.Context, conversation *models.Conversation, memberIDs []int64) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO conversations (is_group, created_by)
+			VALUES ($1, $2)
+			RETURNING id, created_at, updated_at
+		`
+
+		err := tx.QueryRowContext(ctx, query, conversation.IsGroup, conversation.CreatedBy).Scan(
+			&conversation.ID, &conversation.CreatedAt, &conversation.UpdatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		query = `
+			INSERT INTO conversation_members (conversation_id, user_id)
+			SELECT $1::INTEGER, UNNEST($2::INTEGER[])
+		`
+
+		if _, err := tx.ExecContext(ctx, query, conversation.ID, pq.Array(memberIDs)); err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+				return ErrNotFound
+			}
+			return err
+		}
+
+		return nil
+	})
+}
+
+func (s *ConversationStore) GetByID(ctx context.Context, conversationID int64) (*models.Conversation, error) {
+	query := `
+		SELECT id, is_group, COALESCE(created_by, 0), created_at, updated_at
+		FROM conversations
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var c models.Conversation
+	err := s.db.QueryRowContext(ctx, query, conversationID).Scan(
+		&c.ID, &c.IsGroup, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	members, err := s.getMembers(ctx, []int64{c.ID})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Members = members[c.ID]
+
+	return &c, nil
+}
+
+// GetDirect returns the one-to-one conversation between two users.
+func (s *ConversationStore) GetDirect(ctx context.Context, userID, otherID int64) (*models.Conversation, error) {
+	query := `
+		SELECT c.id
+		FROM conversations c
+		JOIN conversation_members a ON a.conversation_id = c.id AND a.user_id = $1
+		JOIN conversation_members b ON b.conversation_id = c.id AND b.user_id = $2
+		WHERE NOT c.is_group
+		ORDER BY c.id
+		LIMIT 1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var id int64
+	if err := s.db.QueryRowContext(ctx, query, userID, otherID).Scan(&id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return s.GetByID(ctx, id)
+}
+
+// GetByUserID returns the conversations of a user, the most recently active
+// first, with their last message and the number of messages the user hasn't
+// read yet.
+func (s *ConversationStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]models.Conversation, error) {
+	query := `
+		SELECT
+			c.id, c.is_group, COALESCE(c.created_by, 0), c.created_at, c.updated_at,
+			lm.id, lm.sender_id, lm.content, lm.created_at,
+			(
+				SELECT COUNT(*)
+				FROM messages m
+				WHERE m.conversation_id = c.id
+					AND m.id > COALESCE(cm.last_read_message_id, 0)
+					AND m.sender_id IS DISTINCT FROM cm.user_id
+			) AS unread_count
+		FROM conversation_members cm
+		JOIN conversations c ON c.id = cm.conversation_id
+		LEFT JOIN LATERAL (
+			SELECT m.id, COALESCE(m.sender_id, 0) AS sender_id, m.content, m.created_at
+			FROM messages m
+			WHERE m.conversation_id = c.id
+			ORDER BY m.created_at DESC, m.id DESC
+			LIMIT 1
+		) lm ON TRUE
+		WHERE cm.user_id = $1
+		ORDER BY c.updated_at DESC, c.id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conversations := []models.Conversation{}
+	var ids []int64
+	for rows.Next() {
+		var c models.Conversation
+		var lastID, lastSenderID sql.NullInt64
+		var lastContent sql.NullString
+		var lastCreatedAt sql.NullTime
+
+		err := rows.Scan(
+			&c.ID, &c.IsGroup, &c.CreatedBy, &c.CreatedAt, &c.UpdatedAt,
+			&lastID, &lastSenderID, &lastContent, &lastCreatedAt,
+			&c.UnreadCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if lastID.Valid {
+			c.LastMessage = &models.Message{
+				ID:             lastID.Int64,
+				ConversationID: c.ID,
+				SenderID:       lastSenderID.Int64,
+				Content:        lastContent.String,
+				CreatedAt:      lastCreatedAt.Time,
+			}
+		}
+
+		conversations = append(conversations, c)
+		ids = append(ids, c.ID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	members, err := s.getMembers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range conversations {
+		conversations[i].Members = members[conversations[i].ID]
+	}
+
+	return conversations, nil
+}
+
+// MarkRead moves the read marker of a member forward to a message of the
+// conversation. Marking an older message as read doesn't move it back.
+func (s *ConversationStore) MarkRead(ctx context.Context, conversationID, userID, messageID int64) error {
+	query := `
+		UPDATE conversation_members
+		SET last_read_message_id = GREATEST(COALESCE(last_read_message_id, 0), $3)
+		WHERE conversation_id = $1 AND user_id = $2
+			AND EXISTS (SELECT 1 FROM messages WHERE id = $3 AND conversation_id = $1)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, conversationID, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *ConversationStore) getMembers(ctx context.Context, conversationIDs []int64) (map[int64][]models.ConversationMember, error) {
+	query := `
+		SELECT cm.conversation_id, u.id, u.username, u.avatar, cm.last_read_message_id
+		FROM conversation_members cm
+		JOIN users u ON u.id = cm.user_id
+		WHERE cm.conversation_id = ANY($1)
+		ORDER BY cm.joined_at, u.id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(conversationIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := make(map[int64][]models.ConversationMember, len(conversationIDs))
+	for rows.Next() {
+		var conversationID int64
+		var m models.ConversationMember
+		if err := rows.Scan(&conversationID, &m.ID, &m.Username, (*imageVariants)(&m.Avatar), &m.LastReadMessageID); err != nil {
+			return nil, err
+		}
+
+		members[conversationID] = append(members[conversationID], m)
+	}
+
+	return members, rows.Err()
+}
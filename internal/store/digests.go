@@ -0,0 +1,288 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type DigestStore struct {
+	db *sql.DB
+}
+
+// digestSize is the most followers, posts and replies listed in a digest.
+const digestSize = 5
+
+func (s *DigestStore) GetSettings(ctx context.Context, userID int64) (*models.DigestSettings, error) {
+	query := `SELECT frequency FROM digest_settings WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	settings := models.DigestSettings{Frequency: models.DigestOff}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&settings.Frequency)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (s *DigestStore) SetFrequency(ctx context.Context, userID int64, frequency string) error {
+	query := `
+		INSERT INTO digest_settings (user_id, frequency)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE
+		SET frequency = EXCLUDED.frequency, updated_at = NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	if _, err := s.db.ExecContext(ctx, query, userID, frequency); err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// GetDue returns the active users, after afterID in id order, who get
+// digests at the given frequency and haven't had the one of the period
+// starting at periodStart yet. A digest claimed for longer than lease, by an
+// instance that stopped before sending it, is due again.
+func (s *DigestStore) GetDue(ctx context.Context, frequency string, periodStart time.Time, lease time.Duration, afterID int64, limit int) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.username, u.email
+		FROM digest_settings ds
+		JOIN users u ON u.id = ds.user_id
+		WHERE ds.frequency = $1 AND ds.user_id > $3 AND u.is_active
+			AND NOT EXISTS (
+				SELECT 1 FROM digest_deliveries dd
+				WHERE dd.user_id = ds.user_id AND dd.frequency = $1 AND dd.period_start = $2
+					AND NOT (dd.status = '` + models.DigestSending + `' AND dd.claimed_at < NOW() - make_interval(secs => $5))
+			)
+		ORDER BY ds.user_id
+		LIMIT $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, frequency, periodStart, afterID, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email); err != nil {
+			return nil, err
+		}
+
+		users = append(users, u)
+	}
+
+	return users, rows.Err()
+}
+
+// Claim records that the digest of a user for a period is being sent, for
+// lease. It returns false when it was sent already, or is claimed by another
+// instance, in which case it must not be sent. A claim older than lease was
+// left by an instance that stopped, and is taken over.
+func (s *DigestStore) Claim(ctx context.Context, userID int64, frequency string, periodStart time.Time, lease time.Duration) (bool, error) {
+	query := `
+		INSERT INTO digest_deliveries (user_id, frequency, period_start)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, frequency, period_start) DO UPDATE
+		SET claimed_at = NOW(), updated_at = NOW()
+		WHERE digest_deliveries.status = '` + models.DigestSending + `'
+			AND digest_deliveries.claimed_at < NOW() - make_interval(secs => $4)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, userID, frequency, periodStart, lease.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+// Finish sets the final status of a claimed digest.
+func (s *DigestStore) Finish(ctx context.Context, userID int64, frequency string, periodStart time.Time, status string) error {
+	query := `
+		UPDATE digest_deliveries
+		SET status = $4, updated_at = NOW()
+		WHERE user_id = $1 AND frequency = $2 AND period_start = $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, frequency, periodStart, status)
+	return err
+}
+
+// Release drops the claim on a digest that couldn't be sent, for it to be
+// tried again.
+func (s *DigestStore) Release(ctx context.Context, userID int64, frequency string, periodStart time.Time) error {
+	query := `
+		DELETE FROM digest_deliveries
+		WHERE user_id = $1 AND frequency = $2 AND period_start = $3 AND status = '` + models.DigestSending + `'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, frequency, periodStart)
+	return err
+}
+
+// Get compiles the digest of a user for [since, until). The users they
+// blocked, or who blocked them, are left out of it.
+func (s *DigestStore) Get(ctx context.Context, userID int64, since, until time.Time) (*models.Digest, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var d models.Digest
+
+	if err := s.getFollowers(ctx, &d, userID, since, until); err != nil {
+		return nil, err
+	}
+
+	if err := s.getTopPosts(ctx, &d, userID, since, until); err != nil {
+		return nil, err
+	}
+
+	if err := s.getReplies(ctx, &d, userID, since, until); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (s *DigestStore) getFollowers(ctx context.Context, d *models.Digest, userID int64, since, until time.Time) error {
+	query := `
+		SELECT u.id, u.username, COUNT(*) OVER ()
+		FROM followers f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.user_id = $1 AND f.created_at >= $2 AND f.created_at < $3
+			AND ` + notBlockedBetween("f.follower_id") + `
+		ORDER BY f.created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, since, until, digestSize)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u models.User
+		if err := rows.Scan(&u.ID, &u.Username, &d.FollowerCount); err != nil {
+			return err
+		}
+
+		d.Followers = append(d.Followers, u)
+	}
+
+	return rows.Err()
+}
+
+func (s *DigestStore) getTopPosts(ctx context.Context, d *models.Digest, userID int64, since, until time.Time) error {
+	query := `
+		SELECT p.id, p.title, u.username, e.comments, e.reposts
+		FROM posts p
+		JOIN followers f ON f.user_id = p.user_id AND f.follower_id = $1
+		JOIN users u ON u.id = p.user_id
+		CROSS JOIN LATERAL (
+			SELECT
+				(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.deleted_at IS NULL) AS comments,
+				(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts
+		) e
+		WHERE p.created_at >= $2 AND p.created_at < $3
+			AND ` + visibleTo(1) + `
+			AND ` + notBlockedBetween("p.user_id") + `
+		ORDER BY e.comments + e.reposts DESC, p.created_at DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, since, until, digestSize)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p models.DigestPost
+		if err := rows.Scan(&p.ID, &p.Title, &p.Author, &p.CommentCount, &p.RepostCount); err != nil {
+			return err
+		}
+
+		d.TopPosts = append(d.TopPosts, p)
+	}
+
+	return rows.Err()
+}
+
+func (s *DigestStore) getReplies(ctx context.Context, d *models.Digest, userID int64, since, until time.Time) error {
+	query := `
+		SELECT c.id, c.post_id, p.title, c.content, u.username, c.created_at, COUNT(*) OVER ()
+		FROM comments c
+		JOIN posts p ON p.id = c.post_id
+		JOIN users u ON u.id = c.user_id
+		LEFT JOIN comments parent ON parent.id = c.parent_id
+		WHERE c.user_id <> $1 AND c.deleted_at IS NULL
+			AND c.created_at >= $2 AND c.created_at < $3
+			AND ((c.parent_id IS NULL AND p.user_id = $1) OR parent.user_id = $1)
+			AND ` + notBlockedBetween("c.user_id") + `
+		ORDER BY c.created_at DESC, c.id DESC
+		LIMIT $4
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, userID, since, until, digestSize)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r models.DigestReply
+		err := rows.Scan(&r.CommentID, &r.PostID, &r.PostTitle, &r.Content, &r.Author, &r.CreatedAt, &d.ReplyCount)
+		if err != nil {
+			return err
+		}
+
+		d.Replies = append(d.Replies, r)
+	}
+
+	return rows.Err()
+}
+
+// notBlockedBetween builds the predicate leaving out the users in otherCol
+// who blocked the user bound to $1, or were blocked by them.
+func notBlockedBetween(otherCol string) string {
+	return `NOT EXISTS (
+		SELECT 1 FROM blocks b
+		WHERE (b.blocker_id = $1 AND b.blocked_id = ` + otherCol + `)
+			OR (b.blocker_id = ` + otherCol + ` AND b.blocked_id = $1)
+	)`
+}
@@ -11,6 +11,8 @@ type FollowerStore struct {
 	db *sql.DB
 }
 
+// Follow makes followerID follow userID and counts them among the followers
+// of userID.
 func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
 	query := `
 	INSERT INTO followers (user_id, follower_id)
@@ -20,15 +22,17 @@ func (s *FollowerStore) Follow(ctx context.Context, followerID, userID int64) er
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, followerID)
-	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
-			return ErrDuplicateKey
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, query, userID, followerID)
+		if err != nil {
+			if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+				return ErrDuplicateKey
+			}
+			return err
 		}
-		return err
-	}
 
-	return nil
+		return addFollowers(ctx, tx, userID, 1)
+	})
 }
 
 func (s *FollowerStore) UnFollow(ctx context.Context, unfollowedID, userID int64) error {
@@ -40,10 +44,91 @@ func (s *FollowerStore) UnFollow(ctx context.Context, unfollowedID, userID int64
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	_, err := s.db.ExecContext(ctx, query, userID, unfollowedID)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, query, userID, unfollowedID)
+		if err != nil {
+			return err
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil || rows == 0 {
+			return err
+		}
+
+		return addFollowers(ctx, tx, userID, -1)
+	})
+}
+
+// addFollowers adds n, which may be negative, to the follower count of a
+// user.
+func addFollowers(ctx context.Context, tx *sql.Tx, userID int64, n int) error {
+	query := `UPDATE users SET follower_count = follower_count + $1 WHERE id = $2`
+
+	_, err := tx.ExecContext(ctx, query, n, userID)
+	return err
+}
+
+// GetFollowerIDs returns the IDs of the users following userID.
+func (s *FollowerStore) GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error) {
+	query := `SELECT follower_id FROM followers WHERE user_id = $1`
+
+	return s.queryIDs(ctx, query, userID)
+}
+
+// GetFolloweeIDs returns the IDs of the users followerID follows.
+func (s *FollowerStore) GetFolloweeIDs(ctx context.Context, followerID int64) ([]int64, error) {
+	query := `SELECT user_id FROM followers WHERE follower_id = $1`
+
+	return s.queryIDs(ctx, query, followerID)
+}
+
+// GetHeavyFolloweeIDs returns the IDs of the users followerID follows that
+// have more than minFollowers followers themselves.
+func (s *FollowerStore) GetHeavyFolloweeIDs(ctx context.Context, followerID int64, minFollowers int) ([]int64, error) {
+	query := `
+	SELECT f.user_id
+	FROM followers f
+	JOIN users u ON u.id = f.user_id
+	WHERE f.follower_id = $1 AND u.follower_count > $2
+	`
+
+	return s.queryIDs(ctx, query, followerID, minFollowers)
+}
+
+// IsFollowing reports whether followerID follows userID.
+func (s *FollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM followers WHERE follower_id = $1 AND user_id = $2)`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var following bool
+	if err := s.db.QueryRowContext(ctx, query, followerID, userID).Scan(&following); err != nil {
+		return false, err
+	}
+
+	return following, nil
+}
+
+func (s *FollowerStore) queryIDs(ctx context.Context, query string, args ...any) ([]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
 	}
 
-	return nil
+	return ids, rows.Err()
 }
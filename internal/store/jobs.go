@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type JobStore struct {
+	db *sql.DB
+}
+
+// Enqueue queues a job, returning ErrDuplicateKey when a job of the same
+// kind has its unique key.
+func (s *JobStore) Enqueue(ctx context.Context, j *models.Job) error {
+	query := `
+		INSERT INTO jobs (kind, args, unique_key, max_attempts, run_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (kind, unique_key) DO NOTHING
+		RETURNING id, status, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx, query, j.Kind, string(j.Args), j.UniqueKey, j.MaxAttempts, j.RunAt,
+	).Scan(&j.ID, &j.Status, &j.CreatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrDuplicateKey
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ClaimDue takes up to limit pending jobs of the given kinds that are due,
+// leaving the ones other workers are claiming, and pushes them back by lease.
+// A job whose outcome is never recorded, because its worker stopped, is due
+// again once the lease is over.
+func (s *JobStore) ClaimDue(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]models.Job, error) {
+	query := `
+		UPDATE jobs
+		SET run_at = NOW() + make_interval(secs => $3), attempts = attempts + 1
+		WHERE id IN (
+			SELECT id
+			FROM jobs
+			WHERE status = '` + models.JobPending + `' AND run_at <= NOW() AND kind = ANY($1)
+			ORDER BY run_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, kind, args, unique_key, status, attempts, max_attempts, last_error, run_at, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(kinds), limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []models.Job
+	for rows.Next() {
+		var j models.Job
+		err := rows.Scan(
+			&j.ID, &j.Kind, (*[]byte)(&j.Args), &j.UniqueKey, &j.Status, &j.Attempts,
+			&j.MaxAttempts, &j.LastError, &j.RunAt, &j.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		jobs = append(jobs, j)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Finish moves a job out of pending, to done or failed.
+func (s *JobStore) Finish(ctx context.Context, jobID int64, status, lastError string) error {
+	query := `
+		UPDATE jobs
+		SET status = $2, last_error = $3, finished_at = NOW()
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, jobID, status, lastError)
+	return err
+}
+
+// Retry records a failed run of a job, to be run again at runAt.
+func (s *JobStore) Retry(ctx context.Context, jobID int64, lastError string, runAt time.Time) error {
+	query := `UPDATE jobs SET last_error = $2, run_at = $3 WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, jobID, lastError, runAt)
+	return err
+}
+
+// Prune deletes the jobs finished before the given time, returning how many
+// were.
+func (s *JobStore) Prune(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM jobs WHERE status <> '` + models.JobPending + `' AND finished_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+// CountPending counts the pending jobs of each kind.
+func (s *JobStore) CountPending(ctx context.Context) (map[string]int, error) {
+	query := `
+		SELECT kind, COUNT(*)
+		FROM jobs
+		WHERE status = '` + models.JobPending + `'
+		GROUP BY kind
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var kind string
+		var count int
+		if err := rows.Scan(&kind, &count); err != nil {
+			return nil, err
+		}
+
+		counts[kind] = count
+	}
+
+	return counts, rows.Err()
+}
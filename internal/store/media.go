@@ -0,0 +1,121 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type MediaStore struct {
+	db *sql.DB
+}
+
+func (s *MediaStore) Create(ctx context.Context, m *models.Media) error {
+	query := `
+		INSERT INTO media (user_id, key, thumbnail_key, url, thumbnail_url, content_type, width, height, size)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx, query, m.UserID, m.Key, m.ThumbnailKey, m.URL, m.ThumbnailURL,
+		m.ContentType, m.Width, m.Height, m.Size,
+	).Scan(&m.ID, &m.CreatedAt)
+}
+
+// attachMedia attaches the media listed in post.Attachments to the post, in
+// that order, and fills them in. They must have been uploaded by the post's
+// author and not be attached to another post, ErrNotFound otherwise.
+func attachMedia(ctx context.Context, tx *sql.Tx, post *models.Post) error {
+	ids := make([]int64, len(post.Attachments))
+	for i, m := range post.Attachments {
+		ids[i] = m.ID
+	}
+
+	query := `
+		UPDATE media
+		SET post_id = $1, position = array_position($3::BIGINT[], id)
+		WHERE id = ANY($3) AND user_id = $2 AND post_id IS NULL
+		RETURNING id, content_type, width, height, size, url, thumbnail_url, created_at
+	`
+
+	rows, err := tx.QueryContext(ctx, query, post.ID, post.UserID, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var attached []models.Media
+	for rows.Next() {
+		var m models.Media
+		if err := rows.Scan(&m.ID, &m.ContentType, &m.Width, &m.Height, &m.Size, &m.URL, &m.ThumbnailURL, &m.CreatedAt); err != nil {
+			return err
+		}
+
+		attached = append(attached, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if len(attached) != len(ids) {
+		return ErrNotFound
+	}
+
+	slices.SortFunc(attached, func(a, b models.Media) int {
+		return slices.Index(ids, a.ID) - slices.Index(ids, b.ID)
+	})
+	post.Attachments = attached
+
+	return nil
+}
+
+// attachmentsColumn selects the media attached to the post aliased p as a
+// JSON array, scanned with attachmentList.
+const attachmentsColumn = `(
+			SELECT COALESCE(json_agg(json_build_object(
+				'id', m.id, 'contentType', m.content_type, 'width', m.width, 'height', m.height,
+				'size', m.size, 'url', m.url, 'thumbnailUrl', m.thumbnail_url, 'createdAt', m.created_at
+			) ORDER BY m.position), '[]')
+			FROM media m
+			WHERE m.post_id = p.id
+		)`
+
+type attachmentList []models.Media
+
+func (a *attachmentList) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into attachments", src)
+	}
+
+	return json.Unmarshal(data, a)
+}
+
+// imageVariants scans a nullable JSON object of image URLs, such as a user's
+// avatar.
+type imageVariants models.ImageVariants
+
+func (v *imageVariants) Scan(src any) error {
+	if src == nil {
+		*v = nil
+		return nil
+	}
+
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into image variants", src)
+	}
+
+	return json.Unmarshal(data, v)
+}
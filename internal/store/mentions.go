@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type MentionStore struct {
+	db *sql.DB
+}
+
+// GetByUserID returns the latest mentions of a user in the posts and
+// comments they can see, leaving out the ones of the users they blocked or
+// who blocked them.
+func (s *MentionStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]models.MentionOfUser, error) {
+	query := `
+		SELECT
+			m.id, m.post_id, m.comment_id, COALESCE(c.content, p.content),
+			m.start_offset, m.end_offset, m.created_at,
+			u.id, u.username, u.avatar
+		FROM mentions m
+		JOIN posts p ON p.id = m.post_id
+		LEFT JOIN comments c ON c.id = m.comment_id
+		JOIN users u ON u.id = m.author_id
+		WHERE m.user_id = $1 AND ` + visibleTo(1) + `
+			AND NOT EXISTS (
+				SELECT 1 FROM blocks b
+				WHERE (b.blocker_id = $1 AND b.blocked_id = m.author_id)
+					OR (b.blocker_id = m.author_id AND b.blocked_id = $1)
+			)
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	mentions := []models.MentionOfUser{}
+	for rows.Next() {
+		var m models.MentionOfUser
+		err := rows.Scan(
+			&m.ID, &m.PostID, &m.CommentID, &m.Content,
+			&m.Start, &m.End, &m.CreatedAt,
+			&m.Author.ID, &m.Author.Username, (*imageVariants)(&m.Author.Avatar),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		mentions = append(mentions, m)
+	}
+
+	return mentions, rows.Err()
+}
+
+// saveMentions replaces the mentions made in a post, or in one of its
+// comments when commentID is set. The users still mentioned keep the time
+// they were first mentioned at.
+func saveMentions(ctx context.Context, tx *sql.Tx, authorID, postID int64, commentID *int64, mentions []models.Mention) error {
+	userIDs := make([]int64, len(mentions))
+	starts := make([]int64, len(mentions))
+	ends := make([]int64, len(mentions))
+	for i, m := range mentions {
+		userIDs[i], starts[i], ends[i] = m.UserID, int64(m.Start), int64(m.End)
+	}
+
+	query := `
+		WITH old AS (
+			DELETE FROM mentions
+			WHERE post_id = $2 AND comment_id IS NOT DISTINCT FROM $3::INTEGER
+			RETURNING user_id, created_at
+		)
+		INSERT INTO mentions (user_id, author_id, post_id, comment_id, start_offset, end_offset, created_at)
+		SELECT
+			n.user_id, $1::INTEGER, $2::INTEGER, $3::INTEGER, n.start_offset, n.end_offset,
+			COALESCE((SELECT MIN(old.created_at) FROM old WHERE old.user_id = n.user_id), NOW())
+		FROM unnest($4::BIGINT[], $5::BIGINT[], $6::BIGINT[]) AS n(user_id, start_offset, end_offset)
+	`
+
+	_, err := tx.ExecContext(
+		ctx, query, authorID, postID, commentID,
+		pq.Array(userIDs), pq.Array(starts), pq.Array(ends),
+	)
+
+	return err
+}
+
+// mentionsOf selects the mentions matching where as a JSON array, scanned
+// with mentionList.
+const mentionsOf = `(
+			SELECT COALESCE(json_agg(json_build_object(
+				'userId', m.user_id, 'username', mu.username, 'start', m.start_offset, 'end', m.end_offset
+			) ORDER BY m.start_offset), '[]')
+			FROM mentions m
+			JOIN users mu ON mu.id = m.user_id
+			WHERE `
+
+// postMentionsColumn selects the mentions of the post aliased p.
+const postMentionsColumn = mentionsOf + `m.post_id = p.id AND m.comment_id IS NULL
+		)`
+
+// commentMentionsColumn selects the mentions of the comment aliased c.
+const commentMentionsColumn = mentionsOf + `m.comment_id = c.id
+		)`
+
+type mentionList []models.Mention
+
+func (l *mentionList) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into mentions", src)
+	}
+
+	return json.Unmarshal(data, l)
+}
@@ -0,0 +1,97 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+)
+
+type MessageStore struct {
+	db *sql.DB
+}
+
+// Create adds a message to a conversation, bumps the conversation to the top
+// of its members' lists and marks it as read by its sender.
+func (s *MessageStore) Create(ctx context.Context, message *models.Message) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		query := `
+			INSERT INTO messages (conversation_id, sender_id, content)
+			VALUES ($1, $2, $3)
+			RETURNING id, created_at
+		`
+
+		err := tx.QueryRowContext(ctx, query, message.ConversationID, message.SenderID, message.Content).Scan(
+			&message.ID, &message.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		query = `UPDATE conversations SET updated_at = $2 WHERE id = $1`
+
+		if _, err := tx.ExecContext(ctx, query, message.ConversationID, message.CreatedAt); err != nil {
+			return err
+		}
+
+		query = `
+			UPDATE conversation_members
+			SET last_read_message_id = $3
+			WHERE conversation_id = $1 AND user_id = $2
+		`
+
+		_, err = tx.ExecContext(ctx, query, message.ConversationID, message.SenderID, message.ID)
+
+		return err
+	})
+}
+
+// GetByConversationID returns a page of the messages of a conversation,
+// newest first.
+func (s *MessageStore) GetByConversationID(ctx context.Context, conversationID int64, q utils.PaginatedMessageQuery) ([]models.Message, error) {
+	where, orderBy, keysetArgs, reversed := keyset(q.Keyset, "desc", "m.created_at", "m.id", 3)
+
+	query := `
+		SELECT m.id, m.conversation_id, COALESCE(m.sender_id, 0), m.content, m.created_at
+		FROM messages m
+		WHERE m.conversation_id = $1 AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	args := append([]any{conversationID, q.Limit}, keysetArgs...)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []models.Message{}
+	for rows.Next() {
+		var m models.Message
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Content, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if reversed {
+		slices.Reverse(messages)
+	}
+
+	return messages, nil
+}
@@ -6,21 +6,41 @@ import (
 	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+	"github.com/stretchr/testify/mock"
 )
 
 func NewMockStore() Storage {
 	return Storage{
-		Users: &MockUserStore{},
+		Posts:         &MockPostStore{},
+		Users:         &MockUserStore{},
+		Comments:      &MockCommentStore{},
+		Followers:     &MockFollowerStore{},
+		Roles:         &MockRoleStore{},
+		Reposts:       &MockRepostStore{},
+		Tags:          &MockTagStore{},
+		Notifications: &MockNotificationStore{},
+		Webhooks:      &MockWebhookStore{},
+		Blocks:        &MockBlockStore{},
+		Conversations: &MockConversationStore{},
+		Messages:      &MockMessageStore{},
 	}
 }
 
-type MockUserStore struct{}
+type MockUserStore struct {
+	// Users are the ones GetByID finds, any other ID being user 1.
+	Users map[int64]*models.User
+}
 
 func (m *MockUserStore) Create(context.Context, *sql.Tx, *models.User) error {
 	return nil
 }
 
-func (m *MockUserStore) GetByID(context.Context, int64) (*models.User, error) {
+func (m *MockUserStore) GetByID(_ context.Context, userID int64) (*models.User, error) {
+	if user, ok := m.Users[userID]; ok {
+		return user, nil
+	}
+
 	return &models.User{
 		ID: 1,
 	}, nil
@@ -30,7 +50,11 @@ func (m *MockUserStore) GetByEmail(context.Context, string) (*models.User, error
 	return nil, nil
 }
 
-func (m *MockUserStore) CreateAndInvite(context.Context, *models.User, string, time.Duration) error {
+func (m *MockUserStore) GetByUsernames(context.Context, []string) ([]models.User, error) {
+	return nil, nil
+}
+
+func (m *MockUserStore) CreateAndInvite(context.Context, *models.User, string, time.Duration, *models.OutboxMail) error {
 	return nil
 }
 
@@ -41,3 +65,373 @@ func (m *MockUserStore) Activate(context.Context, string) error {
 func (m *MockUserStore) Delete(context.Context, int64) error {
 	return nil
 }
+
+func (m *MockUserStore) SetPrivacy(context.Context, int64, bool) error {
+	return nil
+}
+
+func (m *MockUserStore) SetAvatar(context.Context, int64, models.ImageVariants) error {
+	return nil
+}
+
+func (m *MockUserStore) SetBanner(context.Context, int64, models.ImageVariants) error {
+	return nil
+}
+
+type MockPostStore struct {
+	mock.Mock
+}
+
+func (m *MockPostStore) Create(ctx context.Context, post *models.Post) error {
+	args := m.Called(post)
+	return args.Error(0)
+}
+
+func (m *MockPostStore) GetByID(ctx context.Context, postID int64) (*models.Post, error) {
+	args := m.Called(postID)
+	post, _ := args.Get(0).(*models.Post)
+	return post, args.Error(1)
+}
+
+func (m *MockPostStore) Delete(ctx context.Context, postID int64) error {
+	args := m.Called(postID)
+	return args.Error(0)
+}
+
+func (m *MockPostStore) Update(ctx context.Context, post *models.Post) error {
+	args := m.Called(post)
+	return args.Error(0)
+}
+
+func (m *MockPostStore) GetUserFeed(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	args := m.Called(userID, fq)
+	feed, _ := args.Get(0).([]models.PostWithMetadata)
+	return feed, args.Error(1)
+}
+
+func (m *MockPostStore) GetUserPosts(ctx context.Context, userID, viewerID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	args := m.Called(userID, viewerID, fq)
+	posts, _ := args.Get(0).([]models.PostWithMetadata)
+	return posts, args.Error(1)
+}
+
+func (m *MockPostStore) GetPublicFeed(ctx context.Context, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	args := m.Called(fq)
+	feed, _ := args.Get(0).([]models.PostWithMetadata)
+	return feed, args.Error(1)
+}
+
+func (m *MockPostStore) GetUserPublicPosts(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	args := m.Called(userID, fq)
+	posts, _ := args.Get(0).([]models.PostWithMetadata)
+	return posts, args.Error(1)
+}
+
+func (m *MockPostStore) GetRankingCandidates(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery, asOf time.Time, window time.Duration, limit int) ([]models.RankCandidate, error) {
+	args := m.Called(userID, fq, asOf, window, limit)
+	candidates, _ := args.Get(0).([]models.RankCandidate)
+	return candidates, args.Error(1)
+}
+
+func (m *MockPostStore) GetRecentActivity(ctx context.Context, userIDs []int64, since, until *time.Time, limit int) ([]models.TimelineEntry, error) {
+	args := m.Called(userIDs, since, until, limit)
+	entries, _ := args.Get(0).([]models.TimelineEntry)
+	return entries, args.Error(1)
+}
+
+func (m *MockPostStore) GetTagActivity(ctx context.Context, tags []string, since, until *time.Time, limit int) ([]models.TimelineEntry, error) {
+	args := m.Called(tags, since, until, limit)
+	entries, _ := args.Get(0).([]models.TimelineEntry)
+	return entries, args.Error(1)
+}
+
+func (m *MockPostStore) GetFeedItems(ctx context.Context, viewerID int64, postIDs []int64) ([]models.PostWithMetadata, error) {
+	args := m.Called(viewerID, postIDs)
+	items, _ := args.Get(0).([]models.PostWithMetadata)
+	return items, args.Error(1)
+}
+
+type MockCommentStore struct {
+	mock.Mock
+}
+
+func (m *MockCommentStore) Create(ctx context.Context, comment *models.Comment) error {
+	args := m.Called(comment)
+	return args.Error(0)
+}
+
+func (m *MockCommentStore) GetByID(ctx context.Context, commentID int64) (*models.Comment, error) {
+	args := m.Called(commentID)
+	comment, _ := args.Get(0).(*models.Comment)
+	return comment, args.Error(1)
+}
+
+func (m *MockCommentStore) GetByPostID(ctx context.Context, postID int64, cq utils.PaginatedCommentQuery) ([]models.Comment, error) {
+	args := m.Called(postID, cq)
+	comments, _ := args.Get(0).([]models.Comment)
+	return comments, args.Error(1)
+}
+
+func (m *MockCommentStore) GetReplies(ctx context.Context, commentID int64, cq utils.PaginatedCommentQuery) ([]models.Comment, error) {
+	args := m.Called(commentID, cq)
+	comments, _ := args.Get(0).([]models.Comment)
+	return comments, args.Error(1)
+}
+
+func (m *MockCommentStore) Update(ctx context.Context, comment *models.Comment) error {
+	args := m.Called(comment)
+	return args.Error(0)
+}
+
+func (m *MockCommentStore) Delete(ctx context.Context, commentID int64) error {
+	args := m.Called(commentID)
+	return args.Error(0)
+}
+
+type MockFollowerStore struct {
+	mock.Mock
+}
+
+func (m *MockFollowerStore) Follow(ctx context.Context, followerID, userID int64) error {
+	args := m.Called(followerID, userID)
+	return args.Error(0)
+}
+
+func (m *MockFollowerStore) UnFollow(ctx context.Context, unfollowedID, userID int64) error {
+	args := m.Called(unfollowedID, userID)
+	return args.Error(0)
+}
+
+func (m *MockFollowerStore) GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error) {
+	args := m.Called(userID)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func (m *MockFollowerStore) GetFolloweeIDs(ctx context.Context, followerID int64) ([]int64, error) {
+	args := m.Called(followerID)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func (m *MockFollowerStore) GetHeavyFolloweeIDs(ctx context.Context, followerID int64, minFollowers int) ([]int64, error) {
+	args := m.Called(followerID, minFollowers)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+func (m *MockFollowerStore) IsFollowing(ctx context.Context, followerID, userID int64) (bool, error) {
+	args := m.Called(followerID, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+type MockRoleStore struct {
+	mock.Mock
+}
+
+func (m *MockRoleStore) GetByName(ctx context.Context, roleName string) (*models.Role, error) {
+	args := m.Called(roleName)
+	role, _ := args.Get(0).(*models.Role)
+	return role, args.Error(1)
+}
+
+type MockRepostStore struct {
+	mock.Mock
+}
+
+func (m *MockRepostStore) Repost(ctx context.Context, postID, userID int64) error {
+	args := m.Called(postID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepostStore) UnRepost(ctx context.Context, postID, userID int64) error {
+	args := m.Called(postID, userID)
+	return args.Error(0)
+}
+
+type MockTagStore struct {
+	mock.Mock
+}
+
+func (m *MockTagStore) Follow(ctx context.Context, userID int64, tag string) error {
+	args := m.Called(userID, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagStore) UnFollow(ctx context.Context, userID int64, tag string) error {
+	args := m.Called(userID, tag)
+	return args.Error(0)
+}
+
+func (m *MockTagStore) GetFollowedTags(ctx context.Context, userID int64) ([]string, error) {
+	args := m.Called(userID)
+	tags, _ := args.Get(0).([]string)
+	return tags, args.Error(1)
+}
+
+type MockNotificationStore struct {
+	mock.Mock
+}
+
+func (m *MockNotificationStore) Create(ctx context.Context, n *models.Notification) error {
+	args := m.Called(n)
+	return args.Error(0)
+}
+
+func (m *MockNotificationStore) GetByUserID(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	args := m.Called(userID, unreadOnly, limit, offset)
+	notifications, _ := args.Get(0).([]models.Notification)
+	return notifications, args.Error(1)
+}
+
+func (m *MockNotificationStore) CountUnread(ctx context.Context, userID int64) (int, error) {
+	args := m.Called(userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockNotificationStore) MarkRead(ctx context.Context, userID, notificationID int64) error {
+	args := m.Called(userID, notificationID)
+	return args.Error(0)
+}
+
+func (m *MockNotificationStore) MarkAllRead(ctx context.Context, userID int64) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockNotificationStore) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	args := m.Called(userID)
+	prefs, _ := args.Get(0).(map[string]bool)
+	return prefs, args.Error(1)
+}
+
+func (m *MockNotificationStore) SetPreferences(ctx context.Context, userID int64, prefs map[string]bool) error {
+	args := m.Called(userID, prefs)
+	return args.Error(0)
+}
+
+type MockWebhookStore struct {
+	mock.Mock
+}
+
+func (m *MockWebhookStore) Create(ctx context.Context, w *models.Webhook) error {
+	args := m.Called(w)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) GetByID(ctx context.Context, webhookID int64) (*models.Webhook, error) {
+	args := m.Called(webhookID)
+	webhook, _ := args.Get(0).(*models.Webhook)
+	return webhook, args.Error(1)
+}
+
+func (m *MockWebhookStore) GetByUserID(ctx context.Context, userID int64) ([]models.Webhook, error) {
+	args := m.Called(userID)
+	webhooks, _ := args.Get(0).([]models.Webhook)
+	return webhooks, args.Error(1)
+}
+
+func (m *MockWebhookStore) Update(ctx context.Context, w *models.Webhook) error {
+	args := m.Called(w)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) Delete(ctx context.Context, webhookID int64) error {
+	args := m.Called(webhookID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) Enqueue(ctx context.Context, event string, userIDs []int64, isPublic bool, payload []byte) error {
+	args := m.Called(event, userIDs, isPublic, payload)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	args := m.Called(limit, lease)
+	deliveries, _ := args.Get(0).([]models.WebhookDelivery)
+	return deliveries, args.Error(1)
+}
+
+func (m *MockWebhookStore) RecordAttempt(ctx context.Context, a *models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	args := m.Called(a, status, nextAttemptAt)
+	return args.Error(0)
+}
+
+func (m *MockWebhookStore) GetDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) ([]models.WebhookDelivery, error) {
+	args := m.Called(webhookID, status, limit, offset)
+	deliveries, _ := args.Get(0).([]models.WebhookDelivery)
+	return deliveries, args.Error(1)
+}
+
+func (m *MockWebhookStore) Redeliver(ctx context.Context, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	args := m.Called(webhookID, deliveryID)
+	delivery, _ := args.Get(0).(*models.WebhookDelivery)
+	return delivery, args.Error(1)
+}
+
+type MockBlockStore struct {
+	mock.Mock
+}
+
+func (m *MockBlockStore) Block(ctx context.Context, blockerID, blockedID int64) error {
+	args := m.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockBlockStore) UnBlock(ctx context.Context, blockerID, blockedID int64) error {
+	args := m.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockBlockStore) GetBlockedBetween(ctx context.Context, userID int64, otherIDs []int64) ([]int64, error) {
+	args := m.Called(userID, otherIDs)
+	ids, _ := args.Get(0).([]int64)
+	return ids, args.Error(1)
+}
+
+type MockConversationStore struct {
+	mock.Mock
+}
+
+func (m *MockConversationStore) Create(ctx context.Context, conversation *models.Conversation, memberIDs []int64) error {
+	args := m.Called(conversation, memberIDs)
+	return args.Error(0)
+}
+
+func (m *MockConversationStore) GetByID(ctx context.Context, conversationID int64) (*models.Conversation, error) {
+	args := m.Called(conversationID)
+	conversation, _ := args.Get(0).(*models.Conversation)
+	return conversation, args.Error(1)
+}
+
+func (m *MockConversationStore) GetDirect(ctx context.Context, userID, otherID int64) (*models.Conversation, error) {
+	args := m.Called(userID, otherID)
+	conversation, _ := args.Get(0).(*models.Conversation)
+	return conversation, args.Error(1)
+}
+
+func (m *MockConversationStore) GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]models.Conversation, error) {
+	args := m.Called(userID, limit, offset)
+	conversations, _ := args.Get(0).([]models.Conversation)
+	return conversations, args.Error(1)
+}
+
+func (m *MockConversationStore) MarkRead(ctx context.Context, conversationID, userID, messageID int64) error {
+	args := m.Called(conversationID, userID, messageID)
+	return args.Error(0)
+}
+
+type MockMessageStore struct {
+	mock.Mock
+}
+
+func (m *MockMessageStore) Create(ctx context.Context, message *models.Message) error {
+	args := m.Called(message)
+	return args.Error(0)
+}
+
+func (m *MockMessageStore) GetByConversationID(ctx context.Context, conversationID int64, q utils.PaginatedMessageQuery) ([]models.Message, error) {
+	args := m.Called(conversationID, q)
+	messages, _ := args.Get(0).([]models.Message)
+	return messages, args.Error(1)
+}
@@ -0,0 +1,233 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type NotificationStore struct {
+	db *sql.DB
+}
+
+// Create notifies n.UserID, merging the notification into their unread one
+// of the same group if any. Nothing is created when the user turned off
+// notifications of its type, nor when they blocked the actor or were blocked
+// by them, moderation excepted; n.ID is left zero then.
+func (s *NotificationStore) Create(ctx context.Context, n *models.Notification) error {
+	query := `
+		INSERT INTO notifications (user_id, type, group_key, post_id, comment_id, action, actor_ids)
+		SELECT $1::INTEGER, $2::VARCHAR, NULLIF($3::TEXT, ''), $4::INTEGER, $5::INTEGER, NULLIF($6::VARCHAR, ''), ARRAY[$7::BIGINT]
+		WHERE NOT EXISTS (
+				SELECT 1 FROM notification_preferences np
+				WHERE np.user_id = $1 AND np.type = $2 AND NOT np.enabled
+			)
+			AND ($2 = '` + models.NotificationModeration + `' OR NOT EXISTS (
+				SELECT 1 FROM blocks b
+				WHERE (b.blocker_id = $1 AND b.blocked_id = $7)
+					OR (b.blocker_id = $7 AND b.blocked_id = $1)
+			))
+		ON CONFLICT (user_id, group_key) WHERE read_at IS NULL DO UPDATE
+		SET
+			actor_ids = (array_prepend($7::BIGINT, array_remove(notifications.actor_ids, $7::BIGINT)))[1:10],
+			actor_count = notifications.actor_count
+				+ CASE WHEN $7 = ANY(notifications.actor_ids) THEN 0 ELSE 1 END,
+			comment_id = EXCLUDED.comment_id,
+			updated_at = NOW()
+		RETURNING id, actor_count, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(
+		ctx, query, n.UserID, n.Type, n.GroupKey, n.PostID, n.CommentID, n.Action, n.ActorID,
+	).Scan(&n.ID, &n.ActorCount, &n.CreatedAt, &n.UpdatedAt)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	return nil
+}
+
+// GetByUserID returns a page of the notifications of a user, the most
+// recently updated first, only the unread ones if unreadOnly is set.
+func (s *NotificationStore) GetByUserID(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error) {
+	query := `
+		SELECT
+			n.id, n.type, n.post_id, n.comment_id, COALESCE(n.action, ''),
+			n.actor_count, n.read_at IS NOT NULL, n.created_at, n.updated_at,
+			(
+				SELECT COALESCE(json_agg(json_build_object(
+					'id', u.id, 'username', u.username, 'avatar', u.avatar
+				) ORDER BY a.position), '[]')
+				FROM unnest(n.actor_ids[1:3]) WITH ORDINALITY AS a(id, position)
+				JOIN users u ON u.id = a.id
+			)
+		FROM notifications n
+		WHERE n.user_id = $1 AND (NOT $4 OR n.read_at IS NULL)
+		ORDER BY n.updated_at DESC, n.id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID, limit, offset, unreadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notifications := []models.Notification{}
+	for rows.Next() {
+		n := models.Notification{UserID: userID}
+		err := rows.Scan(
+			&n.ID, &n.Type, &n.PostID, &n.CommentID, &n.Action,
+			&n.ActorCount, &n.IsRead, &n.CreatedAt, &n.UpdatedAt,
+			(*userList)(&n.Actors),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		notifications = append(notifications, n)
+	}
+
+	return notifications, rows.Err()
+}
+
+func (s *NotificationStore) CountUnread(ctx context.Context, userID int64) (int, error) {
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var count int
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&count)
+
+	return count, err
+}
+
+// MarkRead marks a notification of the user as read, ErrNotFound if they
+// have no such notification.
+func (s *NotificationStore) MarkRead(ctx context.Context, userID, notificationID int64) error {
+	query := `
+		UPDATE notifications
+		SET read_at = COALESCE(read_at, NOW())
+		WHERE id = $1 AND user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, notificationID, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+func (s *NotificationStore) MarkAllRead(ctx context.Context, userID int64) error {
+	query := `UPDATE notifications SET read_at = NOW() WHERE user_id = $1 AND read_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID)
+
+	return err
+}
+
+// GetPreferences tells, for every notification type, whether the user gets
+// notified of it. They all are on by default.
+func (s *NotificationStore) GetPreferences(ctx context.Context, userID int64) (map[string]bool, error) {
+	query := `SELECT type, enabled FROM notification_preferences WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := make(map[string]bool, len(models.NotificationTypes))
+	for _, typ := range models.NotificationTypes {
+		prefs[typ] = true
+	}
+
+	for rows.Next() {
+		var typ string
+		var enabled bool
+		if err := rows.Scan(&typ, &enabled); err != nil {
+			return nil, err
+		}
+
+		prefs[typ] = enabled
+	}
+
+	return prefs, rows.Err()
+}
+
+// SetPreferences turns the given notification types on or off for the user,
+// leaving the others as they are.
+func (s *NotificationStore) SetPreferences(ctx context.Context, userID int64, prefs map[string]bool) error {
+	var types []string
+	var enabled []bool
+	for typ, on := range prefs {
+		types = append(types, typ)
+		enabled = append(enabled, on)
+	}
+
+	query := `
+		INSERT INTO notification_preferences (user_id, type, enabled)
+		SELECT $1::INTEGER, p.type, p.enabled
+		FROM unnest($2::VARCHAR[], $3::BOOLEAN[]) AS p(type, enabled)
+		ON CONFLICT (user_id, type) DO UPDATE
+		SET enabled = EXCLUDED.enabled
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, pq.Array(types), pq.Array(enabled))
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23503" {
+			return ErrNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// userList scans a JSON array of users.
+type userList []models.User
+
+func (l *userList) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into users", src)
+	}
+
+	return json.Unmarshal(data, l)
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+type OutboxStore struct {
+	db *sql.DB
+}
+
+// enqueueMail queues a mail within tx, so that it is only sent if tx is
+// committed.
+func enqueueMail(ctx context.Context, tx *sql.Tx, m *models.OutboxMail) error {
+	query := `
+		INSERT INTO mail_outbox (template, username, email, data, is_sandbox, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return tx.QueryRowContext(
+		ctx, query, m.Template, m.Username, m.Email, string(m.Data), m.IsSandbox, m.ExpiresAt,
+	).Scan(&m.ID)
+}
+
+// ClaimDue takes up to limit pending mails that are due, leaving the ones
+// other instances are claiming, and pushes them back by lease so that a mail
+// whose outcome is never recorded is tried again.
+func (s *OutboxStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error) {
+	query := `
+		UPDATE mail_outbox
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		WHERE id IN (
+			SELECT id
+			FROM mail_outbox
+			WHERE status = '` + models.OutboxPending + `' AND next_attempt_at <= NOW()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, template, username, email, data, is_sandbox, attempts, expires_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var mails []models.OutboxMail
+	for rows.Next() {
+		var m models.OutboxMail
+		err := rows.Scan(
+			&m.ID, &m.Template, &m.Username, &m.Email, (*[]byte)(&m.Data), &m.IsSandbox, &m.Attempts, &m.ExpiresAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		mails = append(mails, m)
+	}
+
+	return mails, rows.Err()
+}
+
+// Finish moves a mail out of pending. Its data is emptied, as it can carry
+// tokens that must not outlive the mail.
+func (s *OutboxStore) Finish(ctx context.Context, mailID int64, status, lastError string) error {
+	query := `
+		UPDATE mail_outbox
+		SET
+			status = $2,
+			attempts = attempts + CASE WHEN $2 = '` + models.OutboxExpired + `' THEN 0 ELSE 1 END,
+			last_error = $3,
+			data = '{}',
+			sent_at = CASE WHEN $2 = '` + models.OutboxSent + `' THEN NOW() END
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, mailID, status, lastError)
+	return err
+}
+
+// Retry records a failed attempt at a mail, to be tried again at
+// nextAttemptAt.
+func (s *OutboxStore) Retry(ctx context.Context, mailID int64, lastError string, nextAttemptAt time.Time) error {
+	query := `
+		UPDATE mail_outbox
+		SET attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, mailID, lastError, nextAttemptAt)
+	return err
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
+	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
 	"github.com/sandoxlabs99/gopher_social/internal/utils"
@@ -17,8 +20,8 @@ type PostStore struct {
 
 func (s *PostStore) Create(ctx context.Context, post *models.Post) error {
 	query := `
-	INSERT INTO posts (title, content, tags, user_id)
-	VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at
+	INSERT INTO posts (title, content, tags, user_id, quoted_post_id, visibility)
+	VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -26,24 +29,38 @@ func (s *PostStore) Create(ctx context.Context, post *models.Post) error {
 
 	// use pg_sleep(10) or generate_series to simulate long running/sslow queries to test the context timeout
 
-	err := s.db.QueryRowContext(
-		ctx,
-		query,
-		post.Title,
-		post.Content,
-		pq.Array(post.Tags),
-		post.UserID,
-	).Scan(
-		&post.ID,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx,
+			query,
+			post.Title,
+			post.Content,
+			pq.Array(post.Tags),
+			post.UserID,
+			post.QuotedPostID,
+			post.Visibility,
+		).Scan(
+			&post.ID,
+			&post.CreatedAt,
+			&post.UpdatedAt,
+		)
 
-	if err != nil {
-		return err
-	}
+		if err != nil {
+			return err
+		}
 
-	return nil
+		if len(post.Mentions) > 0 {
+			if err := saveMentions(ctx, tx, post.UserID, post.ID, nil, post.Mentions); err != nil {
+				return err
+			}
+		}
+
+		if len(post.Attachments) == 0 {
+			return nil
+		}
+
+		return attachMedia(ctx, tx, post)
+	})
 }
 
 func (s *PostStore) GetByID(ctx context.Context, postID int64) (*models.Post, error) {
@@ -51,10 +68,11 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*models.Post, er
 
 	query := `
 		SELECT 
-			id, title, content, tags, user_id, 
-			created_at, updated_at, version
-		FROM posts
-		WHERE id = $1
+			p.id, p.title, p.content, p.tags, p.user_id, p.quoted_post_id,
+			p.visibility, p.created_at, p.updated_at, p.version, ` + attachmentsColumn + `,
+			` + postMentionsColumn + `
+		FROM posts p
+		WHERE p.id = $1
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
@@ -62,7 +80,8 @@ func (s *PostStore) GetByID(ctx context.Context, postID int64) (*models.Post, er
 
 	err := s.db.QueryRowContext(ctx, query, postID).Scan(
 		&post.ID, &post.Title, &post.Content, pq.Array(&post.Tags),
-		&post.UserID, &post.CreatedAt, &post.UpdatedAt, &post.Version,
+		&post.UserID, &post.QuotedPostID, &post.Visibility, &post.CreatedAt, &post.UpdatedAt, &post.Version,
+		(*attachmentList)(&post.Attachments), (*mentionList)(&post.Mentions),
 	)
 
 	if err != nil {
@@ -108,56 +127,356 @@ func (s *PostStore) Update(ctx context.Context, post *models.Post) error {
 			title = $1, 
 			content = $2, 
 			tags = $3,
+			visibility = $4,
 			version = version + 1,
 			updated_at = NOW()
-		WHERE id = $4 AND version = $5
+		WHERE id = $5 AND version = $6
 		RETURNING version
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	err := s.db.QueryRowContext(
-		ctx, query, post.Title, post.Content,
-		pq.Array(post.Tags), post.ID, post.Version,
-	).Scan(&post.Version)
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		err := tx.QueryRowContext(
+			ctx, query, post.Title, post.Content,
+			pq.Array(post.Tags), post.Visibility, post.ID, post.Version,
+		).Scan(&post.Version)
 
-	if err != nil {
-		switch {
-		case errors.Is(err, sql.ErrNoRows):
-			return ErrUpdateConflict
-		default:
-			return err
+		if err != nil {
+			switch {
+			case errors.Is(err, sql.ErrNoRows):
+				return ErrUpdateConflict
+			default:
+				return err
+			}
 		}
-	}
 
-	return nil
+		return saveMentions(ctx, tx, post.UserID, post.ID, nil, post.Mentions)
+	})
 }
 
+// GetUserFeed returns the posts authored or reposted by the users that userID
+// follows, and their own posts if fq.WithOwnPosts is set. A post reposted by
+// several followees only shows up once, attributed to the most recent repost.
 func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
-	query := `
+	return s.listItems(ctx, userID, userID, fq, func(postsRange, repostsRange string) string {
+		return feedItems(postsRange, repostsRange, fq.WithOwnPosts)
+	})
+}
+
+// GetUserPosts returns the profile timeline of a user: the posts they
+// authored or reposted that viewerID can see, zero being an anonymous
+// viewer.
+func (s *PostStore) GetUserPosts(ctx context.Context, userID, viewerID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	return s.listItems(ctx, userID, viewerID, fq, profileItems)
+}
+
+// listItems lists the posts of the latest_items built by items for userID
+// that viewerID can see, filtered and paginated by fq.
+func (s *PostStore) listItems(ctx context.Context, userID, viewerID int64, fq utils.PaginatedFeedQuery, items func(postsRange, repostsRange string) string) ([]models.PostWithMetadata, error) {
+	args := []any{userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags), viewerID}
+
+	var sincePos, untilPos int
+	if fq.Since != nil {
+		args = append(args, *fq.Since)
+		sincePos = len(args)
+	}
+	if fq.Until != nil {
+		args = append(args, *fq.Until)
+		untilPos = len(args)
+	}
+
+	postsRange := timeRange("p.created_at", sincePos, untilPos)
+	repostsRange := timeRange("r.created_at", sincePos, untilPos)
+
+	where, orderBy, keysetArgs, reversed := keyset(fq.Keyset, fq.Sort, "li.activity_at", "p.id", len(args)+1)
+	args = append(args, keysetArgs...)
+
+	query := items(postsRange, repostsRange) + `
 		SELECT 
-			p.id, p.title, p.content, p.tags,
-			p.user_id, p.created_at, p.version, u.username,
-			COALESCE(c.comment_count, 0) AS comments_count
-		FROM posts p
+			p.id, p.title, p.content, p.tags, p.quoted_post_id,
+			p.user_id, p.created_at, p.updated_at, p.version, p.visibility, u.username, u.avatar, ` + attachmentsColumn + `, ` + postMentionsColumn + `,
+			COALESCE(c.comment_count, 0) AS comments_count,
+			COALESCE(rc.repost_count, 0) AS reposts_count,
+			ru.id, ru.username, ru.avatar, li.activity_at, li.reason
+		FROM latest_items li
+		JOIN posts p ON p.id = li.post_id
 		JOIN users u ON u.id = p.user_id
-		JOIN followers f ON f.user_id = u.id AND f.follower_id = $1
+		LEFT JOIN users ru ON ru.id = li.reposter_id
 		LEFT JOIN (
 			SELECT post_id, COUNT(*) AS comment_count
 			FROM comments
 			GROUP BY post_id
 		) c ON c.post_id = p.id
+		LEFT JOIN (
+			SELECT post_id, COUNT(*) AS repost_count
+			FROM reposts
+			GROUP BY post_id
+		) rc ON rc.post_id = p.id
 		WHERE (p.title ILIKE '%' || $4 || '%' OR p.content ILIKE '%' || $4 || '%') AND (p.tags @> $5 OR $5 = '{}')
-		ORDER BY p.created_at ` + fq.Sort + `
+			AND ` + visibleTo(6) + `
+			AND ` + where + `
+		ORDER BY ` + orderBy + `
 		LIMIT $2
 		OFFSET $3
 	`
 
+	feed, err := s.queryFeedItems(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if reversed {
+		slices.Reverse(feed)
+	}
+
+	return feed, nil
+}
+
+// GetPublicFeed returns the recent public posts of everyone, filtered and
+// paginated like a user's feed.
+func (s *PostStore) GetPublicFeed(ctx context.Context, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	return s.listPublic(ctx, 0, fq)
+}
+
+// GetUserPublicPosts returns the recent public posts authored by a user,
+// leaving out their reposts, filtered and paginated like a user's feed.
+func (s *PostStore) GetUserPublicPosts(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	return s.listPublic(ctx, userID, fq)
+}
+
+// listPublic lists the public posts of authorID, or of everyone when zero.
+func (s *PostStore) listPublic(ctx context.Context, authorID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	args := []any{fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags), authorID}
+
+	var sincePos, untilPos int
+	if fq.Since != nil {
+		args = append(args, *fq.Since)
+		sincePos = len(args)
+	}
+	if fq.Until != nil {
+		args = append(args, *fq.Until)
+		untilPos = len(args)
+	}
+
+	where, orderBy, keysetArgs, reversed := keyset(fq.Keyset, fq.Sort, "p.created_at", "p.id", len(args)+1)
+	args = append(args, keysetArgs...)
+
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.quoted_post_id,
+			p.user_id, p.created_at, p.updated_at, p.version, p.visibility, u.username, u.avatar, ` + attachmentsColumn + `, ` + postMentionsColumn + `,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts_count,
+			NULL, NULL, NULL, p.created_at, NULL
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		WHERE (p.title ILIKE '%' || $3 || '%' OR p.content ILIKE '%' || $3 || '%') AND (p.tags @> $4 OR $4 = '{}')
+			AND ` + isPublic + ` AND ($5 = 0 OR p.user_id = $5)
+			AND ` + timeRange("p.created_at", sincePos, untilPos) + `
+			AND ` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT $1
+		OFFSET $2
+	`
+
+	feed, err := s.queryFeedItems(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if reversed {
+		slices.Reverse(feed)
+	}
+
+	return feed, nil
+}
+
+// GetRankingCandidates returns the most recent posts of the user's feed that
+// were active in the window ending at asOf, along with the signals to rank
+// them on: their engagement and the viewer's affinity with their author, all
+// counted as of asOf so that ranking the same candidates again gives the
+// same result.
+func (s *PostStore) GetRankingCandidates(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery, asOf time.Time, window time.Duration, limit int) ([]models.RankCandidate, error) {
+	since, until := asOf.Add(-window), asOf
+	if fq.Since != nil && fq.Since.After(since) {
+		since = *fq.Since
+	}
+	if fq.Until != nil && fq.Until.Before(until) {
+		until = *fq.Until
+	}
+
+	args := []any{userID, limit, fq.Search, pq.Array(fq.Tags), asOf, since, until}
+
+	postsRange := timeRange("p.created_at", 6, 7)
+	repostsRange := timeRange("r.created_at", 6, 7)
+
+	query := feedItems(postsRange, repostsRange, fq.WithOwnPosts) + `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.quoted_post_id,
+			p.user_id, p.created_at, p.updated_at, p.version, p.visibility, u.username, u.avatar, ` + attachmentsColumn + `, ` + postMentionsColumn + `,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id AND c.created_at < $5) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id AND r.created_at < $5) AS reposts_count,
+			ru.id, ru.username, ru.avatar, li.activity_at, li.reason,
+			(
+				SELECT COUNT(*)
+				FROM comments c
+				JOIN posts ap ON ap.id = c.post_id
+				WHERE c.user_id = $1 AND ap.user_id = p.user_id
+					AND c.created_at >= $5 - INTERVAL '30 days' AND c.created_at < $5
+			) + (
+				SELECT COUNT(*)
+				FROM reposts r
+				JOIN posts ap ON ap.id = r.post_id
+				WHERE r.user_id = $1 AND ap.user_id = p.user_id
+					AND r.created_at >= $5 - INTERVAL '30 days' AND r.created_at < $5
+			) AS affinity
+		FROM latest_items li
+		JOIN posts p ON p.id = li.post_id
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN users ru ON ru.id = li.reposter_id
+		WHERE (p.title ILIKE '%' || $3 || '%' OR p.content ILIKE '%' || $3 || '%') AND (p.tags @> $4 OR $4 = '{}')
+			AND ` + visibleTo(1) + `
+		ORDER BY li.activity_at DESC, p.id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []models.RankCandidate
+	for rows.Next() {
+		var c models.RankCandidate
+		if err := scanFeedItem(rows, &c.PostWithMetadata, &c.Affinity); err != nil {
+			return nil, err
+		}
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// GetRecentActivity returns the most recent posts authored or reposted by
+// any of userIDs between since and until, both inclusive and both optional.
+func (s *PostStore) GetRecentActivity(ctx context.Context, userIDs []int64, since, until *time.Time, limit int) ([]models.TimelineEntry, error) {
+	query := `
+		SELECT post_id, MAX(activity_at) AS activity_at
+		FROM (
+			SELECT p.id AS post_id, p.created_at AS activity_at
+			FROM posts p
+			WHERE p.user_id = ANY($1)
+				AND ($3::TIMESTAMPTZ IS NULL OR p.created_at >= $3)
+				AND ($4::TIMESTAMPTZ IS NULL OR p.created_at <= $4)
+			UNION ALL
+			SELECT r.post_id, r.created_at
+			FROM reposts r
+			WHERE r.user_id = ANY($1)
+				AND ($3::TIMESTAMPTZ IS NULL OR r.created_at >= $3)
+				AND ($4::TIMESTAMPTZ IS NULL OR r.created_at <= $4)
+		) activity
+		GROUP BY post_id
+		ORDER BY activity_at DESC, post_id DESC
+		LIMIT $2
+	`
+
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
-	rows, err := s.db.QueryContext(ctx, query, userID, fq.Limit, fq.Offset, fq.Search, pq.Array(fq.Tags))
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(userIDs), limit, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimelineEntry
+	for rows.Next() {
+		var e models.TimelineEntry
+		if err := rows.Scan(&e.PostID, &e.ActivityAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetTagActivity returns the most recent public posts tagged with any of the
+// given tags. Like GetRecentActivity, both bounds are inclusive.
+func (s *PostStore) GetTagActivity(ctx context.Context, tags []string, since, until *time.Time, limit int) ([]models.TimelineEntry, error) {
+	query := `
+		SELECT p.id, p.created_at
+		FROM posts p
+		WHERE p.tags && $1 AND ` + isPublic + `
+			AND ($3::TIMESTAMPTZ IS NULL OR p.created_at >= $3)
+			AND ($4::TIMESTAMPTZ IS NULL OR p.created_at <= $4)
+		ORDER BY p.created_at DESC, p.id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(tags), limit, since, until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []models.TimelineEntry
+	for rows.Next() {
+		e := models.TimelineEntry{Reason: models.FeedReasonFollowedTag}
+		if err := rows.Scan(&e.PostID, &e.ActivityAt); err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// GetFeedItems hydrates the given posts the way GetUserFeed returns them to
+// viewerID, leaving out the ones they cannot see. The activity time of each
+// item is the time it was posted.
+func (s *PostStore) GetFeedItems(ctx context.Context, viewerID int64, postIDs []int64) ([]models.PostWithMetadata, error) {
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.quoted_post_id,
+			p.user_id, p.created_at, p.updated_at, p.version, p.visibility, u.username, u.avatar, ` + attachmentsColumn + `, ` + postMentionsColumn + `,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts_count,
+			ru.id, ru.username, ru.avatar, p.created_at, NULL
+		FROM posts p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN LATERAL (
+			SELECT ru.id, ru.username, ru.avatar
+			FROM reposts r
+			JOIN followers f ON f.user_id = r.user_id AND f.follower_id = $1
+			JOIN users ru ON ru.id = r.user_id
+			WHERE r.post_id = p.id
+			ORDER BY r.created_at DESC
+			LIMIT 1
+		) ru ON TRUE
+		WHERE p.id = ANY($2) AND ` + visibleTo(1) + `
+	`
+
+	return s.queryFeedItems(ctx, query, viewerID, pq.Array(postIDs))
+}
+
+func (s *PostStore) queryFeedItems(ctx context.Context, query string, args ...any) ([]models.PostWithMetadata, error) {
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -167,17 +486,136 @@ func (s *PostStore) GetUserFeed(ctx context.Context, userID int64, fq utils.Pagi
 	var feed []models.PostWithMetadata
 	for rows.Next() {
 		var p models.PostWithMetadata
-		err := rows.Scan(
-			&p.ID, &p.Title, &p.Content,
-			pq.Array(&p.Tags), &p.UserID, &p.CreatedAt,
-			&p.Version, &p.User.Username, &p.CommentCount,
-		)
-		if err != nil {
+		if err := scanFeedItem(rows, &p); err != nil {
 			return nil, err
 		}
 
 		feed = append(feed, p)
 	}
 
-	return feed, nil
+	return feed, rows.Err()
+}
+
+// scanFeedItem scans a row made of the feed item columns, followed by the
+// extra ones.
+func scanFeedItem(rows *sql.Rows, p *models.PostWithMetadata, extra ...any) error {
+	var reposterID sql.NullInt64
+	var reposterUsername, reason sql.NullString
+	var reposterAvatar models.ImageVariants
+
+	dest := []any{
+		&p.ID, &p.Title, &p.Content,
+		pq.Array(&p.Tags), &p.QuotedPostID, &p.UserID, &p.CreatedAt, &p.UpdatedAt,
+		&p.Version, &p.Visibility, &p.User.Username, (*imageVariants)(&p.User.Avatar),
+		(*attachmentList)(&p.Attachments), (*mentionList)(&p.Mentions), &p.CommentCount, &p.RepostCount,
+		&reposterID, &reposterUsername, (*imageVariants)(&reposterAvatar), &p.ActivityAt, &reason,
+	}
+
+	if err := rows.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	p.Reason = reason.String
+
+	if reposterID.Valid {
+		p.RepostedBy = &models.User{
+			ID:       reposterID.Int64,
+			Username: reposterUsername.String,
+			Avatar:   reposterAvatar,
+		}
+	}
+
+	return nil
+}
+
+// feedItems is the WITH clause listing the posts of a user's feed as
+// latest_items: one row per post, with the time of the latest activity that
+// brought it to the feed, the followee that reposted it, if any, and the
+// reason it is on the feed. The user is bound to $1, the time ranges are
+// applied to each source of the feed separately so that the
+// (user_id, created_at) and tags indexes can be used.
+func feedItems(postsRange, repostsRange string, withOwnPosts bool) string {
+	ownPosts := ""
+	if withOwnPosts {
+		ownPosts = `
+			UNION ALL
+			SELECT p.id, p.created_at, NULL, '` + models.FeedReasonOwnPost + `'
+			FROM posts p
+			WHERE p.user_id = $1 AND ` + postsRange
+	}
+
+	return `
+		WITH feed_items AS (
+			SELECT p.id AS post_id, p.created_at AS activity_at, NULL::INTEGER AS reposter_id,
+				'` + models.FeedReasonFollowedUser + `' AS reason
+			FROM posts p
+			JOIN followers f ON f.user_id = p.user_id AND f.follower_id = $1
+			WHERE ` + postsRange + `
+			UNION ALL
+			SELECT r.post_id, r.created_at, r.user_id, '` + models.FeedReasonFollowedUser + `'
+			FROM reposts r
+			JOIN followers f ON f.user_id = r.user_id AND f.follower_id = $1
+			WHERE ` + repostsRange + `
+			UNION ALL
+			SELECT p.id, p.created_at, NULL, '` + models.FeedReasonFollowedTag + `'
+			FROM posts p
+			WHERE p.tags && ARRAY(SELECT tag FROM tag_follows WHERE user_id = $1)
+				AND ` + isPublic + ` AND ` + postsRange + ownPosts + `
+		), ` + latestItems
+}
+
+// profileItems is the WITH clause listing the posts authored or reposted by
+// the user bound to $1 as latest_items, see feedItems.
+func profileItems(postsRange, repostsRange string) string {
+	return `
+		WITH feed_items AS (
+			SELECT p.id AS post_id, p.created_at AS activity_at, NULL::INTEGER AS reposter_id,
+				NULL::TEXT AS reason
+			FROM posts p
+			WHERE p.user_id = $1 AND ` + postsRange + `
+			UNION ALL
+			SELECT r.post_id, r.created_at, r.user_id, NULL
+			FROM reposts r
+			WHERE r.user_id = $1 AND ` + repostsRange + `
+		), ` + latestItems
+}
+
+// latestItems keeps the latest activity of each post, preferring any other
+// reason for a post to be on the feed over a followed tag.
+const latestItems = `latest_items AS (
+			SELECT DISTINCT ON (post_id) post_id, activity_at, reposter_id, reason
+			FROM feed_items
+			ORDER BY post_id, activity_at DESC, reason = '` + models.FeedReasonFollowedTag + `'
+		)`
+
+// isPublic restricts the posts aliased p to the public ones, the only ones
+// listed to people who don't follow their author.
+const isPublic = `p.visibility = '` + models.VisibilityPublic + `'`
+
+// visibleTo builds the predicate restricting the posts aliased p to the ones
+// the user bound to the viewerPos placeholder can see, a zero user being an
+// anonymous viewer.
+func visibleTo(viewerPos int) string {
+	return fmt.Sprintf(`(p.visibility IN ('%[1]s', '%[2]s') OR p.user_id = $%[4]d
+		OR (p.visibility = '%[3]s' AND EXISTS (
+			SELECT 1 FROM followers vf WHERE vf.user_id = p.user_id AND vf.follower_id = $%[4]d
+		)))`,
+		models.VisibilityPublic, models.VisibilityUnlisted, models.VisibilityFollowers, viewerPos,
+	)
+}
+
+// timeRange builds the predicate restricting col to the range bound to the
+// since/until placeholders, a zero position leaving that side open.
+func timeRange(col string, sincePos, untilPos int) string {
+	predicate := "TRUE"
+
+	if sincePos > 0 {
+		predicate += fmt.Sprintf(" AND %s >= $%d", col, sincePos)
+	}
+
+	if untilPos > 0 {
+		predicate += fmt.Sprintf(" AND %s < $%d", col, untilPos)
+	}
+
+	return predicate
 }
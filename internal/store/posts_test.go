@@ -0,0 +1,125 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVisibleTo(t *testing.T) {
+	tests := []struct {
+		name      string
+		viewerPos int
+		want      []string
+	}{
+		{
+			name:      "first placeholder",
+			viewerPos: 1,
+			want: []string{
+				`p.visibility IN ('public', 'unlisted')`,
+				`OR p.user_id = $1`,
+				`p.visibility = 'followers' AND EXISTS`,
+				`vf.user_id = p.user_id AND vf.follower_id = $1`,
+			},
+		},
+		{
+			name:      "later placeholder",
+			viewerPos: 6,
+			want: []string{
+				`OR p.user_id = $6`,
+				`vf.follower_id = $6`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := visibleTo(tt.viewerPos)
+
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("visibleTo(%d) = %s, want it to contain %q", tt.viewerPos, got, want)
+				}
+			}
+
+			// private posts are only ever shown to their author
+			if strings.Contains(got, "private") {
+				t.Errorf("visibleTo(%d) = %s, want private posts left out", tt.viewerPos, got)
+			}
+		})
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	if want := `p.visibility = 'public'`; isPublic != want {
+		t.Errorf("isPublic = %s, want %s", isPublic, want)
+	}
+}
+
+// squash collapses the whitespace of a query, for it to be searched.
+func squash(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+func TestFeedItems(t *testing.T) {
+	got := squash(feedItems("TRUE", "TRUE", false))
+
+	want := []string{
+		// the posts and reposts of the followees
+		`FROM posts p JOIN followers f ON f.user_id = p.user_id AND f.follower_id = $1`,
+		`SELECT r.post_id, r.created_at, r.user_id, 'followed_user' FROM reposts r JOIN followers f ON f.user_id = r.user_id AND f.follower_id = $1`,
+		// a post shows up once, at its latest activity
+		`SELECT DISTINCT ON (post_id) post_id, activity_at, reposter_id, reason FROM feed_items ORDER BY post_id, activity_at DESC`,
+	}
+
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("feedItems() = %s, want it to contain %q", got, w)
+		}
+	}
+}
+
+func TestFeedItemsOwnPosts(t *testing.T) {
+	ownPosts := `SELECT p.id, p.created_at, NULL, 'own_post' FROM posts p WHERE p.user_id = $1 AND TRUE AND p.created_at >= $7`
+
+	tests := []struct {
+		name         string
+		withOwnPosts bool
+		want         bool
+		wantSources  int
+	}{
+		{name: "with own posts", withOwnPosts: true, want: true, wantSources: 4},
+		{name: "without own posts", withOwnPosts: false, want: false, wantSources: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := squash(feedItems(timeRange("p.created_at", 7, 0), timeRange("r.created_at", 7, 0), tt.withOwnPosts))
+
+			if strings.Contains(got, ownPosts) != tt.want {
+				t.Errorf("feedItems() = %s, want own posts %v", got, tt.want)
+			}
+
+			// own posts are a source of their own, deduplicated like the others
+			if n := strings.Count(got, "UNION ALL") + 1; n != tt.wantSources {
+				t.Errorf("feedItems() has %d sources, want %d", n, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestFeedItemsFollowedTags(t *testing.T) {
+	got := squash(feedItems("TRUE", "TRUE", false))
+
+	want := []string{
+		// only the public posts are reached through tags
+		`'followed_tag' FROM posts p WHERE p.tags && ARRAY(SELECT tag FROM tag_follows WHERE user_id = $1) AND p.visibility = 'public'`,
+		// a post also reached through a followed user is kept as such
+		`ORDER BY post_id, activity_at DESC, reason = 'followed_tag'`,
+	}
+
+	for _, w := range want {
+		if !strings.Contains(got, w) {
+			t.Errorf("feedItems() = %s, want it to contain %q", got, w)
+		}
+	}
+}
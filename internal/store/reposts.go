@@ -0,0 +1,54 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type RepostStore struct {
+	db *sql.DB
+}
+
+func (s *RepostStore) Repost(ctx context.Context, postID, userID int64) error {
+	query := `
+	INSERT INTO reposts (user_id, post_id)
+	VALUES ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok {
+			switch pqErr.Code {
+			case "23505":
+				return ErrDuplicateKey
+			case "23503":
+				return ErrNotFound
+			}
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *RepostStore) UnRepost(ctx context.Context, postID, userID int64) error {
+	query := `
+	DELETE FROM reposts
+	WHERE user_id = $1 AND post_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, postID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
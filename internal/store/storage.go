@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
@@ -24,35 +25,149 @@ type Storage struct {
 		Delete(context.Context, int64) error
 		Update(context.Context, *models.Post) error
 		GetUserFeed(context.Context, int64, utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error)
+		GetUserPosts(ctx context.Context, userID, viewerID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error)
+		GetPublicFeed(ctx context.Context, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error)
+		GetUserPublicPosts(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error)
+		GetRankingCandidates(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery, asOf time.Time, window time.Duration, limit int) ([]models.RankCandidate, error)
+		GetRecentActivity(ctx context.Context, userIDs []int64, since, until *time.Time, limit int) ([]models.TimelineEntry, error)
+		GetTagActivity(ctx context.Context, tags []string, since, until *time.Time, limit int) ([]models.TimelineEntry, error)
+		GetFeedItems(ctx context.Context, viewerID int64, postIDs []int64) ([]models.PostWithMetadata, error)
 	}
 	Users interface {
 		Create(context.Context, *sql.Tx, *models.User) error
 		GetByID(context.Context, int64) (*models.User, error)
 		GetByEmail(context.Context, string) (*models.User, error)
-		CreateAndInvite(context.Context, *models.User, string, time.Duration) error
+		GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error)
+		CreateAndInvite(context.Context, *models.User, string, time.Duration, *models.OutboxMail) error
 		Activate(context.Context, string) error
 		Delete(context.Context, int64) error
+		SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error
+		SetAvatar(ctx context.Context, userID int64, avatar models.ImageVariants) error
+		SetBanner(ctx context.Context, userID int64, banner models.ImageVariants) error
 	}
 	Comments interface {
 		Create(context.Context, *models.Comment) error
-		GetByPostID(context.Context, int64) ([]models.Comment, error)
+		GetByID(context.Context, int64) (*models.Comment, error)
+		GetByPostID(context.Context, int64, utils.PaginatedCommentQuery) ([]models.Comment, error)
+		GetReplies(context.Context, int64, utils.PaginatedCommentQuery) ([]models.Comment, error)
+		Update(context.Context, *models.Comment) error
+		Delete(context.Context, int64) error
 	}
 	Followers interface {
 		Follow(ctx context.Context, followerID, userID int64) error
 		UnFollow(ctx context.Context, unfollowedID, userID int64) error
+		GetFollowerIDs(ctx context.Context, userID int64) ([]int64, error)
+		GetFolloweeIDs(ctx context.Context, followerID int64) ([]int64, error)
+		GetHeavyFolloweeIDs(ctx context.Context, followerID int64, minFollowers int) ([]int64, error)
+		IsFollowing(ctx context.Context, followerID, userID int64) (bool, error)
 	}
 	Roles interface {
 		GetByName(ctx context.Context, roleName string) (*models.Role, error)
 	}
+	Reposts interface {
+		Repost(ctx context.Context, postID, userID int64) error
+		UnRepost(ctx context.Context, postID, userID int64) error
+	}
+	Mentions interface {
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]models.MentionOfUser, error)
+	}
+	Notifications interface {
+		Create(ctx context.Context, n *models.Notification) error
+		GetByUserID(ctx context.Context, userID int64, unreadOnly bool, limit, offset int) ([]models.Notification, error)
+		CountUnread(ctx context.Context, userID int64) (int, error)
+		MarkRead(ctx context.Context, userID, notificationID int64) error
+		MarkAllRead(ctx context.Context, userID int64) error
+		GetPreferences(ctx context.Context, userID int64) (map[string]bool, error)
+		SetPreferences(ctx context.Context, userID int64, prefs map[string]bool) error
+	}
+	Digests interface {
+		GetSettings(ctx context.Context, userID int64) (*models.DigestSettings, error)
+		SetFrequency(ctx context.Context, userID int64, frequency string) error
+		GetDue(ctx context.Context, frequency string, periodStart time.Time, lease time.Duration, afterID int64, limit int) ([]models.User, error)
+		Claim(ctx context.Context, userID int64, frequency string, periodStart time.Time, lease time.Duration) (bool, error)
+		Finish(ctx context.Context, userID int64, frequency string, periodStart time.Time, status string) error
+		Release(ctx context.Context, userID int64, frequency string, periodStart time.Time) error
+		Get(ctx context.Context, userID int64, since, until time.Time) (*models.Digest, error)
+	}
+	Webhooks interface {
+		Create(ctx context.Context, w *models.Webhook) error
+		GetByID(ctx context.Context, webhookID int64) (*models.Webhook, error)
+		GetByUserID(ctx context.Context, userID int64) ([]models.Webhook, error)
+		Update(ctx context.Context, w *models.Webhook) error
+		Delete(ctx context.Context, webhookID int64) error
+		Enqueue(ctx context.Context, event string, userIDs []int64, isPublic bool, payload []byte) error
+		ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error)
+		RecordAttempt(ctx context.Context, a *models.WebhookAttempt, status string, nextAttemptAt time.Time) error
+		GetDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) ([]models.WebhookDelivery, error)
+		Redeliver(ctx context.Context, webhookID, deliveryID int64) (*models.WebhookDelivery, error)
+	}
+	Outbox interface {
+		ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.OutboxMail, error)
+		Finish(ctx context.Context, mailID int64, status, lastError string) error
+		Retry(ctx context.Context, mailID int64, lastError string, nextAttemptAt time.Time) error
+	}
+	Jobs interface {
+		Enqueue(ctx context.Context, j *models.Job) error
+		ClaimDue(ctx context.Context, kinds []string, limit int, lease time.Duration) ([]models.Job, error)
+		Finish(ctx context.Context, jobID int64, status, lastError string) error
+		Retry(ctx context.Context, jobID int64, lastError string, runAt time.Time) error
+		Prune(ctx context.Context, before time.Time) (int64, error)
+		CountPending(ctx context.Context) (map[string]int, error)
+	}
+	Media interface {
+		Create(ctx context.Context, m *models.Media) error
+	}
+	Blocks interface {
+		Block(ctx context.Context, blockerID, blockedID int64) error
+		UnBlock(ctx context.Context, blockerID, blockedID int64) error
+		GetBlockedBetween(ctx context.Context, userID int64, otherIDs []int64) ([]int64, error)
+	}
+	Conversations interface {
+		Create(ctx context.Context, conversation *models.Conversation, memberIDs []int64) error
+		GetByID(ctx context.Context, conversationID int64) (*models.Conversation, error)
+		GetDirect(ctx context.Context, userID, otherID int64) (*models.Conversation, error)
+		GetByUserID(ctx context.Context, userID int64, limit, offset int) ([]models.Conversation, error)
+		MarkRead(ctx context.Context, conversationID, userID, messageID int64) error
+	}
+	Messages interface {
+		Create(ctx context.Context, message *models.Message) error
+		GetByConversationID(ctx context.Context, conversationID int64, q utils.PaginatedMessageQuery) ([]models.Message, error)
+	}
+	Tags interface {
+		Follow(ctx context.Context, userID int64, tag string) error
+		UnFollow(ctx context.Context, userID int64, tag string) error
+		GetFollowedTags(ctx context.Context, userID int64) ([]string, error)
+	}
+	Trends interface {
+		CountTags(ctx context.Context, since, until time.Time, minAuthors int) ([]models.TagActivity, error)
+		CountEngagement(ctx context.Context, since, until time.Time, minEngagers int) ([]models.PostActivity, error)
+		ReplaceTags(ctx context.Context, period string, tags []models.TrendingTag) error
+		ReplacePosts(ctx context.Context, period string, posts []models.TrendingPost) error
+		GetTags(ctx context.Context, period string, limit int) ([]models.TrendingTag, error)
+		GetPosts(ctx context.Context, period string, limit int) ([]models.PostWithMetadata, error)
+	}
 }
 
 func NewStorage(db *sql.DB) Storage {
 	return Storage{
-		Posts:     &PostStore{db},
-		Users:     &UserStore{db},
-		Comments:  &CommentStore{db},
-		Followers: &FollowerStore{db},
-		Roles:     &RoleStore{db},
+		Posts:         &PostStore{db},
+		Users:         &UserStore{db},
+		Comments:      &CommentStore{db},
+		Followers:     &FollowerStore{db},
+		Roles:         &RoleStore{db},
+		Reposts:       &RepostStore{db},
+		Tags:          &TagStore{db},
+		Mentions:      &MentionStore{db},
+		Notifications: &NotificationStore{db},
+		Digests:       &DigestStore{db},
+		Webhooks:      &WebhookStore{db},
+		Outbox:        &OutboxStore{db},
+		Jobs:          &JobStore{db},
+		Media:         &MediaStore{db},
+		Blocks:        &BlockStore{db},
+		Conversations: &ConversationStore{db},
+		Messages:      &MessageStore{db},
+		Trends:        &TrendStore{db},
 	}
 }
 
@@ -69,3 +184,21 @@ func withTx(db *sql.DB, ctx context.Context, fn func(*sql.Tx) error) error {
 
 	return tx.Commit()
 }
+
+// keyset builds the predicate and ordering to fetch a page of rows sorted by
+// (timeCol, idCol), starting after the given cursor. The cursor values are
+// bound to the placeholders $argPos and $argPos+1 of the args it returns.
+// When reversed is true the rows come back in the opposite order and must be
+// flipped by the caller.
+func keyset(c *utils.Cursor, sort, timeCol, idCol string, argPos int) (where, orderBy string, args []any, reversed bool) {
+	if c == nil {
+		return "TRUE", fmt.Sprintf("%s %s, %s %s", timeCol, sort, idCol, sort), nil, false
+	}
+
+	cmp, order, reversed := c.Keyset(sort)
+
+	where = fmt.Sprintf("(%s, %s) %s ($%d, $%d)", timeCol, idCol, cmp, argPos, argPos+1)
+	orderBy = fmt.Sprintf("%s %s, %s %s", timeCol, order, idCol, order)
+
+	return where, orderBy, []any{c.CreatedAt, c.ID}, reversed
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+)
+
+type TagStore struct {
+	db *sql.DB
+}
+
+func (s *TagStore) Follow(ctx context.Context, userID int64, tag string) error {
+	query := `
+	INSERT INTO tag_follows (user_id, tag)
+	VALUES ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tag)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "23505" {
+			return ErrDuplicateKey
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *TagStore) UnFollow(ctx context.Context, userID int64, tag string) error {
+	query := `
+	DELETE FROM tag_follows
+	WHERE user_id = $1 AND tag = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, userID, tag)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFollowedTags returns the tags userID follows.
+func (s *TagStore) GetFollowedTags(ctx context.Context, userID int64) ([]string, error) {
+	query := `SELECT tag FROM tag_follows WHERE user_id = $1 ORDER BY tag`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags, rows.Err()
+}
@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+)
+
+type TrendStore struct {
+	db *sql.DB
+}
+
+// CountTags returns how many posts used each tag in [since, until), and how
+// many distinct users authored them, leaving out the tags used by less than
+// minAuthors users.
+func (s *TrendStore) CountTags(ctx context.Context, since, until time.Time, minAuthors int) ([]models.TagActivity, error) {
+	query := `
+		SELECT tag, COUNT(*) AS posts, COUNT(DISTINCT p.user_id) AS authors
+		FROM posts p, UNNEST(p.tags) AS tag
+		WHERE p.created_at >= $1 AND p.created_at < $2
+			AND p.visibility = '` + models.VisibilityPublic + `'
+		GROUP BY tag
+		HAVING COUNT(DISTINCT p.user_id) >= $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, since, until, minAuthors)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tags []models.TagActivity
+	for rows.Next() {
+		var t models.TagActivity
+		if err := rows.Scan(&t.Tag, &t.Posts, &t.Authors); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// CountEngagement returns how many distinct users commented on or reposted
+// each post in [since, until), leaving out the posts' authors and the posts
+// engaged with by less than minEngagers users.
+func (s *TrendStore) CountEngagement(ctx context.Context, since, until time.Time, minEngagers int) ([]models.PostActivity, error) {
+	query := `
+		WITH engagements AS (
+			SELECT post_id, user_id FROM comments
+			WHERE created_at >= $1 AND created_at < $2
+			UNION
+			SELECT post_id, user_id FROM reposts
+			WHERE created_at >= $1 AND created_at < $2
+		)
+		SELECT e.post_id, COUNT(DISTINCT e.user_id) AS engagers
+		FROM engagements e
+		JOIN posts p ON p.id = e.post_id
+		WHERE e.user_id <> p.user_id AND p.visibility = '` + models.VisibilityPublic + `'
+		GROUP BY e.post_id
+		HAVING COUNT(DISTINCT e.user_id) >= $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, since, until, minEngagers)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []models.PostActivity
+	for rows.Next() {
+		var p models.PostActivity
+		if err := rows.Scan(&p.PostID, &p.Engagers); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+// ReplaceTags swaps the trending tags of a period for the given ones.
+func (s *TrendStore) ReplaceTags(ctx context.Context, period string, tags []models.TrendingTag) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_tags WHERE period = $1`, period); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO trending_tags (period, tag, posts, authors, velocity, score)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`
+
+		for _, t := range tags {
+			_, err := tx.ExecContext(ctx, query, period, t.Tag, t.Posts, t.Authors, t.Velocity, t.Score)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// ReplacePosts swaps the trending posts of a period for the given ones.
+func (s *TrendStore) ReplacePosts(ctx context.Context, period string, posts []models.TrendingPost) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		if _, err := tx.ExecContext(ctx, `DELETE FROM trending_posts WHERE period = $1`, period); err != nil {
+			return err
+		}
+
+		query := `
+			INSERT INTO trending_posts (period, post_id, engagers, velocity, score)
+			VALUES ($1, $2, $3, $4, $5)
+		`
+
+		for _, p := range posts {
+			_, err := tx.ExecContext(ctx, query, period, p.PostID, p.Engagers, p.Velocity, p.Score)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// GetTags returns the trending tags of a period, hottest first.
+func (s *TrendStore) GetTags(ctx context.Context, period string, limit int) ([]models.TrendingTag, error) {
+	query := `
+		SELECT tag, posts, authors, velocity, score
+		FROM trending_tags
+		WHERE period = $1
+		ORDER BY score DESC, tag
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, period, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []models.TrendingTag{}
+	for rows.Next() {
+		var t models.TrendingTag
+		if err := rows.Scan(&t.Tag, &t.Posts, &t.Authors, &t.Velocity, &t.Score); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, t)
+	}
+
+	return tags, rows.Err()
+}
+
+// GetPosts returns the trending posts of a period, hottest first, with their
+// score.
+func (s *TrendStore) GetPosts(ctx context.Context, period string, limit int) ([]models.PostWithMetadata, error) {
+	query := `
+		SELECT
+			p.id, p.title, p.content, p.tags, p.quoted_post_id,
+			p.user_id, p.created_at, p.updated_at, p.version, p.visibility, u.username, u.avatar, ` + attachmentsColumn + `, ` + postMentionsColumn + `,
+			(SELECT COUNT(*) FROM comments c WHERE c.post_id = p.id) AS comments_count,
+			(SELECT COUNT(*) FROM reposts r WHERE r.post_id = p.id) AS reposts_count,
+			NULL, NULL, NULL, p.created_at, NULL, tp.score
+		FROM trending_posts tp
+		JOIN posts p ON p.id = tp.post_id
+		JOIN users u ON u.id = p.user_id
+		WHERE tp.period = $1 AND ` + isPublic + `
+		ORDER BY tp.score DESC, p.id DESC
+		LIMIT $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, period, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	posts := []models.PostWithMetadata{}
+	for rows.Next() {
+		var p models.PostWithMetadata
+		if err := scanFeedItem(rows, &p, &p.Score); err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
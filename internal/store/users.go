@@ -5,10 +5,13 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"time"
 
 	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
 )
 
 var (
@@ -65,7 +68,7 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*models.User, er
 	var user models.User
 
 	query := `
-		SELECT users.id, first_name, last_name, username, email, created_at, roles.*
+		SELECT users.id, first_name, last_name, username, email, created_at, is_private, avatar, banner, roles.*
 		FROM users
 		JOIN roles ON roles.id = users.role_id
 		WHERE users.id = $1 AND is_active = true
@@ -76,7 +79,8 @@ func (s *UserStore) GetByID(ctx context.Context, userID int64) (*models.User, er
 
 	err := s.db.QueryRowContext(ctx, query, userID).Scan(
 		&user.ID, &user.FirstName, &user.LastName,
-		&user.Username, &user.Email, &user.CreatedAt,
+		&user.Username, &user.Email, &user.CreatedAt, &user.IsPrivate,
+		(*imageVariants)(&user.Avatar), (*imageVariants)(&user.Banner),
 		&user.Role.ID, &user.Role.Name, &user.Role.Level, &user.Role.Description,
 	)
 
@@ -119,7 +123,36 @@ func (s *UserStore) GetByEmail(ctx context.Context, email string) (*models.User,
 	return &user, nil
 }
 
-func (s *UserStore) CreateAndInvite(ctx context.Context, user *models.User, token string, invitationExp time.Duration) error {
+// GetByUsernames returns the active users among usernames, with only their
+// ID and username.
+func (s *UserStore) GetByUsernames(ctx context.Context, usernames []string) ([]models.User, error) {
+	query := `SELECT id, username FROM users WHERE username = ANY($1) AND is_active = true`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(usernames))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		if err := rows.Scan(&user.ID, &user.Username); err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// CreateAndInvite creates a user with its invitation and queues the mail
+// inviting them, all at once.
+func (s *UserStore) CreateAndInvite(ctx context.Context, user *models.User, token string, invitationExp time.Duration, invite *models.OutboxMail) error {
 	return withTx(s.db, ctx, func(tx *sql.Tx) error {
 		// create the user
 		if err := s.Create(ctx, tx, user); err != nil {
@@ -131,6 +164,11 @@ func (s *UserStore) CreateAndInvite(ctx context.Context, user *models.User, toke
 			return err
 		}
 
+		// queue the invitation mail
+		if err := enqueueMail(ctx, tx, invite); err != nil {
+			return err
+		}
+
 		return nil
 	})
 }
@@ -172,6 +210,71 @@ func (s *UserStore) Delete(ctx context.Context, userID int64) error {
 	})
 }
 
+// SetPrivacy makes a user's account private or public.
+func (s *UserStore) SetPrivacy(ctx context.Context, userID int64, isPrivate bool) error {
+	query := `UPDATE users SET is_private = $1 WHERE id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, isPrivate, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// SetAvatar replaces the avatar of a user, nil removing it.
+func (s *UserStore) SetAvatar(ctx context.Context, userID int64, avatar models.ImageVariants) error {
+	return s.setImage(ctx, `UPDATE users SET avatar = $1 WHERE id = $2`, userID, avatar)
+}
+
+// SetBanner replaces the profile banner of a user, nil removing it.
+func (s *UserStore) SetBanner(ctx context.Context, userID int64, banner models.ImageVariants) error {
+	return s.setImage(ctx, `UPDATE users SET banner = $1 WHERE id = $2`, userID, banner)
+}
+
+func (s *UserStore) setImage(ctx context.Context, query string, userID int64, image models.ImageVariants) error {
+	// a string, pq would send bytes as bytea
+	var data sql.NullString
+	if image != nil {
+		b, err := json.Marshal(image)
+		if err != nil {
+			return err
+		}
+		data = sql.NullString{String: string(b), Valid: true}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, data, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
 func (s *UserStore) createUserInvitation(ctx context.Context, tx *sql.Tx, token string, expiry time.Duration, userID int64) error {
 	query := `
 	INSERT INTO user_invitations (token, user_id, expiry)
@@ -264,11 +367,21 @@ func (s *UserStore) deleteUserInvitations(ctx context.Context, tx *sql.Tx, userI
 }
 
 func (s *UserStore) delete(ctx context.Context, tx *sql.Tx, userID int64) error {
+	// the follows of the user are dropped along with it, by cascade
+	unfollowQuery := `
+	UPDATE users
+	SET follower_count = follower_count - 1
+	WHERE id IN (SELECT user_id FROM followers WHERE follower_id = $1)
+	`
 	query := `DELETE FROM users WHERE id = $1`
 
 	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
 	defer cancel()
 
+	if _, err := tx.ExecContext(ctx, unfollowQuery, userID); err != nil {
+		return err
+	}
+
 	_, err := tx.ExecContext(ctx, query, userID)
 	if err != nil {
 		return err
@@ -0,0 +1,319 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+
+	"github.com/lib/pq"
+)
+
+type WebhookStore struct {
+	db *sql.DB
+}
+
+const webhookColumns = `id, user_id, is_app, url, events, is_active, created_at, updated_at`
+
+func (s *WebhookStore) Create(ctx context.Context, w *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (user_id, is_app, url, secret, events)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, is_active, created_at, updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	return s.db.QueryRowContext(
+		ctx, query, w.UserID, w.IsApp, w.URL, w.Secret, pq.Array(w.Events),
+	).Scan(&w.ID, &w.IsActive, &w.CreatedAt, &w.UpdatedAt)
+}
+
+func (s *WebhookStore) GetByID(ctx context.Context, webhookID int64) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	var w models.Webhook
+	err := s.db.QueryRowContext(ctx, query, webhookID).Scan(
+		&w.ID, &w.UserID, &w.IsApp, &w.URL, pq.Array(&w.Events), &w.IsActive, &w.CreatedAt, &w.UpdatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &w, nil
+}
+
+func (s *WebhookStore) GetByUserID(ctx context.Context, userID int64) ([]models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE user_id = $1 ORDER BY id`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var w models.Webhook
+		err := rows.Scan(
+			&w.ID, &w.UserID, &w.IsApp, &w.URL, pq.Array(&w.Events), &w.IsActive, &w.CreatedAt, &w.UpdatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		webhooks = append(webhooks, w)
+	}
+
+	return webhooks, rows.Err()
+}
+
+func (s *WebhookStore) Update(ctx context.Context, w *models.Webhook) error {
+	query := `
+		UPDATE webhooks
+		SET url = $1, events = $2, is_active = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING updated_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, query, w.URL, pq.Array(w.Events), w.IsActive, w.ID).Scan(&w.UpdatedAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *WebhookStore) Delete(ctx context.Context, webhookID int64) error {
+	query := `DELETE FROM webhooks WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, webhookID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rows == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Enqueue queues an event for the active webhooks subscribed to it that
+// belong to one of userIDs, and for the app webhooks when the event is
+// public.
+func (s *WebhookStore) Enqueue(ctx context.Context, event string, userIDs []int64, isPublic bool, payload []byte) error {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT w.id, $1::VARCHAR, $2::JSONB
+		FROM webhooks w
+		WHERE w.is_active AND $1 = ANY(w.events)
+			AND (w.user_id = ANY($3::INTEGER[]) OR (w.is_app AND $4::BOOLEAN))
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, query, event, string(payload), pq.Array(userIDs), isPublic)
+	return err
+}
+
+// ClaimDue takes up to limit pending deliveries that are due, leaving the
+// ones other instances are claiming, and pushes them back by lease. A
+// delivery whose attempt is never recorded, because the instance stopped,
+// is due again once the lease is over.
+func (s *WebhookStore) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = NOW() + make_interval(secs => $2)
+		FROM webhooks w
+		WHERE w.id = d.webhook_id AND d.id IN (
+			SELECT dd.id
+			FROM webhook_deliveries dd
+			JOIN webhooks ww ON ww.id = dd.webhook_id
+			WHERE dd.status = '` + models.WebhookPending + `' AND dd.next_attempt_at <= NOW() AND ww.is_active
+			ORDER BY dd.next_attempt_at
+			LIMIT $1
+			FOR UPDATE OF dd SKIP LOCKED
+		)
+		RETURNING d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.created_at, w.url, w.secret
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []models.WebhookDelivery
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.Event, (*[]byte)(&d.Payload), &d.Status, &d.Attempts, &d.CreatedAt, &d.URL, &d.Secret,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt logs an attempt at a delivery and moves it to status, to be
+// tried again at nextAttemptAt if it is still pending.
+func (s *WebhookStore) RecordAttempt(ctx context.Context, a *models.WebhookAttempt, status string, nextAttemptAt time.Time) error {
+	return withTx(s.db, ctx, func(tx *sql.Tx) error {
+		ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+		defer cancel()
+
+		err := tx.QueryRowContext(ctx, `
+			INSERT INTO webhook_attempts (delivery_id, status_code, error, duration_ms)
+			VALUES ($1, $2, $3, $4)
+			RETURNING id, created_at
+		`, a.DeliveryID, a.StatusCode, a.Error, a.DurationMs).Scan(&a.ID, &a.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET
+				attempts = attempts + 1,
+				status = $2,
+				next_attempt_at = $3,
+				delivered_at = CASE WHEN $2 = '`+models.WebhookDelivered+`' THEN NOW() END
+			WHERE id = $1
+		`, a.DeliveryID, status, nextAttemptAt)
+
+		return err
+	})
+}
+
+// GetDeliveries returns a page of the deliveries of a webhook, the latest
+// first, only the ones with the given status unless it is empty.
+func (s *WebhookStore) GetDeliveries(ctx context.Context, webhookID int64, status string, limit, offset int) ([]models.WebhookDelivery, error) {
+	query := `
+		SELECT
+			d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts,
+			CASE WHEN d.status = '` + models.WebhookPending + `' THEN d.next_attempt_at END,
+			d.delivered_at, d.created_at,
+			(
+				SELECT COALESCE(json_agg(json_build_object(
+					'id', a.id, 'statusCode', a.status_code, 'error', a.error,
+					'durationMs', a.duration_ms, 'createdAt', a.created_at
+				) ORDER BY a.id DESC), '[]')
+				FROM webhook_attempts a
+				WHERE a.delivery_id = d.id
+			)
+		FROM webhook_deliveries d
+		WHERE d.webhook_id = $1 AND ($4 = '' OR d.status = $4)
+		ORDER BY d.created_at DESC, d.id DESC
+		LIMIT $2
+		OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, webhookID, limit, offset, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var d models.WebhookDelivery
+		err := rows.Scan(
+			&d.ID, &d.WebhookID, &d.Event, (*[]byte)(&d.Payload), &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt,
+			(*attemptList)(&d.AttemptLog),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Redeliver queues the payload of a past delivery of a webhook again, as a
+// new delivery.
+func (s *WebhookStore) Redeliver(ctx context.Context, webhookID, deliveryID int64) (*models.WebhookDelivery, error) {
+	query := `
+		INSERT INTO webhook_deliveries (webhook_id, event, payload)
+		SELECT webhook_id, event, payload
+		FROM webhook_deliveries
+		WHERE id = $1 AND webhook_id = $2
+		RETURNING id, webhook_id, event, payload, status, attempts, next_attempt_at, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, QueryTimeoutDuration)
+	defer cancel()
+
+	d := models.WebhookDelivery{AttemptLog: []models.WebhookAttempt{}}
+	err := s.db.QueryRowContext(ctx, query, deliveryID, webhookID).Scan(
+		&d.ID, &d.WebhookID, &d.Event, (*[]byte)(&d.Payload), &d.Status, &d.Attempts, &d.NextAttemptAt, &d.CreatedAt,
+	)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &d, nil
+}
+
+// attemptList scans a JSON array of webhook attempts.
+type attemptList []models.WebhookAttempt
+
+func (l *attemptList) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into webhook attempts", src)
+	}
+
+	return json.Unmarshal(data, l)
+}
@@ -0,0 +1,90 @@
+package syndication
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testFeed() Feed {
+	at := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	return Feed{
+		ID:      "https://example.com/users/1",
+		Title:   "Posts by <gopher> & co",
+		Link:    "https://example.com/users/1",
+		Self:    "https://api.example.com/v1/users/1/posts.atom",
+		Updated: at,
+		Entries: []Entry{
+			{
+				ID:        "https://example.com/posts/1",
+				Title:     `"Quotes" & <tags>`,
+				Link:      "https://example.com/posts/1",
+				Author:    "gopher",
+				Content:   "<script>alert(1)</script> & \x00 friends",
+				Tags:      []string{"go"},
+				Published: at,
+				Updated:   at,
+			},
+		},
+	}
+}
+
+func TestAtomEscapesContent(t *testing.T) {
+	doc, err := Atom(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(doc), "<script>") {
+		t.Errorf("content was not escaped:\n%s", doc)
+	}
+
+	var parsed struct {
+		Title   string `xml:"title"`
+		Entries []struct {
+			Title   string `xml:"title"`
+			Content string `xml:"content"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(doc, &parsed); err != nil {
+		t.Fatalf("invalid document: %v\n%s", err, doc)
+	}
+
+	if parsed.Title != "Posts by <gopher> & co" {
+		t.Errorf("title = %q", parsed.Title)
+	}
+
+	if len(parsed.Entries) != 1 || parsed.Entries[0].Title != `"Quotes" & <tags>` {
+		t.Errorf("entries = %+v", parsed.Entries)
+	}
+
+	if !strings.HasPrefix(parsed.Entries[0].Content, "<script>alert(1)</script> & ") {
+		t.Errorf("content = %q", parsed.Entries[0].Content)
+	}
+}
+
+func TestRSSDoubleEscapesDescription(t *testing.T) {
+	doc, err := RSS(testFeed())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Channel struct {
+			Items []struct {
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	if err := xml.Unmarshal(doc, &parsed); err != nil {
+		t.Fatalf("invalid document: %v\n%s", err, doc)
+	}
+
+	// descriptions are HTML, the markup of the post must stay text
+	got := parsed.Channel.Items[0].Description
+	if !strings.HasPrefix(got, "&lt;script&gt;alert(1)&lt;/script&gt; &amp; ") {
+		t.Errorf("description = %q", got)
+	}
+}
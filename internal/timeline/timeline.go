@@ -0,0 +1,405 @@
+package timeline
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+)
+
+type Config struct {
+	IsEnabled bool
+	// MaxLength is the number of entries kept on each home timeline.
+	MaxLength int
+	// FanoutThreshold is the number of followers above which an author's
+	// posts are no longer pushed to their followers' timelines, but merged
+	// into them when they are read.
+	FanoutThreshold int
+	// IncludeOwnPosts pushes the posts of a user to their own timeline too.
+	IncludeOwnPosts bool
+}
+
+// Service keeps a home timeline per user in the cache: the posts of the
+// users they follow are pushed onto it as they are written (fan-out on
+// write), except for the posts of authors with huge follower counts which
+// are looked up as the timeline is read (fan-out on read).
+type Service struct {
+	store  store.Storage
+	cache  cache.Storage
+	config Config
+}
+
+func NewService(store store.Storage, cache cache.Storage, config Config) *Service {
+	return &Service{
+		store:  store,
+		cache:  cache,
+		config: config,
+	}
+}
+
+func (s *Service) IsEnabled() bool {
+	return s != nil && s.config.IsEnabled
+}
+
+// OnPostCreated pushes a new post to the timelines of its author's followers,
+// and to the author's own if IncludeOwnPosts is set. Private posts only go to
+// the author's.
+func (s *Service) OnPostCreated(ctx context.Context, post *models.Post) error {
+	entry := models.TimelineEntry{PostID: post.ID, ActivityAt: post.CreatedAt}
+
+	if post.Visibility == models.VisibilityPrivate {
+		if !s.IsEnabled() || !s.config.IncludeOwnPosts {
+			return nil
+		}
+
+		return s.cache.Timelines.Push(ctx, []int64{post.UserID}, []models.TimelineEntry{entry}, s.config.MaxLength)
+	}
+
+	return s.fanOut(ctx, post.UserID, entry, s.config.IncludeOwnPosts)
+}
+
+// OnPostDeleted takes a deleted post off the timelines of its author's
+// followers. Timelines it reached through reposts drop it when hydrated.
+func (s *Service) OnPostDeleted(ctx context.Context, post *models.Post) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	followerIDs, err := s.store.Followers.GetFollowerIDs(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+
+	if s.config.IncludeOwnPosts {
+		followerIDs = append(followerIDs, post.UserID)
+	}
+
+	return s.cache.Timelines.Remove(ctx, followerIDs, []int64{post.ID})
+}
+
+// OnRepost pushes a reposted post to the timelines of the reposter's
+// followers, moving it up if it is already there.
+func (s *Service) OnRepost(ctx context.Context, userID, postID int64, repostedAt time.Time) error {
+	entry := models.TimelineEntry{PostID: postID, ActivityAt: repostedAt}
+
+	return s.fanOut(ctx, userID, entry, false)
+}
+
+// OnUnrepost takes a post off the timelines of the reposter's followers,
+// unless they follow its author too.
+func (s *Service) OnUnrepost(ctx context.Context, userID int64, post *models.Post) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	followerIDs, err := s.store.Followers.GetFollowerIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	authorFollowerIDs, err := s.store.Followers.GetFollowerIDs(ctx, post.UserID)
+	if err != nil {
+		return err
+	}
+
+	followerIDs = slices.DeleteFunc(followerIDs, func(id int64) bool {
+		return slices.Contains(authorFollowerIDs, id) || id == post.UserID
+	})
+
+	return s.cache.Timelines.Remove(ctx, followerIDs, []int64{post.ID})
+}
+
+// OnFollow backfills the follower's timeline with the recent activity of the
+// user they just followed, if it is built. Otherwise it is built with it on
+// its next read.
+func (s *Service) OnFollow(ctx context.Context, followerID, userID int64) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	entries, err := s.store.Posts.GetRecentActivity(ctx, []int64{userID}, nil, nil, s.config.MaxLength)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Timelines.Push(ctx, []int64{followerID}, entries, s.config.MaxLength)
+}
+
+// OnUnfollow purges the recent activity of the unfollowed user from the
+// follower's timeline.
+func (s *Service) OnUnfollow(ctx context.Context, followerID, userID int64) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	entries, err := s.store.Posts.GetRecentActivity(ctx, []int64{userID}, nil, nil, s.config.MaxLength)
+	if err != nil {
+		return err
+	}
+
+	postIDs := make([]int64, len(entries))
+	for i, e := range entries {
+		postIDs[i] = e.PostID
+	}
+
+	return s.cache.Timelines.Remove(ctx, []int64{followerID}, postIDs)
+}
+
+// CanServe reports whether a feed query can be answered from the timeline,
+// which only holds the most recent posts without their content: filtering
+// by content, ascending order and going back to previous pages are left to
+// the database.
+func (s *Service) CanServe(fq utils.PaginatedFeedQuery) bool {
+	return s.IsEnabled() &&
+		fq.Sort == "desc" &&
+		fq.Search == "" &&
+		len(fq.Tags) == 0 &&
+		(fq.Keyset == nil || fq.Keyset.Direction == utils.CursorNext) &&
+		fq.Offset+fq.Limit <= s.config.MaxLength
+}
+
+// Feed returns a page of the user's home timeline, see CanServe for the
+// queries it supports. The page is refilled past the posts deleted or hidden
+// since they were pushed, and completed from the database once the timeline,
+// which only holds its MaxLength most recent entries, runs out.
+func (s *Service) Feed(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.PostWithMetadata, error) {
+	if err := s.ensureBuilt(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	feed := make([]models.PostWithMetadata, 0, fq.Limit)
+
+	q := fq
+	for {
+		entries, exhausted, err := s.entries(ctx, userID, q)
+		if err != nil {
+			return nil, err
+		}
+
+		items, err := s.hydrate(ctx, userID, entries)
+		if err != nil {
+			return nil, err
+		}
+
+		feed = append(feed, items...)
+		if len(feed) >= fq.Limit {
+			return feed[:fq.Limit], nil
+		}
+
+		q.Limit = fq.Limit - len(feed)
+		if len(entries) == 0 {
+			break
+		}
+
+		// the rest of the page picks up after the last entry, posts or not
+		last := entries[len(entries)-1]
+
+		q.Keyset = &utils.Cursor{CreatedAt: last.ActivityAt, ID: last.PostID, Direction: utils.CursorNext}
+		q.Offset = 0
+
+		if exhausted {
+			break
+		}
+	}
+
+	rest, err := s.store.Posts.GetUserFeed(ctx, userID, q)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(feed, rest...), nil
+}
+
+// entries returns the entries of a page of the user's home timeline, merged
+// with the posts pulled from heavy followees and followed tags, and whether
+// the timeline ran out before it.
+func (s *Service) entries(ctx context.Context, userID int64, fq utils.PaginatedFeedQuery) ([]models.TimelineEntry, bool, error) {
+	until := fq.Until
+	if fq.Keyset != nil && (until == nil || fq.Keyset.CreatedAt.Before(*until)) {
+		until = &fq.Keyset.CreatedAt
+	}
+
+	// extra entries are fetched as the bounds are inclusive while the
+	// cursor and until are not
+	want := fq.Offset + fq.Limit
+	fetch := want + fq.Limit
+
+	entries, err := s.cache.Timelines.Get(ctx, userID, fq.Since, until, fetch)
+	if err != nil {
+		return nil, false, err
+	}
+
+	exhausted := len(entries) < fetch
+
+	heavyIDs, err := s.store.Followers.GetHeavyFolloweeIDs(ctx, userID, s.config.FanoutThreshold)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(heavyIDs) > 0 {
+		pulled, err := s.store.Posts.GetRecentActivity(ctx, heavyIDs, fq.Since, until, fetch)
+		if err != nil {
+			return nil, false, err
+		}
+
+		entries = append(entries, pulled...)
+	}
+
+	tags, err := s.store.Tags.GetFollowedTags(ctx, userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(tags) > 0 {
+		pulled, err := s.store.Posts.GetTagActivity(ctx, tags, fq.Since, until, fetch)
+		if err != nil {
+			return nil, false, err
+		}
+
+		entries = append(entries, pulled...)
+	}
+
+	return page(entries, fq), exhausted, nil
+}
+
+// hydrate returns the posts of the entries, skipping those deleted or hidden
+// from the user since they were pushed.
+func (s *Service) hydrate(ctx context.Context, userID int64, entries []models.TimelineEntry) ([]models.PostWithMetadata, error) {
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	postIDs := make([]int64, len(entries))
+	for i, e := range entries {
+		postIDs[i] = e.PostID
+	}
+
+	items, err := s.store.Posts.GetFeedItems(ctx, userID, postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[int64]models.PostWithMetadata, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+	}
+
+	feed := make([]models.PostWithMetadata, 0, len(entries))
+	for _, e := range entries {
+		item, ok := byID[e.PostID]
+		if !ok {
+			continue
+		}
+
+		item.ActivityAt = e.ActivityAt
+		item.Reason = e.Reason
+		if item.Reason == "" {
+			item.Reason = models.FeedReasonFollowedUser
+			if item.UserID == userID {
+				item.Reason = models.FeedReasonOwnPost
+			}
+		}
+
+		feed = append(feed, item)
+	}
+
+	return feed, nil
+}
+
+// page sorts the merged entries newest first, drops duplicates and the
+// entries outside of the requested range, and returns the requested page. A
+// post reached through both a followed user and a followed tag at the same
+// time is kept as coming from the user.
+func page(entries []models.TimelineEntry, fq utils.PaginatedFeedQuery) []models.TimelineEntry {
+	slices.SortFunc(entries, func(a, b models.TimelineEntry) int {
+		if c := b.ActivityAt.Compare(a.ActivityAt); c != 0 {
+			return c
+		}
+		if c := cmp.Compare(b.PostID, a.PostID); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Reason, b.Reason)
+	})
+
+	seen := make(map[int64]bool, len(entries))
+
+	entries = slices.DeleteFunc(entries, func(e models.TimelineEntry) bool {
+		if seen[e.PostID] {
+			return true
+		}
+		seen[e.PostID] = true
+
+		if fq.Until != nil && !e.ActivityAt.Before(*fq.Until) {
+			return true
+		}
+
+		if c := fq.Keyset; c != nil {
+			return e.ActivityAt.After(c.CreatedAt) || (e.ActivityAt.Equal(c.CreatedAt) && e.PostID >= c.ID)
+		}
+
+		return false
+	})
+
+	start := min(fq.Offset, len(entries))
+	end := min(fq.Offset+fq.Limit, len(entries))
+
+	return entries[start:end]
+}
+
+// ensureBuilt rebuilds a timeline that was never built or has expired from
+// the recent activity of the users it follows.
+func (s *Service) ensureBuilt(ctx context.Context, userID int64) error {
+	exists, err := s.cache.Timelines.Exists(ctx, userID)
+	if err != nil || exists {
+		return err
+	}
+
+	followeeIDs, err := s.store.Followers.GetFolloweeIDs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.config.IncludeOwnPosts {
+		followeeIDs = append(followeeIDs, userID)
+	}
+
+	if len(followeeIDs) == 0 {
+		return nil
+	}
+
+	entries, err := s.store.Posts.GetRecentActivity(ctx, followeeIDs, nil, nil, s.config.MaxLength)
+	if err != nil {
+		return err
+	}
+
+	return s.cache.Timelines.Build(ctx, userID, entries, s.config.MaxLength)
+}
+
+// fanOut pushes an entry to the timelines of the author's followers, and to
+// the author's own one when toAuthor is set.
+func (s *Service) fanOut(ctx context.Context, authorID int64, entry models.TimelineEntry, toAuthor bool) error {
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	followerIDs, err := s.store.Followers.GetFollowerIDs(ctx, authorID)
+	if err != nil {
+		return err
+	}
+
+	// followers of heavy authors pull their posts when reading instead
+	if len(followerIDs) > s.config.FanoutThreshold {
+		followerIDs = nil
+	}
+
+	if toAuthor {
+		followerIDs = append(followerIDs, authorID)
+	}
+
+	return s.cache.Timelines.Push(ctx, followerIDs, []models.TimelineEntry{entry}, s.config.MaxLength)
+}
@@ -0,0 +1,129 @@
+package timeline
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/store/cache"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPage(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time { return now.Add(-time.Duration(minutes) * time.Minute) }
+
+	entries := func() []models.TimelineEntry {
+		return []models.TimelineEntry{
+			{PostID: 1, ActivityAt: at(30)},
+			{PostID: 2, ActivityAt: at(20), Reason: models.FeedReasonFollowedTag},
+			{PostID: 3, ActivityAt: at(10)},
+			{PostID: 3, ActivityAt: at(10), Reason: models.FeedReasonFollowedTag},
+			{PostID: 4, ActivityAt: at(5), Reason: models.FeedReasonFollowedTag},
+			{PostID: 4, ActivityAt: at(5)},
+		}
+	}
+
+	until := at(10)
+
+	tests := []struct {
+		name string
+		fq   utils.PaginatedFeedQuery
+		want []models.TimelineEntry
+	}{
+		{
+			name: "posts reached through users and tags show up once, as from the users",
+			fq:   utils.PaginatedFeedQuery{Limit: 10},
+			want: []models.TimelineEntry{
+				{PostID: 4, ActivityAt: at(5)},
+				{PostID: 3, ActivityAt: at(10)},
+				{PostID: 2, ActivityAt: at(20), Reason: models.FeedReasonFollowedTag},
+				{PostID: 1, ActivityAt: at(30)},
+			},
+		},
+		{
+			name: "offset and limit",
+			fq:   utils.PaginatedFeedQuery{Limit: 2, Offset: 1},
+			want: []models.TimelineEntry{
+				{PostID: 3, ActivityAt: at(10)},
+				{PostID: 2, ActivityAt: at(20), Reason: models.FeedReasonFollowedTag},
+			},
+		},
+		{
+			name: "after a cursor",
+			fq:   utils.PaginatedFeedQuery{Limit: 10, Keyset: &utils.Cursor{CreatedAt: at(10), ID: 3, Direction: utils.CursorNext}},
+			want: []models.TimelineEntry{
+				{PostID: 2, ActivityAt: at(20), Reason: models.FeedReasonFollowedTag},
+				{PostID: 1, ActivityAt: at(30)},
+			},
+		},
+		{
+			name: "until",
+			fq:   utils.PaginatedFeedQuery{Limit: 10, Until: &until},
+			want: []models.TimelineEntry{
+				{PostID: 2, ActivityAt: at(20), Reason: models.FeedReasonFollowedTag},
+				{PostID: 1, ActivityAt: at(30)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := page(entries(), tt.fq)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("page() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedFollowedTags(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	s := store.NewMockStore()
+	c := cache.NewMockRedisStorage()
+
+	timelines := c.Timelines.(*cache.MockTimelineStore)
+	timelines.On("Exists", int64(1)).Return(true, nil)
+	timelines.On("Get", int64(1), mock.Anything, mock.Anything, mock.Anything).Return([]models.TimelineEntry{
+		{PostID: 2, ActivityAt: now.Add(-time.Minute)},
+	}, nil)
+
+	s.Followers.(*store.MockFollowerStore).On("GetHeavyFolloweeIDs", int64(1), 100).Return(nil, nil)
+	s.Tags.(*store.MockTagStore).On("GetFollowedTags", int64(1)).Return([]string{"go"}, nil)
+
+	posts := s.Posts.(*store.MockPostStore)
+	posts.On("GetTagActivity", []string{"go"}, mock.Anything, mock.Anything, mock.Anything).Return([]models.TimelineEntry{
+		{PostID: 3, ActivityAt: now, Reason: models.FeedReasonFollowedTag},
+		{PostID: 2, ActivityAt: now.Add(-time.Minute), Reason: models.FeedReasonFollowedTag},
+	}, nil)
+	posts.On("GetFeedItems", int64(1), []int64{3, 2}).Return([]models.PostWithMetadata{
+		{Post: models.Post{ID: 2, UserID: 5}},
+		{Post: models.Post{ID: 3, UserID: 6}},
+	}, nil)
+	posts.On("GetUserFeed", int64(1), mock.Anything).Return(nil, nil)
+
+	svc := NewService(s, c, Config{IsEnabled: true, MaxLength: 800, FanoutThreshold: 100})
+
+	feed, err := svc.Feed(ctx, 1, utils.PaginatedFeedQuery{Limit: 10, Sort: "desc"})
+	if err != nil {
+		t.Fatalf("Feed() error = %v", err)
+	}
+
+	var got []string
+	for _, item := range feed {
+		got = append(got, item.Reason)
+	}
+
+	want := []string{models.FeedReasonFollowedTag, models.FeedReasonFollowedUser}
+	if len(feed) != 2 || feed[0].ID != 3 || feed[1].ID != 2 || !slices.Equal(got, want) {
+		t.Errorf("Feed() = %+v, want post 3 from the tag then post 2 from the user", feed)
+	}
+}
@@ -0,0 +1,184 @@
+package trends
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/jobs"
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"go.uber.org/zap"
+)
+
+// Period is a sliding window trends are computed over. What happened during
+// the window is compared to what usually happens, as measured over the
+// baseline preceding it.
+type Period struct {
+	Name     string
+	Length   time.Duration
+	Baseline time.Duration
+}
+
+var Periods = []Period{
+	{Name: "1h", Length: time.Hour, Baseline: 24 * time.Hour},
+	{Name: "24h", Length: 24 * time.Hour, Baseline: 7 * 24 * time.Hour},
+	{Name: "7d", Length: 7 * 24 * time.Hour, Baseline: 28 * 24 * time.Hour},
+}
+
+// PeriodNames lists the names of the Periods, as accepted by the validator.
+const PeriodNames = "1h 24h 7d"
+
+type Config struct {
+	IsEnabled bool
+	// Schedule is the cron expression the trends are computed on, by the job
+	// queue. They are not computed while the queue is disabled.
+	Schedule string
+	// MinAuthors is the number of distinct users that must have used a tag,
+	// or engaged with a post, for it to trend. Counting users rather than
+	// posts or comments keeps a single spammer from making anything trend.
+	MinAuthors int
+	// Limit is the number of tags and posts kept per period.
+	Limit int
+}
+
+// Service computes the trending tags and posts of each period, on a schedule
+// of the job queue, and stores them for the API to read.
+type Service struct {
+	store  store.Storage
+	config Config
+	logger *zap.SugaredLogger
+}
+
+func NewService(store store.Storage, config Config, logger *zap.SugaredLogger) *Service {
+	return &Service{
+		store:  store,
+		config: config,
+		logger: logger,
+	}
+}
+
+// computeArgs is the job computing the trends.
+type computeArgs struct{}
+
+func (computeArgs) Kind() string { return "trends.compute" }
+
+// Register schedules the computation of the trends on the job queue.
+func (s *Service) Register(q *jobs.Queue) error {
+	if !s.config.IsEnabled {
+		return nil
+	}
+
+	jobs.Register(q, func(ctx context.Context, _ computeArgs) error {
+		return s.Compute(ctx, time.Now())
+	})
+
+	return q.Schedule(s.config.Schedule, computeArgs{})
+}
+
+// Compute computes the trends of every period as of asOf.
+func (s *Service) Compute(ctx context.Context, asOf time.Time) error {
+	for _, p := range Periods {
+		if err := s.computeTags(ctx, p, asOf); err != nil {
+			return err
+		}
+
+		if err := s.computePosts(ctx, p, asOf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Service) computeTags(ctx context.Context, p Period, asOf time.Time) error {
+	start := asOf.Add(-p.Length)
+
+	current, err := s.store.Trends.CountTags(ctx, start, asOf, s.config.MinAuthors)
+	if err != nil {
+		return err
+	}
+
+	past, err := s.store.Trends.CountTags(ctx, start.Add(-p.Baseline), start, 1)
+	if err != nil {
+		return err
+	}
+
+	baseline := make(map[string]int, len(past))
+	for _, t := range past {
+		baseline[t.Tag] = t.Authors
+	}
+
+	tags := make([]models.TrendingTag, len(current))
+	for i, t := range current {
+		velocity := Velocity(t.Authors, baseline[t.Tag], p)
+
+		tags[i] = models.TrendingTag{
+			Tag:      t.Tag,
+			Posts:    t.Posts,
+			Authors:  t.Authors,
+			Velocity: velocity,
+			Score:    float64(t.Authors) * velocity,
+		}
+	}
+
+	slices.SortFunc(tags, func(a, b models.TrendingTag) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+
+	return s.store.Trends.ReplaceTags(ctx, p.Name, tags[:min(s.config.Limit, len(tags))])
+}
+
+func (s *Service) computePosts(ctx context.Context, p Period, asOf time.Time) error {
+	start := asOf.Add(-p.Length)
+
+	current, err := s.store.Trends.CountEngagement(ctx, start, asOf, s.config.MinAuthors)
+	if err != nil {
+		return err
+	}
+
+	past, err := s.store.Trends.CountEngagement(ctx, start.Add(-p.Baseline), start, 1)
+	if err != nil {
+		return err
+	}
+
+	baseline := make(map[int64]int, len(past))
+	for _, a := range past {
+		baseline[a.PostID] = a.Engagers
+	}
+
+	posts := make([]models.TrendingPost, len(current))
+	for i, a := range current {
+		velocity := Velocity(a.Engagers, baseline[a.PostID], p)
+
+		posts[i] = models.TrendingPost{
+			PostID:   a.PostID,
+			Engagers: a.Engagers,
+			Velocity: velocity,
+			Score:    float64(a.Engagers) * velocity,
+		}
+	}
+
+	slices.SortFunc(posts, func(a, b models.TrendingPost) int {
+		if c := cmp.Compare(b.Score, a.Score); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.PostID, a.PostID)
+	})
+
+	return s.store.Trends.ReplacePosts(ctx, p.Name, posts[:min(s.config.Limit, len(posts))])
+}
+
+// Velocity compares the count of a period with the average count of a
+// period of the same length over the baseline. Both are smoothed so that
+// something never seen before doesn't get an infinite velocity.
+func Velocity(count, baselineCount int, p Period) float64 {
+	expected := float64(baselineCount) * float64(p.Length) / float64(p.Baseline)
+
+	return (float64(count) + 1) / (expected + 1)
+}
@@ -0,0 +1,84 @@
+package trends
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestVelocity(t *testing.T) {
+	hourly := Period{Name: "1h", Length: time.Hour, Baseline: 24 * time.Hour}
+	weekly := Period{Name: "7d", Length: 7 * 24 * time.Hour, Baseline: 28 * 24 * time.Hour}
+
+	tests := []struct {
+		name          string
+		count         int
+		baselineCount int
+		period        Period
+		want          float64
+	}{
+		{
+			name:   "nothing at all",
+			period: hourly,
+			want:   1,
+		},
+		{
+			name:   "zero baseline stays finite",
+			count:  9,
+			period: hourly,
+			want:   10,
+		},
+		{
+			name:          "as usual",
+			count:         2,
+			baselineCount: 48,
+			period:        hourly,
+			want:          1,
+		},
+		{
+			name:          "equal counts over a longer baseline",
+			count:         24,
+			baselineCount: 24,
+			period:        hourly,
+			want:          25.0 / 2,
+		},
+		{
+			name:          "baseline is scaled to the period",
+			count:         9,
+			baselineCount: 16,
+			period:        weekly,
+			want:          10.0 / 5,
+		},
+		{
+			name:          "quieter than usual",
+			baselineCount: 96,
+			period:        hourly,
+			want:          1.0 / 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Velocity(tt.count, tt.baselineCount, tt.period)
+			if math.Abs(got-tt.want) > 1e-12 {
+				t.Errorf("Velocity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVelocityIsMonotonic(t *testing.T) {
+	for _, p := range Periods {
+		t.Run(p.Name, func(t *testing.T) {
+			v := Velocity(5, 20, p)
+
+			if got := Velocity(6, 20, p); got <= v {
+				t.Errorf("Velocity() with a higher count = %v, want more than %v", got, v)
+			}
+
+			if got := Velocity(5, 21, p); got >= v {
+				t.Errorf("Velocity() with a higher baseline = %v, want less than %v", got, v)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+package utils
+
+import "time"
+
+// Backoff returns the wait before retrying something that failed its
+// attempt-th attempt: base, doubled with every attempt up to max.
+func Backoff(base, max time.Duration, attempt int) time.Duration {
+	delay := base
+	for i := 1; i < attempt && delay < max; i++ {
+		delay *= 2
+	}
+
+	return min(delay, max)
+}
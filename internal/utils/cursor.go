@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	CursorNext = "next"
+	CursorPrev = "prev"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a list ordered by (created_at, id), or by score
+// for ranked lists. Clients only ever see it encoded and signed, so it can't
+// be forged or tampered with.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        int64     `json:"id"`
+	Direction string    `json:"d"`
+	// Score and AsOf pin the position in a ranked list, and the time the
+	// list was ranked at.
+	Score float64    `json:"s,omitempty"`
+	AsOf  *time.Time `json:"a,omitempty"`
+}
+
+func EncodeCursor(c Cursor, secret string) string {
+	payload, _ := json.Marshal(c)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return base64.RawURLEncoding.EncodeToString(payload) + "." +
+		base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func DecodeCursor(s, secret string) (*Cursor, error) {
+	encPayload, encSig, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(encSig)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	if !hmac.Equal(sig, mac.Sum(nil)) {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if c.Direction != CursorNext && c.Direction != CursorPrev {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Keyset returns the comparison operator and the order to query the page
+// after (or before) the cursor in a list sorted in the given direction, and
+// whether the rows come back reversed and must be flipped.
+func (c *Cursor) Keyset(sort string) (cmp string, order string, reversed bool) {
+	forward := c.Direction == CursorNext
+
+	if (sort == "desc") == forward {
+		cmp = "<"
+	} else {
+		cmp = ">"
+	}
+
+	order = sort
+	if !forward {
+		order = map[string]string{"asc": "desc", "desc": "asc"}[sort]
+	}
+
+	return cmp, order, !forward
+}
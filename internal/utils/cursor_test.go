@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSecret = "cursor-secret"
+
+func TestCursorRoundTrip(t *testing.T) {
+	asOf := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name   string
+		cursor Cursor
+	}{
+		{
+			name:   "next",
+			cursor: Cursor{CreatedAt: asOf.Add(-time.Hour), ID: 42, Direction: CursorNext},
+		},
+		{
+			name:   "prev",
+			cursor: Cursor{CreatedAt: asOf.Add(-time.Hour), ID: 7, Direction: CursorPrev},
+		},
+		{
+			name:   "ranked",
+			cursor: Cursor{CreatedAt: asOf.Add(-time.Hour), ID: 3, Direction: CursorNext, Score: 1.25, AsOf: &asOf},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeCursor(EncodeCursor(tt.cursor, testSecret), testSecret)
+			if err != nil {
+				t.Fatalf("DecodeCursor() error = %v", err)
+			}
+
+			want := tt.cursor
+			if !got.CreatedAt.Equal(want.CreatedAt) || got.ID != want.ID || got.Direction != want.Direction || got.Score != want.Score {
+				t.Errorf("DecodeCursor() = %+v, want %+v", got, want)
+			}
+
+			if (got.AsOf == nil) != (want.AsOf == nil) || (got.AsOf != nil && !got.AsOf.Equal(*want.AsOf)) {
+				t.Errorf("DecodeCursor() AsOf = %v, want %v", got.AsOf, want.AsOf)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorRejects(t *testing.T) {
+	cursor := Cursor{CreatedAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC), ID: 42, Direction: CursorNext}
+	encoded := EncodeCursor(cursor, testSecret)
+	payload, sig, _ := strings.Cut(encoded, ".")
+
+	// a payload of its own, signed with the original signature
+	forged := cursor
+	forged.ID = 1
+	forgedPayload, _, _ := strings.Cut(EncodeCursor(forged, testSecret), ".")
+
+	tests := []struct {
+		name    string
+		encoded string
+		secret  string
+	}{
+		{name: "tampered payload", encoded: forgedPayload + "." + sig, secret: testSecret},
+		{name: "tampered signature", encoded: payload + "." + base64.RawURLEncoding.EncodeToString([]byte("forged")), secret: testSecret},
+		{name: "wrong secret", encoded: encoded, secret: "another-secret"},
+		{name: "missing signature", encoded: payload, secret: testSecret},
+		{name: "not base64", encoded: "!!." + sig, secret: testSecret},
+		{name: "unknown direction", encoded: EncodeCursor(Cursor{ID: 42, Direction: "sideways"}, testSecret), secret: testSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeCursor(tt.encoded, tt.secret); !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("DecodeCursor() error = %v, want %v", err, ErrInvalidCursor)
+			}
+		})
+	}
+}
+
+func TestCursorKeyset(t *testing.T) {
+	tests := []struct {
+		sort         string
+		direction    string
+		wantCmp      string
+		wantOrder    string
+		wantReversed bool
+	}{
+		{sort: "desc", direction: CursorNext, wantCmp: "<", wantOrder: "desc", wantReversed: false},
+		{sort: "desc", direction: CursorPrev, wantCmp: ">", wantOrder: "asc", wantReversed: true},
+		{sort: "asc", direction: CursorNext, wantCmp: ">", wantOrder: "asc", wantReversed: false},
+		{sort: "asc", direction: CursorPrev, wantCmp: "<", wantOrder: "desc", wantReversed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort+" "+tt.direction, func(t *testing.T) {
+			c := &Cursor{Direction: tt.direction}
+
+			cmp, order, reversed := c.Keyset(tt.sort)
+			if cmp != tt.wantCmp || order != tt.wantOrder || reversed != tt.wantReversed {
+				t.Errorf("Keyset(%q) = %q, %q, %v, want %q, %q, %v",
+					tt.sort, cmp, order, reversed, tt.wantCmp, tt.wantOrder, tt.wantReversed)
+			}
+		})
+	}
+}
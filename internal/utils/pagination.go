@@ -1,20 +1,31 @@
 package utils
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 )
 
+// MaxFeedTimeSpan is the widest since/until range a feed can be queried with.
+const MaxFeedTimeSpan = 90 * 24 * time.Hour
+
 type PaginatedFeedQuery struct {
 	Limit  int      `json:"limit" validate:"gte=1,lte=20"`
 	Offset int      `json:"offset" validate:"gte=0"`
 	Sort   string   `json:"sort" validate:"oneof=asc desc"`
+	Mode   string   `json:"mode" validate:"oneof=chronological top"`
 	Tags   []string `json:"tags" validate:"max=5"`
 	Search string   `json:"search" validate:"max=100"`
-	Since  string   `json:"since"`
-	Until  string   `json:"until"`
+	// Since (inclusive) and Until (exclusive) bound the feed to a time range.
+	Since  *time.Time `json:"since"`
+	Until  *time.Time `json:"until"`
+	Cursor string     `json:"cursor" validate:"excluded_with=Offset"`
+	// Keyset is the decoded Cursor, nil when paginating by offset.
+	Keyset *Cursor `json:"-"`
+	// WithOwnPosts adds the viewer's own posts to their home feed.
+	WithOwnPosts bool `json:"-"`
 }
 
 func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error) {
@@ -43,6 +54,16 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 		fq.Sort = sort
 	}
 
+	mode := qs.Get("mode")
+	if mode != "" {
+		fq.Mode = mode
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		fq.Cursor = cursor
+	}
+
 	tags := qs.Get("tags")
 	if tags != "" {
 		fq.Tags = strings.Split(tags, ",")
@@ -55,22 +76,164 @@ func (fq PaginatedFeedQuery) Parse(r *http.Request) (PaginatedFeedQuery, error)
 
 	since := qs.Get("since")
 	if since != "" {
-		fq.Since = parseTime(since)
+		t, err := parseTime(since)
+		if err != nil {
+			return fq, fmt.Errorf("invalid since: %w", err)
+		}
+		fq.Since = &t
 	}
 
 	until := qs.Get("until")
 	if until != "" {
-		fq.Until = parseTime(until)
+		t, err := parseTime(until)
+		if err != nil {
+			return fq, fmt.Errorf("invalid until: %w", err)
+		}
+		fq.Until = &t
+	}
+
+	if err := fq.checkTimeRange(time.Now()); err != nil {
+		return fq, err
 	}
 
 	return fq, nil
 }
 
-func parseTime(s string) string {
+func (fq PaginatedFeedQuery) checkTimeRange(now time.Time) error {
+	if fq.Since == nil {
+		return nil
+	}
+
+	end := now
+	if fq.Until != nil {
+		if !fq.Since.Before(*fq.Until) {
+			return fmt.Errorf("since must be before until")
+		}
+		end = *fq.Until
+	} else if fq.Since.After(now) {
+		return fmt.Errorf("since cannot be in the future")
+	}
+
+	if end.Sub(*fq.Since) > MaxFeedTimeSpan {
+		return fmt.Errorf("since and until cannot be more than %d days apart", int(MaxFeedTimeSpan.Hours()/24))
+	}
+
+	return nil
+}
+
+type PaginatedCommentQuery struct {
+	Limit  int    `json:"limit" validate:"gte=1,lte=50"`
+	Offset int    `json:"offset" validate:"gte=0"`
+	Sort   string `json:"sort" validate:"oneof=asc desc"`
+	Cursor string `json:"cursor" validate:"excluded_with=Offset"`
+	// Keyset is the decoded Cursor, nil when paginating by offset.
+	Keyset *Cursor `json:"-"`
+}
+
+func (cq PaginatedCommentQuery) Parse(r *http.Request) (PaginatedCommentQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return cq, err
+		}
+		cq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return cq, err
+		}
+		cq.Offset = o
+	}
+
+	sort := qs.Get("sort")
+	if sort != "" {
+		cq.Sort = sort
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		cq.Cursor = cursor
+	}
+
+	return cq, nil
+}
+
+// PaginatedQuery pages through a list by offset only.
+type PaginatedQuery struct {
+	Limit  int `json:"limit" validate:"gte=1,lte=50"`
+	Offset int `json:"offset" validate:"gte=0"`
+}
+
+func (pq PaginatedQuery) Parse(r *http.Request) (PaginatedQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return pq, err
+		}
+		pq.Limit = l
+	}
+
+	offset := qs.Get("offset")
+	if offset != "" {
+		o, err := strconv.Atoi(offset)
+		if err != nil {
+			return pq, err
+		}
+		pq.Offset = o
+	}
+
+	return pq, nil
+}
+
+// PaginatedMessageQuery pages through the messages of a conversation, newest
+// first, by cursor only.
+type PaginatedMessageQuery struct {
+	Limit  int    `json:"limit" validate:"gte=1,lte=100"`
+	Cursor string `json:"cursor"`
+	// Keyset is the decoded Cursor, nil on the first page.
+	Keyset *Cursor `json:"-"`
+}
+
+func (mq PaginatedMessageQuery) Parse(r *http.Request) (PaginatedMessageQuery, error) {
+	qs := r.URL.Query()
+
+	limit := qs.Get("limit")
+	if limit != "" {
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return mq, err
+		}
+		mq.Limit = l
+	}
+
+	cursor := qs.Get("cursor")
+	if cursor != "" {
+		mq.Cursor = cursor
+	}
+
+	return mq, nil
+}
+
+// parseTime accepts RFC 3339 timestamps, and time.DateTime ones which are
+// taken to be in UTC.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+
 	t, err := time.Parse(time.DateTime, s)
 	if err != nil {
-		return ""
+		return time.Time{}, fmt.Errorf("%q is not an RFC 3339 or %q timestamp", s, time.DateTime)
 	}
 
-	return t.Format(time.DateTime)
+	return t, nil
 }
@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTime(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    time.Time
+		wantErr bool
+	}{
+		{
+			name:  "RFC 3339",
+			input: "2026-03-01T12:30:00Z",
+			want:  time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "RFC 3339 with an offset",
+			input: "2026-03-01T14:30:00+02:00",
+			want:  time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{
+			name:  "DateTime is taken to be in UTC",
+			input: "2026-03-01 12:30:00",
+			want:  time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+		},
+		{name: "date only", input: "2026-03-01", wantErr: true},
+		{name: "garbage", input: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTime(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTime(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !got.Equal(tt.want) {
+				t.Errorf("parseTime(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTimeRange(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	ago := func(d time.Duration) *time.Time {
+		t := now.Add(-d)
+		return &t
+	}
+
+	tests := []struct {
+		name    string
+		since   *time.Time
+		until   *time.Time
+		wantErr bool
+	}{
+		{name: "no bounds"},
+		{name: "until only", until: ago(400 * 24 * time.Hour)},
+		{name: "since only", since: ago(24 * time.Hour)},
+		{name: "since and until", since: ago(48 * time.Hour), until: ago(24 * time.Hour)},
+		{name: "since after until", since: ago(24 * time.Hour), until: ago(48 * time.Hour), wantErr: true},
+		{name: "since equal to until", since: ago(24 * time.Hour), until: ago(24 * time.Hour), wantErr: true},
+		{name: "widest span", since: ago(MaxFeedTimeSpan)},
+		{name: "span too wide up to now", since: ago(MaxFeedTimeSpan + time.Second), wantErr: true},
+		{name: "span too wide up to until", since: ago(MaxFeedTimeSpan + 48*time.Hour), until: ago(24 * time.Hour), wantErr: true},
+		{name: "since in the future", since: ago(-time.Hour), wantErr: true},
+		{name: "until in the future", since: ago(time.Hour), until: ago(-time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fq := PaginatedFeedQuery{Since: tt.since, Until: tt.until}
+
+			if err := fq.checkTimeRange(now); (err != nil) != tt.wantErr {
+				t.Errorf("checkTimeRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+	"github.com/sandoxlabs99/gopher_social/internal/utils"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// Headers of the deliveries. The signature is the hex encoded HMAC-SHA256,
+// keyed with the webhook's secret, of the timestamp, a dot and the body.
+const (
+	HeaderEvent     = "X-GopherSocial-Event"
+	HeaderDelivery  = "X-GopherSocial-Delivery"
+	HeaderTimestamp = "X-GopherSocial-Timestamp"
+	HeaderSignature = "X-GopherSocial-Signature"
+)
+
+var ErrPrivateAddress = errors.New("webhook address is not public")
+
+type Config struct {
+	IsEnabled bool
+	// PollInterval is how often the queue is checked for due deliveries.
+	PollInterval time.Duration
+	// BatchSize is the number of deliveries claimed, and sent concurrently,
+	// at once.
+	BatchSize int
+	// Timeout bounds each attempt, the response included.
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts after which a delivery is
+	// dead-lettered.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry, it doubles with every
+	// failed attempt up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// AllowPrivate lets webhooks reach loopback and private addresses, for
+	// development. Otherwise they could be used to probe the internal
+	// network.
+	AllowPrivate bool
+}
+
+// Payload is the body posted to webhooks. Its ID stays the same across the
+// retries and redeliveries of an event, for receivers to ignore the ones
+// they already got.
+type Payload struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// FollowData is the data of the follow and unfollow events.
+type FollowData struct {
+	FollowerID int64 `json:"followerId"`
+	UserID     int64 `json:"userId"`
+}
+
+// DeletedPostData is the data of the post deletion events.
+type DeletedPostData struct {
+	ID     int64 `json:"id"`
+	UserID int64 `json:"userId"`
+}
+
+func NewPayload(typ string, data any) ([]byte, error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(Payload{
+		ID:        id.String(),
+		Type:      typ,
+		CreatedAt: time.Now().UTC(),
+		Data:      data,
+	})
+}
+
+// Sign returns the signature of a body sent at timestamp, in unix seconds.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the wait before retrying a delivery that failed its
+// attempt-th attempt.
+func (c Config) Backoff(attempt int) time.Duration {
+	return utils.Backoff(c.BaseDelay, c.MaxDelay, attempt)
+}
+
+// Dispatcher sends the queued deliveries to their webhooks, retrying the
+// failed ones with an exponential backoff until they are dead-lettered.
+// Deliveries are claimed with SKIP LOCKED so that several instances can
+// dispatch side by side.
+type Dispatcher struct {
+	store  store.Storage
+	client *http.Client
+	config Config
+	logger *zap.SugaredLogger
+}
+
+func NewDispatcher(store store.Storage, config Config, logger *zap.SugaredLogger) *Dispatcher {
+	dialer := &net.Dialer{Timeout: config.Timeout}
+	if !config.AllowPrivate {
+		dialer.Control = denyPrivate
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+
+	return &Dispatcher{
+		store: store,
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   config.Timeout,
+			// a redirect is a failure, the webhook's URL must be fixed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		config: config,
+		logger: logger,
+	}
+}
+
+// Run dispatches the due deliveries every PollInterval until ctx is done.
+// The deliveries in flight are then abandoned, to be claimed again once
+// their lease is over.
+func (d *Dispatcher) Run(ctx context.Context) {
+	if !d.config.IsEnabled {
+		return
+	}
+
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.DispatchDue(ctx); err != nil && ctx.Err() == nil {
+			d.logger.Errorw("failed to dispatch webhooks", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchDue sends the deliveries due now, batch by batch.
+func (d *Dispatcher) DispatchDue(ctx context.Context) error {
+	// the lease covers a whole batch, each attempt being bounded by Timeout
+	lease := 2 * d.config.Timeout
+
+	for {
+		deliveries, err := d.store.Webhooks.ClaimDue(ctx, d.config.BatchSize, lease)
+		if err != nil {
+			return err
+		}
+
+		var wg sync.WaitGroup
+		for _, delivery := range deliveries {
+			wg.Go(func() {
+				d.dispatch(ctx, &delivery)
+			})
+		}
+		wg.Wait()
+
+		if len(deliveries) < d.config.BatchSize {
+			return nil
+		}
+	}
+}
+
+func (d *Dispatcher) dispatch(ctx context.Context, delivery *models.WebhookDelivery) {
+	attempt := d.Deliver(ctx, delivery)
+
+	// interrupted by the shutdown, it will be retried
+	if ctx.Err() != nil {
+		return
+	}
+
+	attempts := delivery.Attempts + 1
+	status := models.WebhookPending
+	nextAttemptAt := time.Now().Add(d.config.Backoff(attempts))
+
+	switch {
+	case attempt.Error == "":
+		status = models.WebhookDelivered
+	case attempts >= d.config.MaxAttempts:
+		status = models.WebhookDead
+		d.logger.Warnw("webhook delivery dead-lettered", "deliveryID", delivery.ID, "webhookID", delivery.WebhookID, "error", attempt.Error)
+	}
+
+	if err := d.store.Webhooks.RecordAttempt(ctx, attempt, status, nextAttemptAt); err != nil {
+		d.logger.Errorw("failed to record webhook attempt", "deliveryID", delivery.ID, "error", err)
+	}
+}
+
+// Deliver posts a delivery to its webhook once. The attempt it returns has
+// an error unless the webhook answered with a 2xx status.
+func (d *Dispatcher) Deliver(ctx context.Context, delivery *models.WebhookDelivery) *models.WebhookAttempt {
+	attempt := &models.WebhookAttempt{DeliveryID: delivery.ID}
+
+	start := time.Now()
+	statusCode, err := d.post(ctx, delivery)
+	attempt.DurationMs = time.Since(start).Milliseconds()
+	attempt.StatusCode = statusCode
+
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+
+	return attempt
+}
+
+func (d *Dispatcher) post(ctx context.Context, delivery *models.WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GopherSocial-Webhooks")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// drain a little of the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// denyPrivate refuses to connect to the addresses that aren't on the public
+// internet. It runs once the host is resolved, so that a public name can't
+// point to a private address.
+func denyPrivate(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+		return ErrPrivateAddress
+	}
+
+	return nil
+}
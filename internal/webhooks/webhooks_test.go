@@ -0,0 +1,112 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/sandoxlabs99/gopher_social/internal/models"
+	"github.com/sandoxlabs99/gopher_social/internal/store"
+
+	"go.uber.org/zap"
+)
+
+func TestBackoff(t *testing.T) {
+	cfg := Config{BaseDelay: 30 * time.Second, MaxDelay: time.Hour}
+
+	tests := []struct {
+		attempt int
+		want    time.Duration
+	}{
+		{attempt: 1, want: 30 * time.Second},
+		{attempt: 2, want: time.Minute},
+		{attempt: 3, want: 2 * time.Minute},
+		{attempt: 7, want: 32 * time.Minute},
+		{attempt: 8, want: time.Hour},
+		{attempt: 100, want: time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.Backoff(tt.attempt); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempt, got, tt.want)
+		}
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	payload := []byte(`{"id":"1","type":"post.created","data":{}}`)
+
+	var status int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		timestamp, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+
+		if r.Header.Get(HeaderSignature) != Sign("secret", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get(HeaderEvent) != models.WebhookPostCreated || r.Header.Get(HeaderDelivery) != "7" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	delivery := &models.WebhookDelivery{
+		ID:      7,
+		Event:   models.WebhookPostCreated,
+		Payload: payload,
+		URL:     srv.URL,
+		Secret:  "secret",
+	}
+
+	cfg := Config{Timeout: time.Second, AllowPrivate: true}
+	d := NewDispatcher(store.Storage{}, cfg, zap.NewNop().Sugar())
+
+	t.Run("accepted", func(t *testing.T) {
+		status = http.StatusNoContent
+
+		attempt := d.Deliver(context.Background(), delivery)
+		if attempt.Error != "" || attempt.StatusCode != http.StatusNoContent {
+			t.Errorf("got status %d and error %q, want %d and no error", attempt.StatusCode, attempt.Error, status)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		status = http.StatusInternalServerError
+
+		attempt := d.Deliver(context.Background(), delivery)
+		if attempt.Error == "" || attempt.StatusCode != http.StatusInternalServerError {
+			t.Errorf("got status %d and error %q, want %d and an error", attempt.StatusCode, attempt.Error, status)
+		}
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		status = http.StatusOK
+
+		forged := *delivery
+		forged.Secret = "other"
+
+		attempt := d.Deliver(context.Background(), &forged)
+		if attempt.StatusCode != http.StatusUnauthorized {
+			t.Errorf("got status %d, want %d", attempt.StatusCode, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("private address", func(t *testing.T) {
+		d := NewDispatcher(store.Storage{}, Config{Timeout: time.Second}, zap.NewNop().Sugar())
+
+		_, err := d.post(context.Background(), delivery)
+		if !errors.Is(err, ErrPrivateAddress) {
+			t.Errorf("got error %v, want %v", err, ErrPrivateAddress)
+		}
+	})
+}